@@ -0,0 +1,111 @@
+// Command opml imports an OPML subscription list into the source whitelist
+// or exports the whitelist as OPML, working directly on the SQLite database
+// configured for the API (DB_PATH).
+//
+//	opml import [-authority 0.6] [-topics tech,ai] feeds.opml
+//	opml export [-o sources.opml]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"news-go/internal/config"
+	"news-go/internal/crawler"
+	"news-go/internal/opml"
+	"news-go/internal/storage"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cfg := config.Load()
+	// Opening the article repository creates and migrates the schema.
+	if _, err := storage.NewSQLiteArticleRepository(cfg.DBPath, "db/schema.sql"); err != nil {
+		log.Fatalf("open database: %v", err)
+	}
+	repo := storage.NewSQLiteSourceRepository(cfg.DBPath)
+	ctx := context.Background()
+	switch os.Args[1] {
+	case "import":
+		access := crawler.LocalAccess{FileRoot: cfg.FileSourceRoot, CADir: cfg.CAFileDir, SecretEnvPrefix: cfg.SecretEnvPrefix}
+		runImport(ctx, repo, access, os.Args[2:])
+	case "export":
+		runExport(ctx, repo, os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: opml import [-authority N] [-topics a,b] <file|->\n       opml export [-o file]")
+	os.Exit(2)
+}
+
+func runImport(ctx context.Context, repo storage.SourceRepository, access crawler.LocalAccess, args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	authority := fs.Float64("authority", 0, "base_authority for feeds whose outline has none")
+	topics := fs.String("topics", "", "comma-separated topics added to every feed")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+	var in io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatalf("open %s: %v", name, err)
+		}
+		defer f.Close()
+		in = f
+	}
+	d := opml.Defaults{BaseAuthority: *authority}
+	if *topics != "" {
+		d.Topics = strings.Split(*topics, ",")
+	}
+	res, err := opml.Import(ctx, repo, in, d, access)
+	if err != nil {
+		log.Fatalf("import: %v", err)
+	}
+	for _, s := range res.Created {
+		log.Printf("created %s %s topics=%s", s.ID, s.FeedURL, strings.Join(s.Topics, ","))
+	}
+	for _, s := range res.Skipped {
+		log.Printf("skipped %s %s: %s", s.ID, s.FeedURL, s.Reason)
+	}
+	log.Printf("%d created, %d skipped", len(res.Created), len(res.Skipped))
+}
+
+func runExport(ctx context.Context, repo storage.SourceRepository, args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("o", "", "output file (default stdout)")
+	fs.Parse(args)
+	items, err := repo.ListSources(ctx)
+	if err != nil {
+		log.Fatalf("list sources: %v", err)
+	}
+	if *out == "" {
+		if err := opml.Write(os.Stdout, items, time.Now()); err != nil {
+			log.Fatalf("export: %v", err)
+		}
+		return
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("create %s: %v", *out, err)
+	}
+	if err := opml.Write(f, items, time.Now()); err != nil {
+		log.Fatalf("export: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("export: %v", err)
+	}
+}
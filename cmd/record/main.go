@@ -0,0 +1,127 @@
+// Command record fetches sources once through a recording transport and
+// saves every HTTP exchange as a cassette in the crawler's test corpus,
+// where the corpus test replays it offline.
+//
+//	record [-sources data/sources.json] [-o internal/crawler/testdata/corpus] [-only bbc,reuters]
+//
+// Sources are merged into <dir>/sources.json by id. Run `go test
+// ./internal/crawler -run Corpus -update` afterwards to write the golden
+// files, and review the diff before committing it.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"news-go/internal/cassette"
+	"news-go/internal/config"
+	"news-go/internal/crawler"
+	"news-go/internal/news"
+)
+
+func main() {
+	log.SetFlags(0)
+	cfg := config.Load()
+	access := crawler.LocalAccess{FileRoot: cfg.FileSourceRoot, CADir: cfg.CAFileDir, SecretEnvPrefix: cfg.SecretEnvPrefix}
+	sourcesPath := flag.String("sources", cfg.SourcesPath, "JSON source list to record")
+	dir := flag.String("o", filepath.Join("internal", "crawler", "testdata", "corpus"), "corpus directory")
+	only := flag.String("only", "", "comma-separated source ids to record (default all)")
+	timeout := flag.Duration("timeout", 30*time.Second, "per-source timeout")
+	flag.Parse()
+
+	sources, err := readSources(*sourcesPath)
+	if err != nil {
+		log.Fatalf("read sources: %v", err)
+	}
+	want := map[string]bool{}
+	for _, id := range strings.Split(*only, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			want[id] = true
+		}
+	}
+	corpusPath := filepath.Join(*dir, "sources.json")
+	corpus, err := readSources(corpusPath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatalf("read corpus: %v", err)
+	}
+	byID := map[string]news.Source{}
+	for _, src := range corpus {
+		byID[src.ID] = src
+	}
+
+	recorded := 0
+	for _, src := range sources {
+		src.Normalize()
+		if len(want) > 0 && !want[src.ID] {
+			continue
+		}
+		if err := crawler.ValidateSource(src, access); err != nil {
+			log.Printf("skip %s: %v", src.ID, err)
+			continue
+		}
+		// Source headers and auth are added below the recorder, so they
+		// never reach the cassette.
+		rec := cassette.NewRecorder(crawler.NewSourceTransport(http.DefaultTransport, *timeout, access))
+		fetchers := crawler.NewFetchers(&http.Client{Timeout: *timeout, Transport: rec}, crawler.WithLocalAccess(access))
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		feed, err := fetchers.Fetch(ctx, src, cfg.RSSUserAgent)
+		cancel()
+		c := rec.Cassette()
+		if len(c.Interactions) == 0 {
+			log.Printf("skip %s: no response: %v", src.ID, err)
+			continue
+		}
+		if err := c.Save(filepath.Join(*dir, src.ID+".cassette.json")); err != nil {
+			log.Fatalf("save %s: %v", src.ID, err)
+		}
+		// A failed fetch is still recorded: the error is what the golden
+		// file will pin down.
+		if err != nil {
+			log.Printf("recorded %s with error: %v", src.ID, err)
+		} else {
+			log.Printf("recorded %s: %d requests, %d items", src.ID, len(c.Interactions), len(feed.Items))
+		}
+		byID[src.ID] = src
+		recorded++
+	}
+
+	merged := make([]corpusSource, 0, len(byID))
+	for _, src := range byID {
+		merged = append(merged, corpusSource{ID: src.ID, Name: src.Name, FeedURL: src.FeedURL, Kind: src.Kind, Options: src.Options})
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].ID < merged[j].ID })
+	b, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(corpusPath, append(b, '\n'), 0o644); err != nil {
+		log.Fatalf("write corpus: %v", err)
+	}
+	log.Printf("%d sources recorded into %s", recorded, *dir)
+}
+
+// corpusSource is the part of a source the corpus test needs to replay it.
+type corpusSource struct {
+	ID      string          `json:"id"`
+	Name    string          `json:"name"`
+	FeedURL string          `json:"rss"`
+	Kind    string          `json:"kind"`
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+func readSources(path string) ([]news.Source, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var items []news.Source
+	return items, json.Unmarshal(b, &items)
+}
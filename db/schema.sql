@@ -1,8 +1,19 @@
 CREATE TABLE IF NOT EXISTS sources (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug TEXT,
     name TEXT NOT NULL,
     url TEXT NOT NULL,
-    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+    country TEXT NOT NULL DEFAULT '',
+    base_authority REAL NOT NULL DEFAULT 0,
+    topics TEXT NOT NULL DEFAULT '[]',
+    enabled INTEGER NOT NULL DEFAULT 1,
+    extract_full_text INTEGER NOT NULL DEFAULT 0,
+    kind TEXT NOT NULL DEFAULT 'rss',
+    options TEXT NOT NULL DEFAULT '',
+    http_options TEXT NOT NULL DEFAULT '',
+    retention_days INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME
 );
 
 CREATE TABLE IF NOT EXISTS articles (
@@ -11,11 +22,92 @@ CREATE TABLE IF NOT EXISTS articles (
     title TEXT NOT NULL,
     url TEXT NOT NULL,
     url_hash TEXT NOT NULL UNIQUE,
+    guid TEXT NOT NULL DEFAULT '',
+    authors TEXT NOT NULL DEFAULT '[]',
+    categories TEXT NOT NULL DEFAULT '[]',
+    image_url TEXT NOT NULL DEFAULT '',
+    media TEXT NOT NULL DEFAULT '[]',
+    language TEXT NOT NULL DEFAULT '',
+    language_confidence REAL NOT NULL DEFAULT 0,
     content TEXT,
+    content_text TEXT NOT NULL DEFAULT '',
+    content_hash TEXT NOT NULL DEFAULT '',
+    search_text TEXT NOT NULL DEFAULT '',
     published_at DATETIME,
+    fetched_at DATETIME,
+    updated_at DATETIME,
+    body TEXT NOT NULL DEFAULT '',
+    extraction_status TEXT NOT NULL DEFAULT '',
     created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY(source_id) REFERENCES sources(id)
 );
 
 CREATE INDEX IF NOT EXISTS idx_articles_published_at ON articles(published_at DESC);
 CREATE INDEX IF NOT EXISTS idx_articles_source_id ON articles(source_id);
+
+CREATE TABLE IF NOT EXISTS article_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    article_id INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL DEFAULT '',
+    content_text TEXT NOT NULL DEFAULT '',
+    content_hash TEXT NOT NULL,
+    seen_at DATETIME,
+    replaced_at DATETIME NOT NULL,
+    FOREIGN KEY(article_id) REFERENCES articles(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_article_revisions_article_id ON article_revisions(article_id, id);
+
+CREATE TABLE IF NOT EXISTS crawl_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    source_id TEXT NOT NULL,
+    trigger TEXT NOT NULL,
+    status TEXT NOT NULL,
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    fetched INTEGER NOT NULL DEFAULT 0,
+    inserted INTEGER NOT NULL DEFAULT 0,
+    updated INTEGER NOT NULL DEFAULT 0,
+    errors TEXT NOT NULL DEFAULT '[]',
+    truncated INTEGER NOT NULL DEFAULT 0,
+    rejected TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_crawl_runs_source_id ON crawl_runs(source_id, id DESC);
+
+CREATE TABLE IF NOT EXISTS source_health (
+    source_id TEXT PRIMARY KEY,
+    last_attempt_at DATETIME,
+    last_success_at DATETIME,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    newest_article_at DATETIME,
+    poll_interval_sec INTEGER NOT NULL DEFAULT 0,
+    next_poll_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS websub_subscriptions (
+    source_id TEXT PRIMARY KEY,
+    hub TEXT NOT NULL,
+    topic TEXT NOT NULL,
+    secret TEXT NOT NULL DEFAULT '',
+    state TEXT NOT NULL,
+    lease_seconds INTEGER NOT NULL DEFAULT 0,
+    requested_at DATETIME NOT NULL,
+    expires_at DATETIME,
+    last_push_at DATETIME,
+    last_error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS source_files (
+    source_id TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    name TEXT NOT NULL,
+    status TEXT NOT NULL,
+    items INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    processed_at DATETIME NOT NULL,
+    PRIMARY KEY (source_id, checksum)
+);
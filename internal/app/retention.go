@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"news-go/internal/config"
+	"news-go/internal/retention"
+	"news-go/internal/storage"
+)
+
+// pruner runs the retention job for the admin API and the background
+// loop. With RETENTION_DRY_RUN every run is a dry run.
+type pruner struct {
+	job    *retention.Job
+	dryRun bool
+}
+
+func newPruner(cfg config.Config, repos repositories) *pruner {
+	return &pruner{
+		job: &retention.Job{
+			Policy: storage.RetentionPolicy{
+				MaxAge:       time.Duration(cfg.RetentionMaxAgeDays) * 24 * time.Hour,
+				MaxPerSource: cfg.RetentionMaxPerSource,
+				MaxRows:      cfg.RetentionMaxRows,
+			},
+			Articles:  repos.articles,
+			Expired:   repos.expired,
+			Revisions: repos.revisions,
+			Sources:   repos.sources,
+			Dir:       cfg.RetentionArchiveDir,
+			MaxPerRun: cfg.RetentionMaxPerRun,
+		},
+		dryRun: cfg.RetentionDryRun,
+	}
+}
+
+func (p *pruner) Prune(ctx context.Context, dryRun bool) (retention.Report, error) {
+	rep, err := p.job.Run(ctx, time.Now().UTC(), dryRun || p.dryRun)
+	if err != nil {
+		log.Printf("event=retention status=error dry_run=%t articles=%d err=%v", rep.DryRun, rep.Articles, err)
+		return rep, err
+	}
+	if rep.Articles > 0 {
+		log.Printf("event=retention status=ok dry_run=%t articles=%d sources=%s archive=%s", rep.DryRun, rep.Articles, sourceCounts(rep.BySource), rep.Archive)
+	}
+	return rep, nil
+}
+
+// loop prunes once at start and then every interval; interval <= 0
+// leaves pruning to the admin API.
+func (p *pruner) loop(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		_, _ = p.Prune(ctx, false)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sourceCounts formats per-source counts as id:n,id:n for the log.
+func sourceCounts(counts map[string]int) string {
+	ids := make([]string, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = id + ":" + strconv.Itoa(counts[id])
+	}
+	return strings.Join(parts, ",")
+}
@@ -2,108 +2,174 @@ package app
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"news-go/internal/config"
 	"news-go/internal/crawler"
+	"news-go/internal/health"
 	"news-go/internal/httpapi"
+	"news-go/internal/news"
 	"news-go/internal/storage"
 )
 
 func NewServer(cfg config.Config) *http.Server {
-	repo := buildRepository(cfg)
-	syncer := newRSSSyncer(cfg, repo)
-	syncer.start(context.Background())
+	return newServer(context.Background(), cfg)
+}
+
+// newServer ties the background syncer to ctx so cancelling it stops
+// crawling, including any retry backoff in progress.
+func newServer(ctx context.Context, cfg config.Config) *http.Server {
+	repos := buildRepositories(cfg)
+	seedSources(ctx, repos.sources, cfg.SourcesPath, localAccess(cfg))
+	syncer := newRSSSyncer(cfg, repos)
+	syncer.start(ctx)
+	pruner := newPruner(cfg, repos)
+	go pruner.loop(ctx, time.Duration(cfg.RetentionIntervalSec)*time.Second)
 
-	h := httpapi.NewHandler(repo)
+	opts := []httpapi.Option{
+		httpapi.WithSources(repos.sources),
+		httpapi.WithLocalAccess(localAccess(cfg)),
+		httpapi.WithCrawler(syncer, repos.runs),
+		httpapi.WithSourceFiles(repos.files),
+		httpapi.WithRevisions(repos.revisions),
+		httpapi.WithPruner(pruner),
+		httpapi.WithDiscovery(crawler.NewDiscoverer(newDiscoverClient(cfg), cfg.RSSUserAgent)),
+		httpapi.WithSourceHealth(repos.health, health.Policy{
+			MaxArticleAge: time.Duration(cfg.SourceStaleAfterSec) * time.Second,
+			MaxFailures:   cfg.SourceStaleFailures,
+			MaxStaleRatio: cfg.ReadyzStaleRatio,
+		}),
+	}
+	if syncer.websub != nil {
+		opts = append(opts, httpapi.WithWebSub(syncer.websub))
+	}
+	h := httpapi.NewHandler(repos.articles, opts...)
 	mux := http.NewServeMux()
 	h.Register(mux)
 
 	return &http.Server{Addr: cfg.HTTPAddr, Handler: httpapi.LoggingMiddleware(mux)}
 }
 
-func buildRepository(cfg config.Config) storage.ArticleRepository {
+// localAccess is what sources may reach on this host.
+func localAccess(cfg config.Config) crawler.LocalAccess {
+	return crawler.LocalAccess{FileRoot: cfg.FileSourceRoot, CADir: cfg.CAFileDir, SecretEnvPrefix: cfg.SecretEnvPrefix}
+}
+
+type repositories struct {
+	articles  storage.ArticleRepository
+	sources   storage.SourceRepository
+	runs      storage.CrawlRunRepository
+	health    storage.SourceHealthRepository
+	bodies    storage.ArticleBodyRepository
+	revisions storage.ArticleRevisionRepository
+	expired   storage.ArticleRetentionRepository
+	websub    storage.WebSubRepository
+	files     storage.SourceFileRepository
+}
+
+func buildRepositories(cfg config.Config) repositories {
 	repo, err := storage.NewSQLiteArticleRepository(cfg.DBPath, "db/schema.sql")
 	if err != nil {
 		if errors.Is(err, storage.ErrSQLiteBinaryNotFound) {
 			log.Printf("sqlite3 not installed, using in-memory repository")
-			return storage.NewMemoryArticleRepository()
+			return memoryRepositories()
 		}
 		log.Printf("sqlite init failed, fallback to memory repo: %v", err)
-		return storage.NewMemoryArticleRepository()
+		return memoryRepositories()
+	}
+	return repositories{
+		articles:  repo,
+		sources:   storage.NewSQLiteSourceRepository(cfg.DBPath),
+		runs:      storage.NewSQLiteCrawlRunRepository(cfg.DBPath),
+		health:    storage.NewSQLiteSourceHealthRepository(cfg.DBPath),
+		bodies:    repo,
+		revisions: repo,
+		expired:   repo,
+		websub:    storage.NewSQLiteWebSubRepository(cfg.DBPath),
+		files:     storage.NewSQLiteSourceFileRepository(cfg.DBPath),
 	}
-	return repo
-}
-
-type rssSyncer struct {
-	cfg     config.Config
-	repo    storage.ArticleRepository
-	fetcher *crawler.RSSFetcher
 }
 
-func newRSSSyncer(cfg config.Config, repo storage.ArticleRepository) *rssSyncer {
-	return &rssSyncer{cfg: cfg, repo: repo, fetcher: crawler.NewRSSFetcher(10 * time.Second)}
+func memoryRepositories() repositories {
+	articles := storage.NewMemoryArticleRepository()
+	return repositories{
+		articles:  articles,
+		bodies:    articles,
+		revisions: articles,
+		expired:   articles,
+		sources:   storage.NewMemorySourceRepository(),
+		runs:      storage.NewMemoryCrawlRunRepository(),
+		health:    storage.NewMemorySourceHealthRepository(),
+		websub:    storage.NewMemoryWebSubRepository(),
+		files:     storage.NewMemorySourceFileRepository(),
+	}
 }
 
-func (s *rssSyncer) start(ctx context.Context) {
-	go s.syncWithRetry(ctx)
-	if s.cfg.RSSSyncIntervalSec <= 0 {
+// seedSources imports the whitelist file into an empty source repository so
+// a fresh install crawls the same feeds as the Python pipeline.
+func seedSources(ctx context.Context, repo storage.SourceRepository, path string, access crawler.LocalAccess) {
+	existing, err := repo.ListSources(ctx)
+	if err != nil {
+		log.Printf("event=source_seed status=error err=%v", err)
 		return
 	}
-	go func() {
-		ticker := time.NewTicker(time.Duration(s.cfg.RSSSyncIntervalSec) * time.Second)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				s.syncWithRetry(ctx)
-			}
+	if len(existing) > 0 || path == "" {
+		return
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("event=source_seed status=error path=%s err=%v", path, err)
 		}
-	}()
-}
-
-func (s *rssSyncer) syncWithRetry(ctx context.Context) {
-	attempts := s.cfg.RSSMaxRetries + 1
-	if attempts < 1 {
-		attempts = 1
+		return
 	}
-	for i := 1; i <= attempts; i++ {
-		if err := s.syncOnce(ctx); err != nil {
-			log.Printf("rss sync attempt=%d/%d failed: %v", i, attempts, err)
-			if i < attempts {
-				time.Sleep(2 * time.Second)
-			}
+	var items []news.Source
+	if err := json.Unmarshal(body, &items); err != nil {
+		log.Printf("event=source_seed status=error path=%s err=%v", path, err)
+		return
+	}
+	created := 0
+	for _, src := range items {
+		src.Normalize()
+		if err := crawler.ValidateSource(src, access); err != nil {
+			log.Printf("event=source_seed status=skip id=%s err=%v", src.ID, err)
 			continue
 		}
-		return
+		if _, err := repo.CreateSource(ctx, src); err != nil {
+			log.Printf("event=source_seed status=skip id=%s err=%v", src.ID, err)
+			continue
+		}
+		created++
 	}
+	log.Printf("event=source_seed status=ok path=%s created=%d", path, created)
 }
 
-func (s *rssSyncer) syncOnce(ctx context.Context) error {
-	callCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
-	defer cancel()
-	items, err := s.fetcher.Fetch(callCtx, s.cfg.RSSFeedURL, s.cfg.RSSUserAgent)
-	if err != nil {
+// Run serves until SIGINT or SIGTERM, then stops the syncer and drains
+// in-flight requests.
+func Run(cfg config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := newServer(ctx, cfg)
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+	fmt.Printf("news-go listening on %s\n", cfg.HTTPAddr)
+	select {
+	case err := <-errCh:
 		return err
+	case <-ctx.Done():
 	}
-	if len(items) == 0 {
-		return nil
-	}
-	if err := s.repo.UpsertArticles(callCtx, items); err != nil {
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
 		return err
 	}
-	log.Printf("event=rss_sync status=ok fetched=%d", len(items))
-	return nil
-}
-
-func Run(cfg config.Config) error {
-	srv := NewServer(cfg)
-	fmt.Printf("news-go listening on %s\n", cfg.HTTPAddr)
-	return srv.ListenAndServe()
+	return http.ErrServerClosed
 }
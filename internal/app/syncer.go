@@ -0,0 +1,568 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"news-go/internal/config"
+	"news-go/internal/crawler"
+	"news-go/internal/extract"
+	"news-go/internal/langid"
+	"news-go/internal/news"
+	"news-go/internal/sanitize"
+	"news-go/internal/storage"
+	"news-go/internal/websub"
+)
+
+const (
+	triggerStartup  = "startup"
+	triggerSchedule = "schedule"
+	triggerManual   = "manual"
+	triggerPush     = "push"
+)
+
+// crawlQueueSize bounds pending manual runs; further requests are rejected
+// instead of piling up behind a slow crawl.
+const crawlQueueSize = 16
+
+var errCrawlQueueFull = errors.New("crawl queue is full")
+
+const (
+	// pollTick is how often the scheduler looks for sources that are due.
+	pollTick = 30 * time.Second
+	// cadenceSamples is how many recent articles a source's polling
+	// interval is learned from.
+	cadenceSamples = 50
+)
+
+// crawlRequest asks the sync loop for an immediate run. An empty sourceID
+// means every enabled source.
+type crawlRequest struct {
+	sourceID string
+}
+
+type rssSyncer struct {
+	cfg     config.Config
+	repo    storage.ArticleRepository
+	sources storage.SourceRepository
+	runs    storage.CrawlRunRepository
+	health  storage.SourceHealthRepository
+	fetch   crawler.Fetchers
+	limits  crawler.FeedLimits
+	websub  *websub.Subscriber
+	bodies  storage.ArticleBodyRepository
+	extract *extract.Extractor
+	backoff crawler.Backoff
+	breaker *crawler.Breaker
+	pool    *crawler.Pool
+	poll    crawler.Schedule
+	queue   chan crawlRequest
+}
+
+func newRSSSyncer(cfg config.Config, repos repositories) *rssSyncer {
+	client := newCrawlClient(cfg)
+	limits := crawler.FeedLimits{
+		MaxBytes:      int64(cfg.FeedMaxBytes),
+		MaxItems:      cfg.FeedMaxItems,
+		MaxFieldBytes: cfg.FeedMaxFieldBytes,
+	}
+	s := &rssSyncer{
+		cfg:     cfg,
+		repo:    repos.articles,
+		sources: repos.sources,
+		runs:    repos.runs,
+		health:  repos.health,
+		fetch:   crawler.NewFetchers(client, crawler.WithFeedLimits(limits), crawler.WithFileLedger(repos.files), crawler.WithLocalAccess(localAccess(cfg))),
+		limits:  limits,
+		bodies:  repos.bodies,
+		extract: extract.NewExtractor(client, cfg.RSSUserAgent),
+		backoff: crawler.Backoff{
+			Base:   time.Duration(cfg.RSSBackoffBaseMS) * time.Millisecond,
+			Max:    time.Duration(cfg.RSSBackoffMaxMS) * time.Millisecond,
+			Jitter: 0.5,
+		},
+		breaker: crawler.NewBreaker(cfg.BreakerThreshold, time.Duration(cfg.BreakerCooldownSec)*time.Second),
+		pool: crawler.NewPool(cfg.CrawlWorkers, crawler.NewHostLimiter(
+			cfg.CrawlHostMaxInFlight,
+			time.Duration(cfg.CrawlHostMinDelayMS)*time.Millisecond,
+		)),
+		poll: crawler.Schedule{
+			Default: time.Duration(cfg.RSSSyncIntervalSec) * time.Second,
+			Min:     time.Duration(cfg.PollMinIntervalSec) * time.Second,
+			Max:     time.Duration(cfg.PollMaxIntervalSec) * time.Second,
+		},
+		queue: make(chan crawlRequest, crawlQueueSize),
+	}
+	if cfg.WebSubCallbackURL != "" {
+		hubClient := &http.Client{Transport: crawlBase(cfg), Timeout: 10 * time.Second}
+		s.websub = websub.NewSubscriber(repos.websub, hubClient, cfg.WebSubCallbackURL, time.Duration(cfg.WebSubLeaseSec)*time.Second, s.receivePush)
+	}
+	return s
+}
+
+func (s *rssSyncer) start(ctx context.Context) {
+	go s.loop(ctx)
+}
+
+// newCrawlClient returns the HTTP client for outbound crawling. Requests
+// carry the settings of the source in their context (see
+// crawler.WithSource), robots.txt lookups included. With ROBOTS_ENABLED
+// every request is checked against robots.txt first.
+func newCrawlClient(cfg config.Config) *http.Client {
+	return crawlClient(cfg, crawlBase(cfg))
+}
+
+// crawlBase keeps crawling, extraction and hub requests to public
+// addresses, since sources and the hubs their feeds name come from API
+// callers; CRAWL_ALLOW_PRIVATE lifts this for deployments that crawl
+// intranet feeds.
+func crawlBase(cfg config.Config) http.RoundTripper {
+	if cfg.CrawlAllowPrivate {
+		return http.DefaultTransport
+	}
+	return crawler.NewPublicTransport()
+}
+
+// newDiscoverClient is the crawl client restricted to public addresses,
+// since discovery fetches whatever URL an API caller names.
+func newDiscoverClient(cfg config.Config) *http.Client {
+	return crawlClient(cfg, crawler.NewPublicTransport())
+}
+
+func crawlClient(cfg config.Config, base http.RoundTripper) *http.Client {
+	transport := crawler.NewSourceTransport(base, crawlTimeout, localAccess(cfg))
+	if !cfg.RobotsEnabled {
+		return &http.Client{Transport: transport}
+	}
+	robots := crawler.NewRobotsCache(&http.Client{Transport: transport}, cfg.RSSUserAgent, time.Duration(cfg.RobotsCacheTTLSec)*time.Second)
+	return &http.Client{Transport: crawler.NewPolicyTransport(transport, crawler.NewCrawlPolicy(robots))}
+}
+
+// crawlTimeout bounds one request of a source without its own
+// http.timeout_sec.
+const crawlTimeout = 10 * time.Second
+
+// callTimeout bounds a source's fetch or extraction call, leaving room
+// beyond the request timeout for robots.txt and storing.
+func callTimeout(src news.Source) time.Duration {
+	d := 15 * time.Second
+	if src.HTTP != nil && src.HTTP.TimeoutSec > 0 {
+		if t := time.Duration(src.HTTP.TimeoutSec)*time.Second + 5*time.Second; t > d {
+			d = t
+		}
+	}
+	return d
+}
+
+// loop serialises scheduled and manual runs so a source is never crawled by
+// two goroutines at once. After the startup run each source is polled on
+// its own schedule.
+func (s *rssSyncer) loop(ctx context.Context) {
+	s.syncAll(ctx, triggerStartup)
+	var tick <-chan time.Time
+	if s.cfg.RSSSyncIntervalSec > 0 {
+		every := pollTick
+		if s.poll.Min > 0 && s.poll.Min < every {
+			every = s.poll.Min
+		}
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+			s.syncDue(ctx, triggerSchedule)
+		case req := <-s.queue:
+			s.runRequest(ctx, req)
+		}
+	}
+}
+
+// TriggerCrawl enqueues an immediate run for one source, or for all enabled
+// sources when sourceID is empty. A named source is crawled even if disabled.
+func (s *rssSyncer) TriggerCrawl(ctx context.Context, sourceID string) error {
+	if sourceID != "" {
+		if _, err := s.sources.GetSource(ctx, sourceID); err != nil {
+			return err
+		}
+	}
+	select {
+	case s.queue <- crawlRequest{sourceID: sourceID}:
+		return nil
+	default:
+		return errCrawlQueueFull
+	}
+}
+
+func (s *rssSyncer) runRequest(ctx context.Context, req crawlRequest) {
+	if req.sourceID == "" {
+		s.syncAll(ctx, triggerManual)
+		return
+	}
+	src, err := s.sources.GetSource(ctx, req.sourceID)
+	if err != nil {
+		log.Printf("event=rss_sync status=error source=%s err=%v", req.sourceID, err)
+		return
+	}
+	s.pool.Run(ctx, []crawler.Job{{
+		Host: crawler.HostOf(src.FeedURL),
+		Run:  func(ctx context.Context, slot *crawler.HostSlot) { s.syncWithRetry(ctx, src, triggerManual, slot) },
+	}})
+}
+
+// syncAll re-reads the source list on every run so sources added, edited or
+// disabled through the API take effect without a restart. Sources are
+// crawled concurrently through the pool, subject to per-host limits.
+func (s *rssSyncer) syncAll(ctx context.Context, trigger string) {
+	s.crawl(ctx, s.enabledSources(ctx), trigger)
+}
+
+// syncDue crawls the enabled sources whose next poll time has passed.
+// Sources never crawled are due at once.
+func (s *rssSyncer) syncDue(ctx context.Context, trigger string) {
+	records, err := s.health.ListSourceHealth(ctx)
+	if err != nil {
+		log.Printf("event=rss_sync status=error err=%v", err)
+	}
+	next := make(map[string]time.Time, len(records))
+	for _, h := range records {
+		if h.NextPollAt != nil {
+			next[h.SourceID] = *h.NextPollAt
+		}
+	}
+	now := time.Now()
+	var due []news.Source
+	for _, src := range s.enabledSources(ctx) {
+		if at, ok := next[src.ID]; ok && at.After(now) {
+			continue
+		}
+		due = append(due, src)
+	}
+	if len(due) > 0 {
+		s.crawl(ctx, due, trigger)
+	}
+}
+
+func (s *rssSyncer) crawl(ctx context.Context, sources []news.Source, trigger string) {
+	jobs := make([]crawler.Job, 0, len(sources))
+	for _, src := range sources {
+		src := src
+		jobs = append(jobs, crawler.Job{
+			Host: crawler.HostOf(src.FeedURL),
+			Run:  func(ctx context.Context, slot *crawler.HostSlot) { s.syncWithRetry(ctx, src, trigger, slot) },
+		})
+	}
+	s.pool.Run(ctx, jobs)
+}
+
+// enabledSources falls back to RSS_FEED_URL when no source is configured,
+// which keeps single-feed deployments working as before.
+func (s *rssSyncer) enabledSources(ctx context.Context) []news.Source {
+	items, err := s.sources.ListSources(ctx)
+	if err != nil {
+		log.Printf("event=rss_sync status=error err=%v", err)
+	}
+	enabled := make([]news.Source, 0, len(items))
+	for _, src := range items {
+		if src.Enabled {
+			enabled = append(enabled, src)
+		}
+	}
+	if len(enabled) == 0 && len(items) == 0 && s.cfg.RSSFeedURL != "" {
+		enabled = append(enabled, news.Source{ID: "rss", Name: "rss", FeedURL: s.cfg.RSSFeedURL, Enabled: true})
+	}
+	return enabled
+}
+
+// syncWithRetry skips sources whose circuit is open unless the run was
+// requested manually; a manual success closes the circuit again. slot is
+// held only while fetching.
+func (s *rssSyncer) syncWithRetry(ctx context.Context, src news.Source, trigger string, slot *crawler.HostSlot) {
+	if trigger != triggerManual {
+		if ok, until := s.breaker.Allow(src.ID, time.Now()); !ok {
+			log.Printf("event=rss_sync status=skipped source=%s reason=circuit_open until=%s", src.ID, until.UTC().Format(time.RFC3339))
+			s.deferPoll(ctx, src.ID, until)
+			return
+		}
+	}
+	run, err := s.runs.CreateCrawlRun(ctx, news.CrawlRun{SourceID: src.ID, Trigger: trigger, Status: news.CrawlRunRunning, StartedAt: time.Now().UTC()})
+	if err != nil {
+		log.Printf("event=crawl_run status=error source=%s err=%v", src.ID, err)
+	}
+	attempts := s.cfg.RSSMaxRetries + 1
+	if attempts < 1 {
+		attempts = 1
+	}
+	run.Status = news.CrawlRunFailed
+	var res syncResult
+	for i := 1; i <= attempts; i++ {
+		run.Attempts = i
+		var err error
+		res, err = s.syncOnce(ctx, src, slot)
+		if err != nil {
+			log.Printf("rss sync source=%s attempt=%d/%d failed: %v", src.ID, i, attempts, err)
+			run.Errors = append(run.Errors, fmt.Sprintf("attempt %d: %v", i, err))
+			if errors.Is(err, crawler.ErrFeedRejected) {
+				run.Rejected = err.Error()
+				break
+			}
+			if i == attempts || errors.Is(err, crawler.ErrDisallowed) {
+				break
+			}
+			delay, ok := s.backoff.RetryDelay(err, i)
+			if !ok {
+				until := time.Now().Add(delay)
+				s.breaker.Trip(src.ID, until)
+				run.Errors = append(run.Errors, fmt.Sprintf("parked until %s as requested by Retry-After", until.UTC().Format(time.RFC3339)))
+				break
+			}
+			if err := crawler.Sleep(ctx, delay); err != nil {
+				run.Errors = append(run.Errors, fmt.Sprintf("retry cancelled: %v", err))
+				break
+			}
+			continue
+		}
+		run.Status = news.CrawlRunOK
+		run.Fetched, run.Inserted, run.Updated, run.Truncated = res.fetched, res.Inserted, res.Updated, res.truncated
+		break
+	}
+	if run.Status == news.CrawlRunOK && src.ExtractFullText {
+		s.extractBodies(ctx, src)
+	}
+	if run.Status == news.CrawlRunOK && s.websub != nil && res.hub != "" {
+		if err := s.websub.Ensure(ctx, src.ID, res.hub, res.topic); err != nil {
+			log.Printf("event=websub status=error source=%s hub=%s err=%v", src.ID, res.hub, err)
+		}
+	}
+	if run.Status == news.CrawlRunOK {
+		s.breaker.Success(src.ID)
+	} else if until := s.breaker.Failure(src.ID, time.Now()); !until.IsZero() {
+		log.Printf("event=rss_sync status=parked source=%s until=%s", src.ID, until.UTC().Format(time.RFC3339))
+	}
+	finished := time.Now().UTC()
+	run.FinishedAt = &finished
+	if run.ID != 0 {
+		if err := s.runs.UpdateCrawlRun(ctx, run); err != nil {
+			log.Printf("event=crawl_run status=error source=%s err=%v", src.ID, err)
+		}
+	}
+	s.recordHealth(ctx, src.ID, run, res)
+}
+
+// recordHealth also schedules the source's next poll, counted from the end
+// of this run.
+func (s *rssSyncer) recordHealth(ctx context.Context, sourceID string, run news.CrawlRun, res syncResult) {
+	h, err := s.health.GetSourceHealth(ctx, sourceID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("event=source_health status=error source=%s err=%v", sourceID, err)
+		return
+	}
+	h.SourceID = sourceID
+	h.Observe(run, res.newest)
+	interval := s.pollInterval(ctx, sourceID, run.Status == news.CrawlRunOK, res.refresh, h.PollIntervalSec)
+	next := h.LastAttemptAt.Add(interval)
+	h.PollIntervalSec, h.NextPollAt = int(interval/time.Second), &next
+	if err := s.health.SaveSourceHealth(ctx, h); err != nil {
+		log.Printf("event=source_health status=error source=%s err=%v", sourceID, err)
+	}
+}
+
+// pollInterval learns a source's cadence from its latest stored articles.
+// A failed run keeps the previous interval, since retries and the breaker
+// already deal with failures.
+func (s *rssSyncer) pollInterval(ctx context.Context, sourceID string, ok bool, hint time.Duration, prevSec int) time.Duration {
+	if !ok && prevSec > 0 {
+		return time.Duration(prevSec) * time.Second
+	}
+	recent, err := s.repo.ListArticles(ctx, storage.ListOptions{Source: sourceID, Limit: cadenceSamples})
+	if err != nil {
+		log.Printf("event=poll_schedule status=error source=%s err=%v", sourceID, err)
+	}
+	published := make([]time.Time, 0, len(recent))
+	for _, a := range recent {
+		published = append(published, a.PublishedAt)
+	}
+	return s.poll.Interval(published, hint, time.Now())
+}
+
+// deferPoll moves a source's next poll to at, e.g. when its circuit is
+// open, so the scheduler does not pick it up on every tick.
+func (s *rssSyncer) deferPoll(ctx context.Context, sourceID string, at time.Time) {
+	h, err := s.health.GetSourceHealth(ctx, sourceID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("event=source_health status=error source=%s err=%v", sourceID, err)
+		return
+	}
+	at = at.UTC()
+	h.SourceID, h.NextPollAt = sourceID, &at
+	if err := s.health.SaveSourceHealth(ctx, h); err != nil {
+		log.Printf("event=source_health status=error source=%s err=%v", sourceID, err)
+	}
+}
+
+type syncResult struct {
+	storage.UpsertResult
+	fetched int
+	// truncated counts items dropped for exceeding FEED_MAX_ITEMS.
+	truncated int
+	newest    time.Time
+	// hub and topic are set when a feed advertises a WebSub hub.
+	hub, topic string
+	// refresh is the feed's own polling hint.
+	refresh time.Duration
+}
+
+func (s *rssSyncer) syncOnce(ctx context.Context, src news.Source, slot *crawler.HostSlot) (syncResult, error) {
+	callCtx, cancel := context.WithTimeout(ctx, callTimeout(src))
+	defer cancel()
+	if err := slot.Acquire(callCtx); err != nil {
+		return syncResult{}, err
+	}
+	feed, err := s.fetch.Fetch(callCtx, src, s.cfg.RSSUserAgent)
+	slot.Release()
+	if err != nil {
+		return syncResult{}, err
+	}
+	if feed.Truncated > 0 {
+		log.Printf("event=feed_limit status=truncated source=%s kept=%d skipped=%d", src.ID, len(feed.Items), feed.Truncated)
+	}
+	topic := feed.Self
+	if topic == "" {
+		topic = src.FeedURL
+	}
+	res, err := s.store(callCtx, src, feed.Items)
+	if err != nil {
+		return syncResult{}, err
+	}
+	if feed.Commit != nil {
+		if err := feed.Commit(callCtx); err != nil {
+			return syncResult{}, err
+		}
+	}
+	res.hub, res.topic, res.refresh, res.truncated = feed.Hub, topic, feed.Refresh, feed.Truncated
+	log.Printf("event=rss_sync status=ok source=%s fetched=%d inserted=%d updated=%d revised=%d", src.ID, res.fetched, res.Inserted, res.Updated, res.Revised)
+	return res, nil
+}
+
+// store cleans fetched or pushed items and upserts them for src.
+func (s *rssSyncer) store(ctx context.Context, src news.Source, items []news.Article) (syncResult, error) {
+	res := syncResult{fetched: len(items)}
+	if len(items) == 0 {
+		return res, nil
+	}
+	for i := range items {
+		items[i].Source = src.ID
+		items[i].Title = sanitize.Inline(items[i].Title)
+		for j := range items[i].Authors {
+			items[i].Authors[j] = sanitize.Inline(items[i].Authors[j])
+		}
+		for j := range items[i].Categories {
+			items[i].Categories[j] = sanitize.Inline(items[i].Categories[j])
+		}
+		items[i].Content, items[i].ContentText = sanitize.HTML(items[i].Content, items[i].URL), sanitize.Text(items[i].Content)
+		lang := langid.Resolve(items[i].Title+"\n"+items[i].ContentText, items[i].Language)
+		items[i].Language, items[i].LanguageConfidence = lang.Code, lang.Confidence
+		if items[i].PublishedAt.After(res.newest) {
+			res.newest = items[i].PublishedAt
+		}
+	}
+	var err error
+	res.UpsertResult, err = s.repo.UpsertArticles(ctx, items)
+	if err != nil {
+		return syncResult{}, err
+	}
+	return res, nil
+}
+
+// receivePush stores a feed body a WebSub hub pushed and records it as a
+// crawl run with trigger "push". Pushes for disabled or deleted sources
+// are refused so the hub stops sending them.
+func (s *rssSyncer) receivePush(ctx context.Context, sourceID string, body []byte) error {
+	src, err := s.sources.GetSource(ctx, sourceID)
+	if errors.Is(err, storage.ErrNotFound) || err == nil && !src.Enabled {
+		return websub.ErrUnknownSubscription
+	}
+	if err != nil {
+		return err
+	}
+	started := time.Now().UTC()
+	run := news.CrawlRun{SourceID: src.ID, Trigger: triggerPush, Status: news.CrawlRunOK, StartedAt: started, Attempts: 1}
+	feed, err := s.limits.Parse(bytes.NewReader(body), started)
+	var res syncResult
+	if err == nil {
+		res, err = s.store(ctx, src, feed.Items)
+		run.Truncated = feed.Truncated
+	}
+	if err != nil {
+		run.Status, run.Errors = news.CrawlRunFailed, []string{err.Error()}
+		if errors.Is(err, crawler.ErrFeedRejected) {
+			run.Rejected = err.Error()
+		}
+	}
+	run.Fetched, run.Inserted, run.Updated = res.fetched, res.Inserted, res.Updated
+	finished := time.Now().UTC()
+	run.FinishedAt = &finished
+	if _, runErr := s.runs.CreateCrawlRun(ctx, run); runErr != nil {
+		log.Printf("event=crawl_run status=error source=%s err=%v", src.ID, runErr)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("event=websub_push status=ok source=%s fetched=%d inserted=%d updated=%d revised=%d", src.ID, res.fetched, res.Inserted, res.Updated, res.Revised)
+	return nil
+}
+
+// extractBodies fetches the pages of articles not yet extracted, newest
+// first. Every article is tried once: failures are recorded as a status
+// rather than retried, so a broken page never costs more than one request.
+func (s *rssSyncer) extractBodies(ctx context.Context, src news.Source) {
+	if s.bodies == nil || s.cfg.ExtractMaxPerRun <= 0 {
+		return
+	}
+	pending, err := s.bodies.PendingExtractions(ctx, src.ID, s.cfg.ExtractMaxPerRun)
+	if err != nil {
+		log.Printf("event=extract status=error source=%s err=%v", src.ID, err)
+		return
+	}
+	counts := map[string]int{}
+	for i, a := range pending {
+		if i > 0 {
+			if err := crawler.Sleep(ctx, time.Duration(s.cfg.CrawlHostMinDelayMS)*time.Millisecond); err != nil {
+				return
+			}
+		}
+		callCtx, cancel := context.WithTimeout(crawler.WithSource(ctx, src), callTimeout(src))
+		body, err := s.extract.Extract(callCtx, a.URL)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		status := news.ExtractionOK
+		switch {
+		case errors.Is(err, crawler.ErrDisallowed):
+			status = news.ExtractionDisallowed
+		case errors.Is(err, extract.ErrNoContent):
+			status = news.ExtractionEmpty
+		case err != nil:
+			status = news.ExtractionFailed
+			log.Printf("event=extract status=failed source=%s url=%s err=%v", src.ID, a.URL, err)
+		}
+		if err := s.bodies.SaveExtraction(ctx, a.ID, body, status); err != nil {
+			log.Printf("event=extract status=error source=%s id=%d err=%v", src.ID, a.ID, err)
+			continue
+		}
+		counts[status]++
+	}
+	if len(pending) > 0 {
+		log.Printf("event=extract status=done source=%s ok=%d empty=%d failed=%d disallowed=%d", src.ID,
+			counts[news.ExtractionOK], counts[news.ExtractionEmpty], counts[news.ExtractionFailed], counts[news.ExtractionDisallowed])
+	}
+}
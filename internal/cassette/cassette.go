@@ -0,0 +1,200 @@
+// Package cassette records HTTP exchanges to JSON files and replays them,
+// so crawler code can be tested against real responses without a network.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrNoInteraction is returned by a Replayer for a request the cassette
+// has no recording of.
+var ErrNoInteraction = errors.New("cassette: no recorded interaction")
+
+// secretHeaders are never written to a cassette.
+var secretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Cassette is an ordered list of recorded request/response pairs.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+}
+
+// Response holds a UTF-8 body as text and anything else (gzip, legacy
+// charsets) as base64 in BodyBytes.
+type Response struct {
+	Status    int         `json:"status"`
+	Header    http.Header `json:"header,omitempty"`
+	Body      string      `json:"body,omitempty"`
+	BodyBytes []byte      `json:"body_bytes,omitempty"`
+}
+
+func (r Response) body() []byte {
+	if r.BodyBytes != nil {
+		return r.BodyBytes
+	}
+	return []byte(r.Body)
+}
+
+func Load(path string) (*Cassette, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette as indented JSON, creating parent directories.
+func (c *Cassette) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// Recorder is a RoundTripper that passes requests to Next and keeps a copy
+// of every response it gets back. Transport errors are not recorded.
+type Recorder struct {
+	Next http.RoundTripper
+
+	mu sync.Mutex
+	c  Cassette
+}
+
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{Next: next}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	rec := Response{Status: resp.StatusCode, Header: scrub(resp.Header)}
+	// The transport only decompresses bodies it asked to be compressed, so
+	// a body still marked as encoded is kept byte for byte.
+	if utf8.Valid(body) && resp.Header.Get("Content-Encoding") == "" {
+		rec.Body = string(body)
+	} else {
+		rec.BodyBytes = body
+	}
+	r.mu.Lock()
+	r.c.Interactions = append(r.c.Interactions, Interaction{
+		Request:  Request{Method: req.Method, URL: req.URL.String(), Header: scrub(req.Header)},
+		Response: rec,
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Cassette returns a copy of what has been recorded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{Interactions: append([]Interaction(nil), r.c.Interactions...)}
+}
+
+func scrub(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	out := h.Clone()
+	for _, k := range secretHeaders {
+		out.Del(k)
+	}
+	return out
+}
+
+// Replayer is a RoundTripper that answers from a cassette. Requests are
+// matched on method and URL; repeated requests for the same URL get the
+// recordings in order, and the last one again once they run out.
+type Replayer struct {
+	mu   sync.Mutex
+	c    *Cassette
+	next map[string]int
+}
+
+func NewReplayer(c *Cassette) *Replayer {
+	return &Replayer{c: c, next: map[string]int{}}
+}
+
+// Client returns an http.Client that replays c.
+func Client(c *Cassette) *http.Client {
+	return &http.Client{Transport: NewReplayer(c)}
+}
+
+func (p *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	key := req.Method + " " + req.URL.String()
+	p.mu.Lock()
+	var matches []Response
+	for _, it := range p.c.Interactions {
+		if it.Request.Method+" "+it.Request.URL == key {
+			matches = append(matches, it.Response)
+		}
+	}
+	if len(matches) == 0 {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("%w for %s", ErrNoInteraction, key)
+	}
+	i := p.next[key]
+	if i < len(matches)-1 {
+		p.next[key] = i + 1
+	}
+	p.mu.Unlock()
+	rec := matches[i]
+	body := rec.body()
+	header := rec.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(rec.Status) + " " + http.StatusText(rec.Status),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
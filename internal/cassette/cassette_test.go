@@ -0,0 +1,85 @@
+package cassette
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Set-Cookie", "session=secret")
+		switch r.URL.Path {
+		case "/latin1.xml":
+			w.Header().Set("Content-Type", "application/xml; charset=iso-8859-1")
+			w.Write([]byte("<t>caf\xe9</t>"))
+		default:
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write([]byte("<rss>" + string(rune('0'+hits)) + "</rss>"))
+		}
+	}))
+	defer srv.Close()
+
+	rec := NewRecorder(nil)
+	client := &http.Client{Transport: rec}
+	for _, path := range []string{"/feed.xml", "/feed.xml", "/latin1.xml"} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer token")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	path := filepath.Join(t.TempDir(), "sub", "feed.json")
+	if err := rec.Cassette().Save(path); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Interactions) != 3 {
+		t.Fatalf("recorded %d interactions", len(c.Interactions))
+	}
+	first := c.Interactions[0]
+	if first.Request.Header.Get("Authorization") != "" || first.Response.Header.Get("Set-Cookie") != "" {
+		t.Errorf("secrets recorded: %+v", first)
+	}
+	if first.Response.Body != "<rss>1</rss>" || c.Interactions[2].Response.BodyBytes == nil {
+		t.Errorf("bodies: %q %q", first.Response.Body, c.Interactions[2].Response.BodyBytes)
+	}
+
+	replay := Client(c)
+	get := func(path string) string {
+		t.Helper()
+		resp, err := replay.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || resp.Request == nil {
+			t.Errorf("replayed response: %d %v", resp.StatusCode, resp.Request)
+		}
+		return string(b)
+	}
+	srv.Close()
+	for i, want := range []string{"<rss>1</rss>", "<rss>2</rss>", "<rss>2</rss>"} {
+		if got := get("/feed.xml"); got != want {
+			t.Errorf("replay %d = %q, want %q", i, got, want)
+		}
+	}
+	if got := get("/latin1.xml"); got != "<t>caf\xe9</t>" {
+		t.Errorf("binary body = %q", got)
+	}
+	if _, err := replay.Get(srv.URL + "/missing"); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("unrecorded request: %v", err)
+	}
+}
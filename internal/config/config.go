@@ -1,26 +1,127 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+)
 
 type Config struct {
-	AppEnv             string
-	HTTPAddr           string
-	DBPath             string
-	RSSFeedURL         string
-	RSSUserAgent       string
+	AppEnv       string
+	HTTPAddr     string
+	DBPath       string
+	SourcesPath  string
+	RSSFeedURL   string
+	RSSUserAgent string
+	// RSSSyncIntervalSec is the polling interval for sources whose cadence
+	// is not known yet; 0 disables scheduled polling. Learned intervals stay
+	// within PollMinIntervalSec and PollMaxIntervalSec.
 	RSSSyncIntervalSec int
+	PollMinIntervalSec int
+	PollMaxIntervalSec int
 	RSSMaxRetries      int
+	// Retries back off exponentially from RSSBackoffBaseMS up to
+	// RSSBackoffMaxMS. After BreakerThreshold failed runs in a row a source
+	// is skipped for BreakerCooldownSec.
+	RSSBackoffBaseMS   int
+	RSSBackoffMaxMS    int
+	BreakerThreshold   int
+	BreakerCooldownSec int
+	// CrawlWorkers caps concurrent source crawls; per host at most
+	// CrawlHostMaxInFlight run at once, started CrawlHostMinDelayMS apart.
+	CrawlWorkers         int
+	CrawlHostMaxInFlight int
+	CrawlHostMinDelayMS  int
+	// CrawlAllowPrivate lets crawling and extraction reach loopback,
+	// private and link-local addresses; by default only public ones.
+	CrawlAllowPrivate bool
+	// RobotsEnabled checks every crawl request against robots.txt, cached
+	// per host for RobotsCacheTTLSec.
+	RobotsEnabled     bool
+	RobotsCacheTTLSec int
+	// A source is stale once it has failed SourceStaleFailures runs in a row
+	// or its newest article is older than SourceStaleAfterSec.
+	SourceStaleAfterSec int
+	SourceStaleFailures int
+	// ReadyzStaleRatio makes /readyz report 503 when at least this share of
+	// enabled sources is stale; 0 disables the check.
+	ReadyzStaleRatio float64
+	// ExtractMaxPerRun caps article pages fetched for full-text extraction
+	// per source and run; the rest are picked up by later runs.
+	ExtractMaxPerRun int
+	// FeedMaxBytes caps a fetched or pushed feed document, FeedMaxItems
+	// the items kept from it and FeedMaxFieldBytes an item's content.
+	FeedMaxBytes      int
+	FeedMaxItems      int
+	FeedMaxFieldBytes int
+	// WebSubCallbackURL is the public base URL hubs use to reach this
+	// server; empty disables WebSub subscriptions.
+	WebSubCallbackURL string
+	// WebSubLeaseSec is the subscription lease asked of hubs.
+	WebSubLeaseSec int
+	// Articles older than RetentionMaxAgeDays, beyond the newest
+	// RetentionMaxPerSource of their source or the newest RetentionMaxRows
+	// overall are archived to RetentionArchiveDir and deleted every
+	// RetentionIntervalSec, at most RetentionMaxPerRun at a time. 0
+	// disables a limit; RetentionDryRun only logs what would be removed.
+	RetentionMaxAgeDays   int
+	RetentionMaxPerSource int
+	RetentionMaxRows      int
+	RetentionIntervalSec  int
+	RetentionMaxPerRun    int
+	RetentionArchiveDir   string
+	RetentionDryRun       bool
+	// FileSourceRoot is the directory file:// and mailbox sources must lie
+	// within; empty disables them.
+	FileSourceRoot string
+	// CAFileDir holds the PEM bundles sources may name as ca_file; empty
+	// disables ca_file.
+	CAFileDir string
+	// SecretEnvPrefix starts the names of the environment variables that
+	// sources may name for passwords and tokens.
+	SecretEnvPrefix string
 }
 
 func Load() Config {
 	return Config{
-		AppEnv:             getEnv("APP_ENV", "dev"),
-		HTTPAddr:           getEnv("HTTP_ADDR", ":8080"),
-		DBPath:             getEnv("DB_PATH", "./data/news.db"),
-		RSSFeedURL:         getEnv("RSS_FEED_URL", "https://hnrss.org/frontpage"),
-		RSSUserAgent:       getEnv("RSS_USER_AGENT", "news-go/1.0"),
-		RSSSyncIntervalSec: getEnvInt("RSS_SYNC_INTERVAL_SEC", 300),
-		RSSMaxRetries:      getEnvInt("RSS_MAX_RETRIES", 2),
+		AppEnv:                getEnv("APP_ENV", "dev"),
+		HTTPAddr:              getEnv("HTTP_ADDR", ":8080"),
+		DBPath:                getEnv("DB_PATH", "./data/news.db"),
+		SourcesPath:           getEnv("SOURCES_PATH", "./data/sources.json"),
+		RSSFeedURL:            getEnv("RSS_FEED_URL", "https://hnrss.org/frontpage"),
+		RSSUserAgent:          getEnv("RSS_USER_AGENT", "news-go/1.0"),
+		RSSSyncIntervalSec:    getEnvInt("RSS_SYNC_INTERVAL_SEC", 300),
+		PollMinIntervalSec:    getEnvInt("POLL_MIN_INTERVAL_SEC", 120),
+		PollMaxIntervalSec:    getEnvInt("POLL_MAX_INTERVAL_SEC", 21600),
+		RSSMaxRetries:         getEnvInt("RSS_MAX_RETRIES", 2),
+		RSSBackoffBaseMS:      getEnvInt("RSS_BACKOFF_BASE_MS", 2000),
+		RSSBackoffMaxMS:       getEnvInt("RSS_BACKOFF_MAX_MS", 60000),
+		BreakerThreshold:      getEnvInt("BREAKER_FAILURE_THRESHOLD", 5),
+		BreakerCooldownSec:    getEnvInt("BREAKER_COOLDOWN_SEC", 1800),
+		CrawlWorkers:          getEnvInt("CRAWL_WORKERS", 4),
+		CrawlHostMaxInFlight:  getEnvInt("CRAWL_HOST_MAX_INFLIGHT", 1),
+		CrawlHostMinDelayMS:   getEnvInt("CRAWL_HOST_MIN_DELAY_MS", 1000),
+		CrawlAllowPrivate:     getEnvBool("CRAWL_ALLOW_PRIVATE", false),
+		RobotsEnabled:         getEnvBool("ROBOTS_ENABLED", true),
+		RobotsCacheTTLSec:     getEnvInt("ROBOTS_CACHE_TTL_SEC", 86400),
+		SourceStaleAfterSec:   getEnvInt("SOURCE_STALE_AFTER_SEC", 86400),
+		SourceStaleFailures:   getEnvInt("SOURCE_STALE_FAILURES", 3),
+		ReadyzStaleRatio:      getEnvFloat("READYZ_STALE_RATIO", 0),
+		ExtractMaxPerRun:      getEnvInt("EXTRACT_MAX_PER_RUN", 20),
+		FeedMaxBytes:          getEnvInt("FEED_MAX_BYTES", 16<<20),
+		FeedMaxItems:          getEnvInt("FEED_MAX_ITEMS", 1000),
+		FeedMaxFieldBytes:     getEnvInt("FEED_MAX_FIELD_BYTES", 1<<20),
+		WebSubCallbackURL:     getEnv("WEBSUB_CALLBACK_URL", ""),
+		WebSubLeaseSec:        getEnvInt("WEBSUB_LEASE_SEC", 86400),
+		RetentionMaxAgeDays:   getEnvInt("RETENTION_MAX_AGE_DAYS", 0),
+		RetentionMaxPerSource: getEnvInt("RETENTION_MAX_PER_SOURCE", 0),
+		RetentionMaxRows:      getEnvInt("RETENTION_MAX_ROWS", 0),
+		RetentionIntervalSec:  getEnvInt("RETENTION_INTERVAL_SEC", 3600),
+		RetentionMaxPerRun:    getEnvInt("RETENTION_MAX_PER_RUN", 10000),
+		RetentionArchiveDir:   getEnv("RETENTION_ARCHIVE_DIR", "./data/archive"),
+		RetentionDryRun:       getEnvBool("RETENTION_DRY_RUN", false),
+		FileSourceRoot:        getEnv("FILE_SOURCE_ROOT", ""),
+		CAFileDir:             getEnv("CA_FILE_DIR", ""),
+		SecretEnvPrefix:       getEnv("SECRET_ENV_PREFIX", "NEWS_SECRET_"),
 	}
 }
 
@@ -44,3 +145,25 @@ func getEnvInt(key string, fallback int) int {
 	}
 	return fallback
 }
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			return fallback
+		}
+		return f
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if v := os.Getenv(key); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fallback
+		}
+		return b
+	}
+	return fallback
+}
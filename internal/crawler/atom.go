@@ -0,0 +1,140 @@
+package crawler
+
+import (
+	"html"
+	"strings"
+	"time"
+
+	"news-go/internal/htmldoc"
+	"news-go/internal/news"
+)
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     atomText   `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Summary   atomText   `xml:"summary"`
+	Content   atomText   `xml:"content"`
+	Lang      string     `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Authors   []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Categories []struct {
+		Term  string `xml:"term,attr"`
+		Label string `xml:"label,attr"`
+	} `xml:"category"`
+	Thumbnails []rssMedia `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	Media      []rssMedia `xml:"http://search.yahoo.com/mrss/ content"`
+	Groups     []struct {
+		Media []rssMedia `xml:"http://search.yahoo.com/mrss/ content"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+// atomText is an Atom text construct: plain text, escaped HTML or inline
+// XHTML depending on its type attribute.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// html returns the construct as HTML.
+func (t atomText) html() string {
+	switch strings.ToLower(t.Type) {
+	case "html":
+		return t.Text
+	case "xhtml":
+		return t.Inner
+	}
+	return html.EscapeString(strings.TrimSpace(t.Text))
+}
+
+// plain returns the construct as one line of text; only the html and
+// xhtml types carry markup to strip.
+func (t atomText) plain() string {
+	text := t.Text
+	switch strings.ToLower(t.Type) {
+	case "html":
+		text = htmldoc.ParseString(t.Text).InnerText()
+	case "xhtml":
+		text = htmldoc.ParseString(t.Inner).InnerText()
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// article maps an entry, dated by <published> or else <updated>, and
+// undated otherwise. The feed's xml:lang is filled in by the caller.
+func (e atomEntry) article(now time.Time) news.Article {
+	var published time.Time
+	if t, ok := parseW3CDate(e.Published); ok {
+		published = t
+	} else if t, ok := parseW3CDate(e.Updated); ok {
+		published = t
+	}
+	content := e.Content.html()
+	if strings.TrimSpace(content) == "" {
+		content = e.Summary.html()
+	}
+	a := news.Article{
+		Title:       e.Title.plain(),
+		URL:         atomAlternate(e.Links),
+		Source:      "atom",
+		Content:     content,
+		PublishedAt: published,
+		Language:    news.NormalizeLanguage(e.Lang),
+		GUID:        strings.TrimSpace(e.ID),
+		FetchedAt:   now,
+	}
+	var authors, categories []string
+	for _, au := range e.Authors {
+		authors = append(authors, au.Name)
+	}
+	for _, c := range e.Categories {
+		if c.Label != "" {
+			categories = append(categories, c.Label)
+		} else {
+			categories = append(categories, c.Term)
+		}
+	}
+	a.Authors, a.Categories = uniqueTrimmed(authors), uniqueTrimmed(categories)
+	for _, l := range e.Links {
+		if !hasToken(l.Rel, "enclosure") {
+			continue
+		}
+		if strings.HasPrefix(l.Type, "image/") && a.ImageURL == "" {
+			a.ImageURL = strings.TrimSpace(l.Href)
+		}
+		a.Media = addMedia(a.Media, l.Href, l.Type, "", l.Length, "")
+	}
+	media := e.Media
+	for _, g := range e.Groups {
+		media = append(media, g.Media...)
+	}
+	for _, m := range media {
+		a.Media = addMedia(a.Media, m.URL, m.Type, m.Medium, m.FileSize, m.Duration)
+	}
+	if a.ImageURL == "" && len(e.Thumbnails) > 0 {
+		a.ImageURL = strings.TrimSpace(e.Thumbnails[0].URL)
+	}
+	return a
+}
+
+// atomAlternate returns the entry's alternate link, which is also what a
+// link without rel means; an HTML one wins over other types.
+func atomAlternate(links []atomLink) string {
+	best := ""
+	for _, l := range links {
+		if l.Rel != "" && !hasToken(l.Rel, "alternate") {
+			continue
+		}
+		if l.Type == "" || strings.Contains(l.Type, "html") {
+			return strings.TrimSpace(l.Href)
+		}
+		if best == "" {
+			best = strings.TrimSpace(l.Href)
+		}
+	}
+	return best
+}
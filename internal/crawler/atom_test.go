@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"news-go/internal/news"
+)
+
+const atomDoc = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="en">
+  <title>Lab notes</title>
+  <link rel="self" href="https://lab.test/atom.xml"/>
+  <link rel="hub" href="https://hub.test/"/>
+  <entry>
+    <id>tag:lab.test,2026:1</id>
+    <title type="html">Results &amp;amp; methods</title>
+    <link rel="alternate" type="text/html" href="https://lab.test/1"/>
+    <link rel="enclosure" type="image/png" href="https://lab.test/1.png"/>
+    <updated>2026-10-18T09:30:00Z</updated>
+    <author><name>Grace Hopper</name></author>
+    <category term="ml" label="Machine learning"/>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Body</p></div></content>
+  </entry>
+  <entry>
+    <title>Plain &lt;text&gt;</title>
+    <link href="https://lab.test/2"/>
+    <published>2026-10-17T08:00:00+02:00</published>
+    <summary>A &lt;b&gt; summary</summary>
+  </entry>
+</feed>`
+
+func TestParseAtom(t *testing.T) {
+	feed, err := ParseFeed([]byte(atomDoc), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Lab notes" || feed.Hub != "https://hub.test/" || feed.Self != "https://lab.test/atom.xml" || len(feed.Items) != 2 {
+		t.Fatalf("feed: %+v", feed)
+	}
+	one, two := feed.Items[0], feed.Items[1]
+	if one.Title != "Results & methods" || one.URL != "https://lab.test/1" || one.GUID != "tag:lab.test,2026:1" || one.ImageURL != "https://lab.test/1.png" || one.Language != "en" {
+		t.Errorf("entry one: %+v", one)
+	}
+	if !reflect.DeepEqual(one.Authors, []string{"Grace Hopper"}) || !reflect.DeepEqual(one.Categories, []string{"Machine learning"}) {
+		t.Errorf("authors/categories: %v %v", one.Authors, one.Categories)
+	}
+	if one.PublishedAt.Format(time.RFC3339) != "2026-10-18T09:30:00Z" || one.Content != `<div xmlns="http://www.w3.org/1999/xhtml"><p>Body</p></div>` {
+		t.Errorf("date/content: %v %q", one.PublishedAt, one.Content)
+	}
+	if two.URL != "https://lab.test/2" || two.Title != "Plain <text>" || two.Content != "A &lt;b&gt; summary" || two.PublishedAt.Format(time.RFC3339) != "2026-10-17T06:00:00Z" {
+		t.Errorf("entry two: %+v", two)
+	}
+}
+
+func TestParseJSONFeed(t *testing.T) {
+	body := `{"version":"https://jsonfeed.org/version/1.1","title":"JF","feed_url":"https://jf.test/feed.json",
+"hubs":[{"type":"WebSub","url":"https://hub.test/"}],"language":"de",
+"items":[{"id":"1","url":"https://jf.test/1","title":"Eins","content_text":"a < b","date_published":"2026-10-18T10:00:00Z","authors":[{"name":"Ada"},{"name":"Alan"}],"tags":["x"]},
+{"id":"2","title":"no url"}]}`
+	feed, err := ParseFeed([]byte(body), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "JF" || feed.Hub != "https://hub.test/" || feed.Self != "https://jf.test/feed.json" || len(feed.Items) != 1 {
+		t.Fatalf("feed: %+v", feed)
+	}
+	a := feed.Items[0]
+	if a.Content != "a &lt; b" || a.Language != "de" || a.GUID != "1" || !reflect.DeepEqual(a.Authors, []string{"Ada", "Alan"}) || a.PublishedAt.Format(time.RFC3339) != "2026-10-18T10:00:00Z" {
+		t.Errorf("item: %+v", a)
+	}
+}
+
+func TestJSONAPIFetcherMapsFields(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"posts":[
+{"headline":"Rates held","link":"/news/rates","teaser":"Central bank","ts":1792317600,"byline":{"name":"Desk"}},
+{"headline":"No link"}]}}`))
+	}))
+	defer srv.Close()
+	src := news.Source{ID: "cb", Kind: KindJSONAPI, FeedURL: srv.URL + "/api/v1/news",
+		Options: []byte(`{"items":"data.posts","title":"headline","url":"link","summary":"teaser","published":"ts","authors":"byline.name"}`)}
+	feed, err := NewFetchers(srv.Client()).Fetch(context.Background(), src, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Items) != 1 {
+		t.Fatalf("got %d items", len(feed.Items))
+	}
+	a := feed.Items[0]
+	if a.Title != "Rates held" || a.URL != srv.URL+"/news/rates" || a.Content != "Central bank" || a.Authors[0] != "Desk" || !a.PublishedAt.Equal(time.Unix(1792317600, 0)) {
+		t.Errorf("item: %+v", a)
+	}
+}
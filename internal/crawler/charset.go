@@ -0,0 +1,113 @@
+package crawler
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// windows1252 maps the bytes 0x80-0x9F where Windows-1252 differs from
+// ISO-8859-1; the rest of the two encodings match Unicode's first 256
+// code points.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// gbkTable is the two-byte part of GB18030, which contains GBK and
+// GB2312: one big-endian UTF-16 unit per lead byte 0x81-0xFE and trail
+// byte 0x40-0xFE, 0xFFFD where the pair is unassigned. It is generated
+// from the WHATWG gb18030 index.
+//
+//go:generate go run ./internal/gen -o gbk.bin
+//go:embed gbk.bin
+var gbkTable string
+
+// charsetReader converts the charsets feeds and newsletters commonly use
+// to UTF-8. ISO-8859-1 is read as Windows-1252, as browsers do, since
+// servers often label the latter as the former.
+func charsetReader(charset string, r io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return r, nil
+	case "iso-8859-1", "latin1", "iso8859-1", "windows-1252", "cp1252":
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		var out strings.Builder
+		out.Grow(len(b))
+		for _, c := range b {
+			if c >= 0x80 && c <= 0x9F {
+				out.WriteRune(windows1252[c-0x80])
+			} else {
+				out.WriteRune(rune(c))
+			}
+		}
+		return strings.NewReader(out.String()), nil
+	case "gb2312", "gbk", "gb18030", "x-gbk", "cp936", "euc-cn":
+		return &gbkReader{r: bufio.NewReader(r)}, nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
+
+// gbkReader decodes GBK as it is read. Four-byte GB18030 sequences,
+// outside GBK and rare in news text, become U+FFFD.
+type gbkReader struct {
+	r   *bufio.Reader
+	out []byte
+	err error
+}
+
+func (g *gbkReader) Read(p []byte) (int, error) {
+	for len(g.out) < len(p) && g.err == nil {
+		g.decode()
+	}
+	if len(g.out) == 0 {
+		return 0, g.err
+	}
+	n := copy(p, g.out)
+	g.out = g.out[n:]
+	return n, nil
+}
+
+// decode appends the next character to out, or sets err.
+func (g *gbkReader) decode() {
+	c, err := g.r.ReadByte()
+	if err != nil {
+		g.err = err
+		return
+	}
+	switch {
+	case c < 0x80:
+		g.out = append(g.out, c)
+		return
+	case c == 0x80:
+		g.out = utf8.AppendRune(g.out, '€')
+		return
+	case c == 0xFF:
+		g.out = utf8.AppendRune(g.out, utf8.RuneError)
+		return
+	}
+	t, err := g.r.ReadByte()
+	switch {
+	case err != nil:
+		g.out = utf8.AppendRune(g.out, utf8.RuneError)
+		g.err = err
+	case t >= 0x30 && t <= 0x39:
+		_, g.err = g.r.Discard(2)
+		g.out = utf8.AppendRune(g.out, utf8.RuneError)
+	case t < 0x40 || t == 0xFF:
+		// An ASCII byte after a lead byte starts the next character.
+		if t < 0x80 {
+			_ = g.r.UnreadByte()
+		}
+		g.out = utf8.AppendRune(g.out, utf8.RuneError)
+	default:
+		i := (int(c-0x81)*191 + int(t-0x40)) * 2
+		g.out = utf8.AppendRune(g.out, rune(gbkTable[i])<<8|rune(gbkTable[i+1]))
+	}
+}
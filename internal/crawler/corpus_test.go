@@ -0,0 +1,135 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"news-go/internal/cassette"
+	"news-go/internal/news"
+	"news-go/internal/sanitize"
+)
+
+var updateCorpus = flag.Bool("update", false, "rewrite testdata/corpus golden files")
+
+const corpusDir = "testdata/corpus"
+
+// corpusResult is what a replayed source is pinned to. Content is reduced
+// to its sanitized text so markup-only changes don't churn the files.
+type corpusResult struct {
+	Error         string       `json:"error,omitempty"`
+	Title         string       `json:"title,omitempty"`
+	Hub           string       `json:"hub,omitempty"`
+	Self          string       `json:"self,omitempty"`
+	Refresh       string       `json:"refresh,omitempty"`
+	Items         []corpusItem `json:"items"`
+	DuplicateURLs []string     `json:"duplicate_urls,omitempty"`
+}
+
+type corpusItem struct {
+	Title       string       `json:"title"`
+	URL         string       `json:"url"`
+	GUID        string       `json:"guid,omitempty"`
+	PublishedAt string       `json:"published_at,omitempty"`
+	Authors     []string     `json:"authors,omitempty"`
+	Categories  []string     `json:"categories,omitempty"`
+	ImageURL    string       `json:"image_url,omitempty"`
+	Media       []news.Media `json:"media,omitempty"`
+	Language    string       `json:"language,omitempty"`
+	Text        string       `json:"text,omitempty"`
+}
+
+// TestCorpus replays every recorded source in testdata/corpus (see
+// cmd/record) and compares what the fetchers make of it with the golden
+// file next to the cassette. Run with -update to accept changes.
+func TestCorpus(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join(corpusDir, "sources.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sources []news.Source
+	if err := json.Unmarshal(b, &sources); err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range sources {
+		src.Normalize()
+		t.Run(src.ID, func(t *testing.T) {
+			c, err := cassette.Load(filepath.Join(corpusDir, src.ID+".cassette.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			feed, err := NewFetchers(cassette.Client(c)).Fetch(context.Background(), src, "")
+			got := corpusSummary(feed, err)
+			goldenPath := filepath.Join(corpusDir, src.ID+".golden.json")
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(got); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.Bytes()
+			if *updateCorpus {
+				if err := os.WriteFile(goldenPath, out, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if string(want) != string(out) {
+				t.Errorf("%s differs from the replayed result (run with -update to accept):\n%s", goldenPath, out)
+			}
+		})
+	}
+}
+
+func corpusSummary(feed Feed, err error) corpusResult {
+	res := corpusResult{Items: []corpusItem{}}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Title, res.Hub, res.Self = feed.Title, feed.Hub, feed.Self
+	if feed.Refresh > 0 {
+		res.Refresh = feed.Refresh.String()
+	}
+	seen := map[string]bool{}
+	for _, a := range feed.Items {
+		it := corpusItem{
+			Title:      a.Title,
+			URL:        a.URL,
+			GUID:       a.GUID,
+			Authors:    a.Authors,
+			Categories: a.Categories,
+			ImageURL:   a.ImageURL,
+			Media:      a.Media,
+			Language:   a.Language,
+			Text:       truncateRunes(sanitize.Text(a.Content), 160),
+		}
+		if !a.PublishedAt.IsZero() {
+			it.PublishedAt = a.PublishedAt.UTC().Format(time.RFC3339)
+		}
+		if seen[a.URL] {
+			res.DuplicateURLs = append(res.DuplicateURLs, a.URL)
+		}
+		seen[a.URL] = true
+		res.Items = append(res.Items, it)
+	}
+	return res
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
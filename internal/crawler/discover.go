@@ -0,0 +1,310 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"news-go/internal/htmldoc"
+	"news-go/internal/news"
+)
+
+// FeedCandidate is one feed found for a page. Valid means the crawler's
+// feed parser read it, and Kind is the source kind to subscribe with.
+type FeedCandidate struct {
+	URL    string `json:"url"`
+	Title  string `json:"title,omitempty"`
+	Format string `json:"format,omitempty"`
+	Kind   string `json:"kind,omitempty"`
+	// Origin is "page" when the given URL is itself a feed, "link" for a
+	// <link rel="alternate"> in the page and "guess" for a common path.
+	Origin string `json:"origin"`
+	Valid  bool   `json:"valid"`
+	Items  int    `json:"items"`
+	Error  string `json:"error,omitempty"`
+}
+
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatRDF  = "rdf"
+	FormatJSON = "json"
+)
+
+// feedTypes are the <link type> values accepted as feeds.
+var feedTypes = map[string]string{
+	"application/rss+xml":   FormatRSS,
+	"application/atom+xml":  FormatAtom,
+	"application/feed+json": FormatJSON,
+	"application/json":      FormatJSON,
+	"application/rdf+xml":   FormatRDF,
+}
+
+// guessPaths are tried on the site root when the page links no feed that
+// validates.
+var guessPaths = []string{"/feed", "/rss.xml", "/feed.xml", "/rss", "/atom.xml", "/index.xml"}
+
+// maxCandidates bounds the candidate requests one discovery makes, linked
+// and guessed together.
+const maxCandidates = 12
+
+// maxDiscoverBytes bounds how much of a page or candidate feed is read.
+const maxDiscoverBytes = 4 << 20
+
+// Discoverer finds the feeds of a website. The client should be the crawl
+// client so robots.txt rules apply.
+type Discoverer struct {
+	client    *http.Client
+	userAgent string
+}
+
+func NewDiscoverer(client *http.Client, userAgent string) *Discoverer {
+	return &Discoverer{client: client, userAgent: userAgent}
+}
+
+// Discover fetches pageURL and returns its feed candidates, valid ones
+// first, then in the order page, link, guess. Guessed paths are only
+// returned when they validate.
+func (d *Discoverer) Discover(ctx context.Context, pageURL string) ([]FeedCandidate, error) {
+	body, final, err := d.get(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if format := sniffFeed(body); format != "" {
+		c := FeedCandidate{URL: final.String(), Origin: "page"}
+		c.check(body, format)
+		return []FeedCandidate{c}, nil
+	}
+	doc := htmldoc.ParseString(string(body))
+	out := []FeedCandidate{}
+	seen := map[string]bool{}
+	for _, c := range feedLinks(doc, final) {
+		if seen[c.URL] || len(seen) >= maxCandidates {
+			continue
+		}
+		seen[c.URL] = true
+		d.validate(ctx, &c)
+		out = append(out, c)
+	}
+	if !anyValid(out) {
+		root := &url.URL{Scheme: final.Scheme, Host: final.Host}
+		for _, p := range guessPaths {
+			c := FeedCandidate{URL: root.JoinPath(p).String(), Origin: "guess"}
+			if seen[c.URL] || len(seen) >= maxCandidates {
+				continue
+			}
+			seen[c.URL] = true
+			if d.validate(ctx, &c); c.Format != "" {
+				out = append(out, c)
+			}
+		}
+	}
+	rank := map[string]int{"page": 0, "link": 1, "guess": 2}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Valid != out[j].Valid {
+			return out[i].Valid
+		}
+		return rank[out[i].Origin] < rank[out[j].Origin]
+	})
+	return out, nil
+}
+
+// ErrPrivateAddress refuses a connection to a loopback, private,
+// link-local or otherwise non-public address.
+var ErrPrivateAddress = errors.New("refusing to connect to a non-public address")
+
+// PublicOnly is a net.Dialer Control hook that allows only public unicast
+// addresses. It sees the resolved address of every connection, redirects
+// and DNS names that point inwards included.
+func PublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, ip)
+	}
+	return nil
+}
+
+// sharedAddressSpace is carrier-grade NAT space (RFC 6598), not covered
+// by netip.Addr.IsPrivate.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// NewPublicTransport returns a transport for requests to caller-supplied
+// URLs, such as discovery: it dials through PublicOnly and uses no proxy,
+// which would otherwise connect on the caller's behalf.
+func NewPublicTransport() *http.Transport {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = nil
+	tr.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: PublicOnly}).DialContext
+	return tr
+}
+
+func anyValid(items []FeedCandidate) bool {
+	for _, c := range items {
+		if c.Valid {
+			return true
+		}
+	}
+	return false
+}
+
+// feedLinks collects <link rel="alternate"> feed references, resolved
+// against <base href> when the page sets one.
+func feedLinks(doc *htmldoc.Node, page *url.URL) []FeedCandidate {
+	base := page
+	if b := doc.Find("base"); b != nil {
+		if href, ok := b.Attr("href"); ok {
+			if u, err := page.Parse(strings.TrimSpace(href)); err == nil {
+				base = u
+			}
+		}
+	}
+	var out []FeedCandidate
+	for _, n := range doc.FindAll("link", "a") {
+		if !hasToken(n.AttrOr("rel"), "alternate") {
+			continue
+		}
+		mt, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(n.AttrOr("type"))), ";")
+		format, ok := feedTypes[strings.TrimSpace(mt)]
+		if !ok {
+			continue
+		}
+		href := strings.TrimSpace(n.AttrOr("href"))
+		u, err := base.Parse(href)
+		if href == "" || err != nil || u.Scheme != "http" && u.Scheme != "https" {
+			continue
+		}
+		u.Fragment = ""
+		out = append(out, FeedCandidate{
+			URL:    u.String(),
+			Title:  strings.TrimSpace(n.AttrOr("title")),
+			Format: format,
+			Origin: "link",
+		})
+	}
+	return out
+}
+
+func hasToken(list, token string) bool {
+	for _, f := range strings.Fields(list) {
+		if strings.EqualFold(f, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// validate fetches a candidate and fills in its format and item count.
+func (d *Discoverer) validate(ctx context.Context, c *FeedCandidate) {
+	body, _, err := d.get(ctx, c.URL)
+	if err != nil {
+		c.Error = err.Error()
+		return
+	}
+	format := sniffFeed(body)
+	if format == "" {
+		c.Format, c.Error = "", "not a feed"
+		return
+	}
+	c.check(body, format)
+}
+
+// check parses a feed body with the crawler's feed parser.
+func (c *FeedCandidate) check(body []byte, format string) {
+	c.Format = format
+	feed, err := ParseFeed(body, time.Now().UTC())
+	if err != nil {
+		c.Error = err.Error()
+		return
+	}
+	c.Valid, c.Items = true, len(feed.Items)
+	switch format {
+	case FormatAtom:
+		c.Kind = KindAtom
+	case FormatJSON:
+		c.Kind = KindJSONAPI
+	default:
+		c.Kind = news.KindRSS
+	}
+	if c.Title == "" {
+		c.Title = feed.Title
+	}
+}
+
+func (d *Discoverer) get(ctx context.Context, rawURL string) ([]byte, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if d.userAgent != "" {
+		req.Header.Set("User-Agent", d.userAgent)
+	}
+	req.Header.Set("Accept", "text/html,application/rss+xml,application/atom+xml,application/feed+json;q=0.9,*/*;q=0.8")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDiscoverBytes))
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, resp.Request.URL, nil
+}
+
+// sniffFeed names the feed format of body from its root element, or
+// returns "" when body is not a feed.
+func sniffFeed(body []byte) string {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")))
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var v struct {
+			Version string `json:"version"`
+		}
+		if json.Unmarshal(trimmed, &v) == nil && strings.Contains(v.Version, "jsonfeed.org") {
+			return FormatJSON
+		}
+		return ""
+	}
+	dec := xml.NewDecoder(bytes.NewReader(trimmed))
+	dec.CharsetReader = charsetReader
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		if el, ok := tok.(xml.StartElement); ok {
+			switch strings.ToLower(el.Name.Local) {
+			case "rss":
+				return FormatRSS
+			case "feed":
+				return FormatAtom
+			case "rdf":
+				return FormatRDF
+			}
+			return ""
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const discoverRSS = `<?xml version="1.0"?><rss version="2.0"><channel><title>Main feed</title>
+<item><title>a</title><link>https://ex.test/a</link></item><item><title>b</title><link>https://ex.test/b</link></item>
+</channel></rss>`
+
+func TestDiscoverRanksLinkedFeeds(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!doctype html><html><head>
+<link rel="alternate" type="application/atom+xml" href="/atom" title="Atom">
+<link rel="stylesheet" href="/s.css">
+<link rel="Alternate" type="application/rss+xml; charset=utf-8" href="/missing.xml">
+<link rel="alternate" type="application/rss+xml" href="feeds/main.xml#top">
+</head><body><a href="/feed">feed</a></body></html>`))
+	})
+	mux.HandleFunc("/atom", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"><title>x</title></feed>`))
+	})
+	mux.HandleFunc("/feeds/main.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(discoverRSS))
+	})
+	mux.HandleFunc("/missing.xml", http.NotFound)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	got, err := NewDiscoverer(srv.Client(), "test").Discover(context.Background(), srv.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d candidates: %+v", len(got), got)
+	}
+	atom, main := got[0], got[1]
+	if main.URL != srv.URL+"/feeds/main.xml" || !main.Valid || main.Items != 2 || main.Title != "Main feed" || main.Format != FormatRSS || main.Kind != "rss" {
+		t.Errorf("rss candidate: %+v", main)
+	}
+	if atom.Format != FormatAtom || !atom.Valid || atom.Title != "Atom" || atom.Kind != KindAtom {
+		t.Errorf("atom candidate: %+v", atom)
+	}
+	if got[2].URL != srv.URL+"/missing.xml" || got[2].Error == "" {
+		t.Errorf("broken candidate: %+v", got[2])
+	}
+}
+
+func TestDiscoverFallsBackToCommonPaths(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" && r.URL.Path != "/news/page" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`<html><body>no feeds here</body></html>`))
+	})
+	mux.HandleFunc("/rss.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(discoverRSS))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	got, err := NewDiscoverer(srv.Client(), "").Discover(context.Background(), srv.URL+"/news/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].URL != srv.URL+"/rss.xml" || got[0].Origin != "guess" || !got[0].Valid {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestDiscoverFeedURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(discoverRSS))
+	}))
+	defer srv.Close()
+	got, err := NewDiscoverer(srv.Client(), "").Discover(context.Background(), srv.URL+"/any")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Origin != "page" || !got[0].Valid {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestDiscoverBoundsRequests(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		var links strings.Builder
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(&links, `<link rel="alternate" type="application/rss+xml" href="/broken%d.xml">`, i)
+		}
+		fmt.Fprintf(w, "<html><head>%s</head></html>", links.String())
+	}))
+	defer srv.Close()
+
+	if _, err := NewDiscoverer(srv.Client(), "").Discover(context.Background(), srv.URL+"/"); err != nil {
+		t.Fatal(err)
+	}
+	if n := hits.Load(); n != 1+maxCandidates {
+		t.Fatalf("expected the page and %d candidates, got %d requests", maxCandidates, n)
+	}
+}
+
+func TestDiscoverRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(discoverRSS))
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: NewPublicTransport()}
+	if _, err := NewDiscoverer(client, "").Discover(context.Background(), srv.URL+"/"); !errors.Is(err, ErrPrivateAddress) {
+		t.Fatalf("expected loopback to be refused, got %v", err)
+	}
+
+	for addr, public := range map[string]bool{
+		"169.254.169.254:80":    false,
+		"10.1.2.3:80":           false,
+		"172.16.0.1:443":        false,
+		"192.168.1.1:80":        false,
+		"100.64.0.1:80":         false,
+		"0.0.0.0:80":            false,
+		"[::1]:80":              false,
+		"[fd00::1]:80":          false,
+		"[fe80::1]:80":          false,
+		"[::ffff:127.0.0.1]:80": false,
+		"93.184.216.34:443":     true,
+		"[2606:4700::1111]:443": true,
+	} {
+		if err := PublicOnly("tcp", addr, nil); (err == nil) != public {
+			t.Errorf("%s: got %v", addr, err)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"news-go/internal/htmldoc"
+	"news-go/internal/news"
+)
+
+const (
+	// KindEmail reads newsletters from a mailbox, see EmailOptions.
+	KindEmail = "email"
+
+	EmailSplitLinks   = "links"
+	EmailSplitMessage = "message"
+
+	defaultEmailSinceDays   = 14
+	defaultEmailMaxMessages = 50
+	// maxStorySummary bounds the text kept next to a story link.
+	maxStorySummary = 600
+)
+
+func init() {
+	Register(Kind{
+		Name:     KindEmail,
+		New:      func(_ *http.Client, o FetcherOptions) Fetcher { return NewEmailFetcher(o.Access) },
+		Validate: validateEmail,
+		Schemes:  []string{"file", "imap", "imaps"},
+	})
+}
+
+// EmailOptions is the options object of an email source, whose rss is a
+// file:// path to an mbox file, a Maildir or a directory of .eml files, or
+// an imap:// or imaps:// URL naming the user, server and mailbox, e.g.
+// imaps://news%40example.com@imap.example.com/Newsletters. The IMAP
+// password is read from the environment variable named by PasswordEnv,
+// which must carry the SecretEnvPrefix, so it never sits in the database.
+//
+// Messages are filtered by a case-insensitive substring of the sender
+// address and of the subject, and limited to the newest MaxMessages of the
+// last SinceDays days. Split "links" (the default) makes one article per
+// story link in a message; Stories can name the story blocks with the same
+// selectors as an html source instead of the built-in heuristics. Split
+// "message" makes one article per message.
+type EmailOptions struct {
+	From        string        `json:"from,omitempty"`
+	Subject     string        `json:"subject,omitempty"`
+	Split       string        `json:"split,omitempty"`
+	Stories     *ScrapeConfig `json:"stories,omitempty"`
+	PasswordEnv string        `json:"password_env,omitempty"`
+	SinceDays   int           `json:"since_days,omitempty"`
+	MaxMessages int           `json:"max_messages,omitempty"`
+}
+
+func validateEmail(src news.Source, access LocalAccess) error {
+	u, err := url.Parse(src.FeedURL)
+	if err != nil {
+		return fmt.Errorf("%w: rss must be a valid URL", news.ErrInvalidSource)
+	}
+	var o EmailOptions
+	if err := src.DecodeOptions(&o); err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "file":
+		if _, err := access.localPath(src.FeedURL); err != nil {
+			return fmt.Errorf("%w: %v", news.ErrInvalidSource, err)
+		}
+	case "imap", "imaps":
+		if u.Hostname() == "" || u.User == nil || u.User.Username() == "" {
+			return fmt.Errorf("%w: imap URL needs a user and a host", news.ErrInvalidSource)
+		}
+		if _, ok := u.User.Password(); ok {
+			return fmt.Errorf("%w: put the imap password in the environment variable named by options.password_env, not in the URL", news.ErrInvalidSource)
+		}
+		if o.PasswordEnv == "" {
+			return fmt.Errorf("%w: imap sources need options.password_env", news.ErrInvalidSource)
+		}
+		if err := access.checkSecretEnv(o.PasswordEnv); err != nil {
+			return fmt.Errorf("%w: password_env: %v", news.ErrInvalidSource, err)
+		}
+	default:
+		return fmt.Errorf("%w: email sources read a file:// path or an imap:// or imaps:// URL", news.ErrInvalidSource)
+	}
+	if o.Split != "" && o.Split != EmailSplitLinks && o.Split != EmailSplitMessage {
+		return fmt.Errorf("%w: split must be %q or %q", news.ErrInvalidSource, EmailSplitLinks, EmailSplitMessage)
+	}
+	if o.SinceDays < 0 || o.MaxMessages < 0 {
+		return fmt.Errorf("%w: since_days and max_messages must not be negative", news.ErrInvalidSource)
+	}
+	if o.Stories != nil {
+		if strings.TrimSpace(o.Stories.Item) == "" || strings.TrimSpace(o.Stories.Title) == "" {
+			return fmt.Errorf("%w: options.stories needs item and title selectors", news.ErrInvalidSource)
+		}
+		for _, sel := range []string{o.Stories.Item, o.Stories.Title, o.Stories.Link, o.Stories.Summary} {
+			if sel == "" {
+				continue
+			}
+			if _, err := compileField(sel); err != nil {
+				return fmt.Errorf("%w: options.stories: %v", news.ErrInvalidSource, err)
+			}
+		}
+	}
+	return nil
+}
+
+// EmailFetcher turns newsletters into articles attributed to the sender.
+// Mailbox files must lie within Access.FileRoot, and the IMAP password is
+// read from an environment variable that Access allows.
+type EmailFetcher struct {
+	Access LocalAccess
+}
+
+func NewEmailFetcher(access LocalAccess) EmailFetcher {
+	return EmailFetcher{Access: access}
+}
+
+func (f EmailFetcher) Fetch(ctx context.Context, src news.Source, _ string) (Feed, error) {
+	var opts EmailOptions
+	if err := src.DecodeOptions(&opts); err != nil {
+		return Feed{}, err
+	}
+	if opts.SinceDays <= 0 {
+		opts.SinceDays = defaultEmailSinceDays
+	}
+	if opts.MaxMessages <= 0 {
+		opts.MaxMessages = defaultEmailMaxMessages
+	}
+	now := time.Now().UTC()
+	since := now.AddDate(0, 0, -opts.SinceDays)
+	raws, err := readMailbox(ctx, f.Access, src.FeedURL, opts, since)
+	if err != nil {
+		return Feed{}, err
+	}
+	var msgs []mailMessage
+	for _, raw := range raws {
+		m, err := parseMail(raw)
+		if err != nil {
+			log.Printf("event=email_parse status=skip source=%s err=%v", src.ID, err)
+			continue
+		}
+		if !m.Date.IsZero() && m.Date.Before(since) || !opts.matches(m) {
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].Date.After(msgs[j].Date) })
+	if len(msgs) > opts.MaxMessages {
+		msgs = msgs[:opts.MaxMessages]
+	}
+	feed := Feed{Items: []news.Article{}}
+	for _, m := range msgs {
+		if feed.Title == "" {
+			feed.Title = m.author()
+		}
+		if opts.Split == EmailSplitMessage {
+			feed.Items = append(feed.Items, messageArticle(m, now))
+			continue
+		}
+		stories, err := messageStories(m, opts.Stories, now)
+		if err != nil {
+			return Feed{}, err
+		}
+		feed.Items = append(feed.Items, stories...)
+	}
+	return feed, nil
+}
+
+func (o EmailOptions) matches(m mailMessage) bool {
+	if o.From != "" && !strings.Contains(m.FromAddr, strings.ToLower(o.From)) {
+		return false
+	}
+	return o.Subject == "" || strings.Contains(strings.ToLower(m.Subject), strings.ToLower(o.Subject))
+}
+
+// readMailbox returns the raw messages at rawURL.
+func readMailbox(ctx context.Context, access LocalAccess, rawURL string, opts EmailOptions, since time.Time) ([][]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "imap" || u.Scheme == "imaps" {
+		password, err := access.secretEnv(opts.PasswordEnv)
+		if err != nil {
+			return nil, fmt.Errorf("imap password: %w", err)
+		}
+		return fetchIMAP(ctx, u, password, since, opts.MaxMessages)
+	}
+	path, err := access.localPath(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if isDir(path) {
+		return readMailDir(path)
+	}
+	return readMbox(path)
+}
+
+// messageArticle makes one article of a whole message, linked to its web
+// version when the newsletter offers one.
+func messageArticle(m mailMessage, now time.Time) news.Article {
+	body := m.HTML
+	if body == "" {
+		body = "<pre>" + html.EscapeString(m.Text) + "</pre>"
+	}
+	link := webVersionLink(body)
+	if link == "" {
+		link = "mid:" + url.PathEscape(m.ID)
+	}
+	return news.Article{
+		Title:       m.Subject,
+		URL:         link,
+		Source:      "email",
+		Content:     body,
+		PublishedAt: m.Date,
+		Authors:     nonEmpty(m.author()),
+		GUID:        m.ID,
+		FetchedAt:   now,
+	}
+}
+
+// messageStories makes one article per story in a message: the blocks
+// matched by stories when given, otherwise every headline-like link of an
+// HTML body, or every paragraph with a link of a plain-text one.
+func messageStories(m mailMessage, stories *ScrapeConfig, now time.Time) ([]news.Article, error) {
+	var out []news.Article
+	switch {
+	case m.HTML != "" && stories != nil:
+		items, err := scrapeListing(m.HTML, &url.URL{}, *stories, now)
+		if err != nil {
+			return nil, err
+		}
+		out = items
+	case m.HTML != "":
+		out = htmlStories(htmldoc.ParseString(m.HTML))
+	default:
+		out = textStories(m.Text)
+	}
+	for i := range out {
+		out[i].Source = "email"
+		out[i].PublishedAt = m.Date
+		out[i].Authors = nonEmpty(m.author())
+		out[i].FetchedAt = now
+		if m.ID != "" {
+			out[i].GUID = m.ID + "#" + strconv.Itoa(i+1)
+		}
+	}
+	return out, nil
+}
+
+// boilerplateText and boilerplateHref match the link text and URL of
+// newsletter furniture: unsubscribe and preference links, web versions,
+// sharing and social profiles.
+var (
+	boilerplateText = regexp.MustCompile(`(?i)unsubscribe|update (your )?preferences|manage (your )?subscription|view (it |this email )?(in|on) (your |a |the )?browser|view online|web version|forward (this |it )?to a friend|^subscribe|privacy policy`)
+	boilerplateHref = regexp.MustCompile(`(?i)^mailto:|unsubscribe|/preferences|://(www\.)?(twitter|x|facebook|instagram)\.com/|linkedin\.com/(share|company)`)
+)
+
+func boilerplate(text, href string) bool {
+	return boilerplateText.MatchString(text) || boilerplateHref.MatchString(href)
+}
+
+var webVersionText = regexp.MustCompile(`(?i)view (it )?(in|on) (your |a |the )?browser|view online|web version|read online`)
+
+func webVersionLink(body string) string {
+	for _, a := range htmldoc.ParseString(body).Select(anchorSelector) {
+		if webVersionText.MatchString(a.InnerText()) {
+			if link := resolveLink(&url.URL{}, a.AttrOr("href")); link != "" {
+				return link
+			}
+		}
+	}
+	return ""
+}
+
+// storyBlocks are the elements whose text is taken as a story's summary.
+var storyBlocks = map[string]bool{"p": true, "li": true, "td": true, "div": true, "section": true, "article": true, "blockquote": true}
+
+var headingTags = map[string]bool{"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true}
+
+// htmlStories finds the story links of an HTML newsletter. A link counts
+// as a story when its text reads like a headline or it sits in or follows
+// a heading; the text of its block, or of the block after its heading, is
+// the summary.
+func htmlStories(doc *htmldoc.Node) []news.Article {
+	var out []news.Article
+	seen := map[string]bool{}
+	for _, a := range doc.Select(anchorSelector) {
+		href := a.AttrOr("href")
+		link := resolveLink(&url.URL{}, href)
+		title := strings.Join(strings.Fields(a.InnerText()), " ")
+		if link == "" || seen[link] || boilerplate(title, href) {
+			continue
+		}
+		heading := ancestor(a, headingTags)
+		if !headlineLike(title) {
+			if heading == nil {
+				heading = precedingHeading(a)
+			}
+			if heading == nil {
+				continue
+			}
+			// A heading is a title however short it is.
+			if title = strings.Join(strings.Fields(heading.InnerText()), " "); title == "" {
+				continue
+			}
+		}
+		seen[link] = true
+		var summary string
+		if heading != nil {
+			summary = nextElementText(heading)
+		} else if block := ancestor(a, storyBlocks); block != nil {
+			summary = strings.TrimPrefix(strings.Join(strings.Fields(block.InnerText()), " "), title)
+		}
+		summary = strings.TrimLeft(summary, "-–—:| ")
+		out = append(out, news.Article{Title: title, URL: link, Content: summaryHTML(summary)})
+	}
+	return out
+}
+
+var (
+	urlPattern       = regexp.MustCompile(`https?://[^\s<>"')\]]+`)
+	paragraphPattern = regexp.MustCompile(`\r?\n\s*\r?\n`)
+)
+
+// textStories finds the story links of a plain-text newsletter: each
+// paragraph with a link is a story titled by its first line of text.
+func textStories(text string) []news.Article {
+	var out []news.Article
+	seen := map[string]bool{}
+	for _, para := range paragraphPattern.Split(text, -1) {
+		href := strings.TrimRight(urlPattern.FindString(para), ".,;:")
+		link := resolveLink(&url.URL{}, href)
+		if link == "" || seen[link] || boilerplate(para, href) {
+			continue
+		}
+		var lines []string
+		for _, line := range strings.Split(para, "\n") {
+			line = strings.Join(strings.Fields(urlPattern.ReplaceAllString(line, "")), " ")
+			line = strings.Trim(line, "-–—:|*#[]() ")
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) == 0 || !headlineLike(lines[0]) {
+			continue
+		}
+		seen[link] = true
+		out = append(out, news.Article{Title: lines[0], URL: link, Content: summaryHTML(strings.Join(lines[1:], " "))})
+	}
+	return out
+}
+
+// headlineLike rejects link texts such as "here" or "Read more". Text in
+// scripts written without spaces counts by characters instead of words.
+func headlineLike(s string) bool {
+	if len(strings.Fields(s)) >= 3 {
+		return true
+	}
+	wide := 0
+	for _, r := range s {
+		if r > unicode.MaxLatin1 && unicode.IsLetter(r) {
+			wide++
+		}
+	}
+	return wide >= 5
+}
+
+func ancestor(n *htmldoc.Node, tags map[string]bool) *htmldoc.Node {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == htmldoc.ElementNode && tags[p.Tag] {
+			return p
+		}
+	}
+	return nil
+}
+
+// precedingHeading finds the heading a "Read more" style link follows
+// within its story block.
+func precedingHeading(a *htmldoc.Node) *htmldoc.Node {
+	for n := a; n.Parent != nil && !storyBlocks[n.Tag]; n = n.Parent {
+		siblings := n.Parent.Children
+		for i := indexOf(siblings, n) - 1; i >= 0; i-- {
+			c := siblings[i]
+			if c.Type == htmldoc.ElementNode && (headingTags[c.Tag] || c.Tag == "b" || c.Tag == "strong") {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+func indexOf(nodes []*htmldoc.Node, n *htmldoc.Node) int {
+	for i, c := range nodes {
+		if c == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextElementText returns the text of the element after n, skipping links
+// such as "Read more", unless that is another heading.
+func nextElementText(n *htmldoc.Node) string {
+	if n.Parent == nil {
+		return ""
+	}
+	after := false
+	for _, c := range n.Parent.Children {
+		if c == n {
+			after = true
+			continue
+		}
+		if !after || c.Type != htmldoc.ElementNode || c.Tag == "a" {
+			continue
+		}
+		if headingTags[c.Tag] {
+			return ""
+		}
+		return strings.Join(strings.Fields(c.InnerText()), " ")
+	}
+	return ""
+}
+
+func summaryHTML(s string) string {
+	r := []rune(strings.TrimSpace(s))
+	if len(r) == 0 {
+		return ""
+	}
+	if len(r) > maxStorySummary {
+		r = append(r[:maxStorySummary], '…')
+	}
+	return "<p>" + html.EscapeString(string(r)) + "</p>"
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
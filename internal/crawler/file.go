@@ -0,0 +1,369 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"news-go/internal/news"
+)
+
+const (
+	// maxFeedFileBytes bounds how much of a local feed file is read.
+	maxFeedFileBytes = 16 << 20
+	// defaultMaxFiles is how many files a directory source reads per run.
+	defaultMaxFiles = 100
+)
+
+const (
+	FileAfterMark = "mark"
+	FileAfterMove = "move"
+)
+
+// partialSuffixes mark uploads still in progress (SFTP clients, WinSCP).
+var partialSuffixes = []string{".part", ".partial", ".filepart", ".tmp"}
+
+func init() {
+	Register(Kind{
+		Name: KindFile,
+		New: func(_ *http.Client, o FetcherOptions) Fetcher {
+			return &FileFetcher{Access: o.Access, Ledger: o.Files, Limits: o.FeedLimits}
+		},
+		Validate: validateFile,
+		Schemes:  []string{"file"},
+	})
+}
+
+// FileOptions configures a file source whose rss names a directory. Every
+// run reads up to MaxFiles files matching Pattern, oldest first; files
+// modified less than MinAgeSec ago are left for the next run. After "mark"
+// (the default) leaves read files in place and only records their
+// checksum; "move" also moves them into MoveTo, a path relative to the
+// directory that must stay within it (default "processed").
+type FileOptions struct {
+	Pattern   string `json:"pattern,omitempty"`
+	After     string `json:"after,omitempty"`
+	MoveTo    string `json:"move_to,omitempty"`
+	MaxFiles  int    `json:"max_files,omitempty"`
+	MinAgeSec int    `json:"min_age_sec,omitempty"`
+}
+
+func (o FileOptions) moveTo() (string, error) {
+	to := o.MoveTo
+	if to == "" {
+		to = "processed"
+	}
+	to = filepath.Clean(to)
+	if filepath.IsAbs(to) || to == "." || !filepath.IsLocal(to) {
+		return "", errors.New("move_to must be a subdirectory of the source directory")
+	}
+	return to, nil
+}
+
+// moveDir creates the move_to directory of dir and checks that, symlinks
+// resolved, it is still inside dir.
+func (o FileOptions) moveDir(dir string) (string, error) {
+	to, err := o.moveTo()
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(dir, to)
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		return "", err
+	}
+	real, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return "", err
+	}
+	if !within(dir, real) || real == dir {
+		return "", errors.New("move_to resolves outside the source directory")
+	}
+	return real, nil
+}
+
+func validateFile(src news.Source, access LocalAccess) error {
+	if _, err := access.localPath(src.FeedURL); err != nil {
+		return fmt.Errorf("%w: %v", news.ErrInvalidSource, err)
+	}
+	var o FileOptions
+	if err := src.DecodeOptions(&o); err != nil {
+		return err
+	}
+	if _, err := o.moveTo(); err != nil {
+		return fmt.Errorf("%w: %v", news.ErrInvalidSource, err)
+	}
+	if _, err := filepath.Match(o.Pattern, ""); err != nil {
+		return fmt.Errorf("%w: pattern: %v", news.ErrInvalidSource, err)
+	}
+	if o.After != "" && o.After != FileAfterMark && o.After != FileAfterMove {
+		return fmt.Errorf("%w: after must be %q or %q", news.ErrInvalidSource, FileAfterMark, FileAfterMove)
+	}
+	if o.MaxFiles < 0 || o.MinAgeSec < 0 {
+		return fmt.Errorf("%w: max_files and min_age_sec must not be negative", news.ErrInvalidSource)
+	}
+	return nil
+}
+
+// filePath returns the local path of a file:// URL.
+func filePath(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "file" {
+		return "", fmt.Errorf("rss must be a file:// URL")
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("file URL must not name a remote host")
+	}
+	if u.Path == "" {
+		return "", fmt.Errorf("file URL needs a path")
+	}
+	return u.Path, nil
+}
+
+// FileLedger remembers which files a directory source has read.
+type FileLedger interface {
+	SourceFileStatus(ctx context.Context, sourceID, checksum string) (string, error)
+	RecordSourceFile(ctx context.Context, f news.SourceFile) error
+}
+
+// FileFetcher reads RSS, Atom or JSON feed files from a local file or
+// directory. Without a Ledger, every file in a directory is read on every
+// run; storage dedupes the articles by URL either way. Files must lie
+// within Access.FileRoot and are parsed within Limits, or
+// DefaultFeedLimits when it is zero.
+type FileFetcher struct {
+	Access LocalAccess
+	Ledger FileLedger
+	Limits FeedLimits
+}
+
+func NewFileFetcher(access LocalAccess, ledger FileLedger) *FileFetcher {
+	return &FileFetcher{Access: access, Ledger: ledger}
+}
+
+func (f *FileFetcher) Fetch(ctx context.Context, src news.Source, _ string) (Feed, error) {
+	path, err := f.Access.localPath(src.FeedURL)
+	if err != nil {
+		return Feed{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Feed{}, err
+	}
+	if info.IsDir() {
+		return f.fetchDir(ctx, src, path)
+	}
+	body, err := readFeedFile(path)
+	if err != nil {
+		return Feed{}, err
+	}
+	return f.Limits.Parse(bytes.NewReader(body), time.Now().UTC())
+}
+
+func readFeedFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	body, err := io.ReadAll(io.LimitReader(file, maxFeedFileBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxFeedFileBytes {
+		return nil, fmt.Errorf("file exceeds %d bytes", maxFeedFileBytes)
+	}
+	return body, nil
+}
+
+// pendingFile is a file read in this run, settled by Feed.Commit.
+type pendingFile struct {
+	path   string
+	record news.SourceFile
+	seen   bool
+}
+
+// fetchDir parses the new files in dir into one feed. Files are only
+// recorded, and moved, by the returned feed's Commit once their articles
+// are stored.
+func (f *FileFetcher) fetchDir(ctx context.Context, src news.Source, dir string) (Feed, error) {
+	var opts FileOptions
+	if err := src.DecodeOptions(&opts); err != nil {
+		return Feed{}, err
+	}
+	names, err := listFeedFiles(dir, opts, time.Now())
+	if err != nil {
+		return Feed{}, err
+	}
+	limit := opts.MaxFiles
+	if limit <= 0 {
+		limit = defaultMaxFiles
+	}
+	now := time.Now().UTC()
+	var feed Feed
+	var pending []pendingFile
+	read := 0
+	for _, name := range names {
+		if read >= limit {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return Feed{}, err
+		}
+		path := filepath.Join(dir, name)
+		body, err := readFeedFile(path)
+		if err != nil {
+			// The file may have been removed or still be growing; try
+			// again next run.
+			log.Printf("event=file_ingest status=skip source=%s file=%s err=%v", src.ID, name, err)
+			continue
+		}
+		sum := sha256.Sum256(body)
+		p := pendingFile{path: path, record: news.SourceFile{
+			SourceID: src.ID,
+			Checksum: hex.EncodeToString(sum[:]),
+			Name:     name,
+			Status:   news.SourceFileProcessed,
+		}}
+		if f.Ledger != nil {
+			status, err := f.Ledger.SourceFileStatus(ctx, src.ID, p.record.Checksum)
+			if err != nil {
+				return Feed{}, err
+			}
+			if status != "" {
+				if opts.After == FileAfterMove && status == news.SourceFileProcessed {
+					p.seen = true
+					pending = append(pending, p)
+				}
+				continue
+			}
+		}
+		read++
+		parsed, err := f.Limits.Parse(bytes.NewReader(body), now)
+		if err != nil {
+			p.record.Status, p.record.Error = news.SourceFileFailed, err.Error()
+			log.Printf("event=file_ingest status=error source=%s file=%s err=%v", src.ID, name, err)
+		} else {
+			p.record.Items = len(parsed.Items)
+			feed.Items = append(feed.Items, parsed.Items...)
+			if feed.Title == "" {
+				feed.Title = parsed.Title
+			}
+		}
+		pending = append(pending, p)
+	}
+	if len(pending) > 0 {
+		feed.Commit = func(ctx context.Context) error {
+			return f.settle(ctx, opts, dir, pending)
+		}
+	}
+	return feed, nil
+}
+
+// settle records read files in the ledger and, with after "move", moves
+// them and already-seen copies out of the directory. Files that failed to
+// parse stay where they are.
+func (f *FileFetcher) settle(ctx context.Context, opts FileOptions, dir string, pending []pendingFile) error {
+	processed := time.Now().UTC()
+	var target string
+	for _, p := range pending {
+		if !p.seen && f.Ledger != nil {
+			p.record.ProcessedAt = processed
+			if err := f.Ledger.RecordSourceFile(ctx, p.record); err != nil {
+				return err
+			}
+		}
+		if opts.After != FileAfterMove || p.record.Status == news.SourceFileFailed {
+			continue
+		}
+		if target == "" {
+			var err error
+			if target, err = opts.moveDir(dir); err != nil {
+				return err
+			}
+		}
+		if err := moveFile(p.path, target, p.record.Checksum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveFile moves path into dir, adding part of the checksum to the name
+// when a different file already has it.
+func moveFile(path, dir, checksum string) error {
+	name := filepath.Base(path)
+	target := filepath.Join(dir, name)
+	if _, err := os.Stat(target); err == nil {
+		ext := filepath.Ext(name)
+		target = filepath.Join(dir, strings.TrimSuffix(name, ext)+"."+checksum[:12]+ext)
+	}
+	return os.Rename(path, target)
+}
+
+// listFeedFiles returns the names of the regular files in dir that are due
+// for reading, oldest first. Hidden files and partial uploads are skipped.
+// MaxFiles is applied by the caller to files not read before, so files
+// kept in place by "mark" don't crowd out new ones.
+func listFeedFiles(dir string, opts FileOptions, now time.Time) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type candidate struct {
+		name    string
+		modTime time.Time
+	}
+	var files []candidate
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || strings.HasPrefix(name, ".") || hasPartialSuffix(name) {
+			continue
+		}
+		if opts.Pattern != "" {
+			if ok, _ := filepath.Match(opts.Pattern, name); !ok {
+				continue
+			}
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if opts.MinAgeSec > 0 && now.Sub(info.ModTime()) < time.Duration(opts.MinAgeSec)*time.Second {
+			continue
+		}
+		files = append(files, candidate{name: name, modTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].modTime.Equal(files[j].modTime) {
+			return files[i].modTime.Before(files[j].modTime)
+		}
+		return files[i].name < files[j].name
+	})
+	names := make([]string, len(files))
+	for i, c := range files {
+		names[i] = c.name
+	}
+	return names, nil
+}
+
+func hasPartialSuffix(name string) bool {
+	lower := strings.ToLower(name)
+	for _, s := range partialSuffixes {
+		if strings.HasSuffix(lower, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"news-go/internal/news"
+)
+
+type mapLedger map[string]news.SourceFile
+
+func (l mapLedger) SourceFileStatus(_ context.Context, sourceID, checksum string) (string, error) {
+	return l[sourceID+"/"+checksum].Status, nil
+}
+
+func (l mapLedger) RecordSourceFile(_ context.Context, f news.SourceFile) error {
+	l[f.SourceID+"/"+f.Checksum] = f
+	return nil
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFileFetcherDirectory(t *testing.T) {
+	dir := t.TempDir()
+	access := LocalAccess{FileRoot: dir}
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom"><entry><title>From Atom</title><link href="https://partner.example/a"/></entry></feed>`
+	for name, body := range map[string]string{
+		"1-rss.xml":     richFeed,
+		"2-atom.xml":    atom,
+		"3-broken.xml":  "<rss><channel><item>",
+		"4-upload.part": richFeed,
+		".hidden.xml":   richFeed,
+		"notes.txt":     "not a feed",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ledger := mapLedger{}
+	fetcher := NewFileFetcher(access, ledger)
+	src := news.Source{ID: "partner", Name: "Partner", Kind: KindFile, FeedURL: "file://" + filepath.ToSlash(dir),
+		Options: []byte(`{"pattern":"*.xml","after":"move"}`)}
+	if err := ValidateSource(src, access); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	feed, err := fetcher.Fetch(ctx, src, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Items) != 3 || feed.Commit == nil {
+		t.Fatalf("got %d items, commit %v", len(feed.Items), feed.Commit != nil)
+	}
+	if len(ledger) != 0 {
+		t.Fatal("files recorded before commit")
+	}
+	if err := feed.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := dirNames(t, dir); !reflect.DeepEqual(got, []string{".hidden.xml", "3-broken.xml", "4-upload.part", "notes.txt", "processed"}) {
+		t.Errorf("directory after commit: %v", got)
+	}
+	if got := dirNames(t, filepath.Join(dir, "processed")); !reflect.DeepEqual(got, []string{"1-rss.xml", "2-atom.xml"}) {
+		t.Errorf("processed: %v", got)
+	}
+	failed := 0
+	for _, f := range ledger {
+		if f.Status == news.SourceFileFailed {
+			failed++
+			if f.Name != "3-broken.xml" || f.Error == "" {
+				t.Errorf("failed record: %+v", f)
+			}
+		}
+	}
+	if len(ledger) != 3 || failed != 1 {
+		t.Errorf("ledger: %+v", ledger)
+	}
+
+	// A re-upload of a file already read is moved aside without being
+	// parsed again, and the broken file is not retried.
+	if err := os.WriteFile(filepath.Join(dir, "1-rss.xml"), []byte(richFeed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	feed, err = fetcher.Fetch(ctx, src, "")
+	if err != nil || len(feed.Items) != 0 {
+		t.Fatalf("second run: %d items, %v", len(feed.Items), err)
+	}
+	if err := feed.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := dirNames(t, filepath.Join(dir, "processed")); len(got) != 3 {
+		t.Errorf("duplicate not moved: %v", got)
+	}
+}
+
+func TestValidateFileOptions(t *testing.T) {
+	root := t.TempDir()
+	for _, opts := range []string{`{"after":"delete"}`, `{"pattern":"[x"}`, `{"max_files":-1}`,
+		`{"after":"move","move_to":"/tmp/out"}`, `{"after":"move","move_to":"../out"}`, `{"move_to":"a/../.."}`, `{"move_to":"."}`} {
+		src := news.Source{ID: "d", Name: "d", Kind: KindFile, FeedURL: "file://" + filepath.ToSlash(root), Options: []byte(opts)}
+		if err := ValidateSource(src, LocalAccess{FileRoot: root}); err == nil {
+			t.Errorf("options %s accepted", opts)
+		}
+	}
+}
+
+func TestFileSourceRoot(t *testing.T) {
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "feed.xml"), []byte(richFeed), 0o644)
+	src := func(path string) news.Source {
+		return news.Source{ID: "d", Name: "d", Kind: KindFile, FeedURL: "file://" + filepath.ToSlash(path)}
+	}
+	if err := ValidateSource(src(filepath.Join(outside, "feed.xml")), LocalAccess{}); !errors.Is(err, news.ErrInvalidSource) {
+		t.Errorf("accepted without FILE_SOURCE_ROOT: %v", err)
+	}
+
+	root := t.TempDir()
+	access := LocalAccess{FileRoot: root}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		filepath.Join(outside, "feed.xml"),
+		filepath.Join(outside, "missing.xml"),
+		root + "/../" + filepath.Base(outside) + "/feed.xml",
+		filepath.Join(root, "link", "feed.xml"),
+	} {
+		err := ValidateSource(src(path), access)
+		if !errors.Is(err, news.ErrInvalidSource) {
+			t.Errorf("%s: got %v", path, err)
+		}
+		if _, err := NewFetchers(nil, WithLocalAccess(access)).Fetch(context.Background(), src(path), ""); err == nil {
+			t.Errorf("%s: fetched", path)
+		}
+	}
+	// Refusals outside the root read the same whether the file exists.
+	a, b := ValidateSource(src(filepath.Join(outside, "feed.xml")), access), ValidateSource(src(filepath.Join(outside, "missing.xml")), access)
+	if a == nil || b == nil || a.Error() != b.Error() {
+		t.Errorf("errors differ: %v / %v", a, b)
+	}
+
+	// A move_to that is a symlink out of the directory is refused when
+	// files are moved.
+	drop := filepath.Join(root, "drop")
+	os.Mkdir(drop, 0o755)
+	os.WriteFile(filepath.Join(drop, "feed.xml"), []byte(richFeed), 0o644)
+	if err := os.Symlink(outside, filepath.Join(drop, "processed")); err != nil {
+		t.Fatal(err)
+	}
+	s := src(drop)
+	s.Options = []byte(`{"after":"move"}`)
+	if err := ValidateSource(s, access); err != nil {
+		t.Fatal(err)
+	}
+	feed, err := NewFileFetcher(access, mapLedger{}).Fetch(context.Background(), s, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := feed.Commit(context.Background()); err == nil {
+		t.Error("moved files through a symlink out of the directory")
+	}
+	if got := dirNames(t, outside); len(got) != 1 {
+		t.Errorf("outside directory: %v", got)
+	}
+}
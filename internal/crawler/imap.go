@@ -0,0 +1,212 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxIMAPLine bounds a response line outside of literals.
+const maxIMAPLine = 64 << 10
+
+// imapClient speaks the handful of IMAP4rev1 commands the email kind
+// needs: LOGIN, EXAMINE, UID SEARCH, UID FETCH and LOGOUT.
+type imapClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapResponse is one untagged response line with the literals it
+// carried, in order.
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+// dialIMAP connects to the server of an imap:// or imaps:// URL and reads
+// its greeting. The connection is closed when ctx is done.
+func dialIMAP(ctx context.Context, u *url.URL) (*imapClient, func(), error) {
+	host := u.Host
+	if u.Port() == "" {
+		port := "143"
+		if u.Scheme == "imaps" {
+			port = "993"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if u.Scheme == "imaps" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: u.Hostname()}}).DialContext(ctx, "tcp", host)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if d, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(d)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	c := &imapClient{conn: conn, r: bufio.NewReader(conn)}
+	closeFn := func() {
+		stop()
+		conn.Close()
+	}
+	greeting, err := c.readLine()
+	if err != nil {
+		closeFn()
+		return nil, nil, err
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		closeFn()
+		return nil, nil, fmt.Errorf("imap greeting: %s", greeting)
+	}
+	return c, closeFn, nil
+}
+
+// cmd sends a command and collects untagged responses until its tagged
+// completion, which must be OK.
+func (c *imapClient) cmd(command string) ([]imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%03d", c.tag)
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, command); err != nil {
+		return nil, err
+	}
+	var out []imapResponse
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(resp.line, tag+" ") {
+			status := strings.TrimPrefix(resp.line, tag+" ")
+			if !strings.HasPrefix(strings.ToUpper(status), "OK") {
+				verb, _, _ := strings.Cut(command, " ")
+				return nil, fmt.Errorf("imap %s: %s", verb, status)
+			}
+			return out, nil
+		}
+		if strings.HasPrefix(resp.line, "*") {
+			out = append(out, resp)
+		}
+	}
+}
+
+// readResponse reads one response line, following "{N}" literals into the
+// continuation of the line.
+func (c *imapClient) readResponse() (imapResponse, error) {
+	var resp imapResponse
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return resp, err
+		}
+		resp.line += line
+		n, ok := literalSize(line)
+		if !ok {
+			return resp, nil
+		}
+		if n > maxMailBytes {
+			return resp, fmt.Errorf("imap literal of %d bytes exceeds limit", n)
+		}
+		lit := make([]byte, n)
+		if _, err := io.ReadFull(c.r, lit); err != nil {
+			return resp, err
+		}
+		resp.literals = append(resp.literals, lit)
+	}
+}
+
+func (c *imapClient) readLine() (string, error) {
+	var b []byte
+	for {
+		chunk, isPrefix, err := c.r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		b = append(b, chunk...)
+		if len(b) > maxIMAPLine {
+			return "", errors.New("imap response line too long")
+		}
+		if !isPrefix {
+			return string(b), nil
+		}
+	}
+}
+
+// literalSize parses a trailing "{N}" (or non-synchronizing "{N+}").
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	i := strings.LastIndexByte(line, '{')
+	if i < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[i+1:len(line)-1], "+"))
+	return n, err == nil && n >= 0
+}
+
+// imapQuote renders s as an IMAP quoted string.
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// fetchIMAP logs in, selects the mailbox named by the URL path (INBOX by
+// default) and returns up to max of the newest messages received since
+// the given time, without marking them as seen.
+func fetchIMAP(ctx context.Context, u *url.URL, password string, since time.Time, max int) ([][]byte, error) {
+	c, closeFn, err := dialIMAP(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+	if _, err := c.cmd("LOGIN " + imapQuote(u.User.Username()) + " " + imapQuote(password)); err != nil {
+		return nil, err
+	}
+	mailbox := strings.Trim(u.Path, "/")
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+	if _, err := c.cmd("EXAMINE " + imapQuote(mailbox)); err != nil {
+		return nil, err
+	}
+	resps, err := c.cmd("UID SEARCH SINCE " + since.UTC().Format("02-Jan-2006"))
+	if err != nil {
+		return nil, err
+	}
+	var uids []string
+	for _, r := range resps {
+		if rest, ok := strings.CutPrefix(r.line, "* SEARCH"); ok {
+			uids = append(uids, strings.Fields(rest)...)
+		}
+	}
+	if len(uids) > max {
+		uids = uids[len(uids)-max:]
+	}
+	var msgs [][]byte
+	if len(uids) > 0 {
+		resps, err = c.cmd("UID FETCH " + strings.Join(uids, ",") + " (BODY.PEEK[])")
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resps {
+			if strings.Contains(strings.ToUpper(r.line), "FETCH") && len(r.literals) > 0 {
+				msgs = append(msgs, r.literals[0])
+			}
+		}
+	}
+	c.cmd("LOGOUT")
+	return msgs, nil
+}
@@ -0,0 +1,112 @@
+// Command gen writes gbk.bin, the crawler's GBK decoding table, from the
+// WHATWG gb18030 index.
+//
+//	gen [-index https://encoding.spec.whatwg.org/index-gb18030.txt] [-o gbk.bin]
+//
+// The table has one big-endian UTF-16 unit per lead byte 0x81-0xFE and
+// trail byte 0x40-0xFE, 0xFFFD where the pair is unassigned. The crawler
+// runs it through `go generate`; -index also takes a local copy of the
+// index for offline builds.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	leads  = 0xFE - 0x81 + 1
+	trails = 0xFE - 0x40 + 1
+)
+
+func main() {
+	log.SetFlags(0)
+	index := flag.String("index", "https://encoding.spec.whatwg.org/index-gb18030.txt", "index URL or file")
+	out := flag.String("o", "gbk.bin", "output file")
+	flag.Parse()
+
+	r, err := open(*index)
+	if err != nil {
+		log.Fatalf("open index: %v", err)
+	}
+	defer r.Close()
+	table, err := build(r)
+	if err != nil {
+		log.Fatalf("read index: %v", err)
+	}
+	if err := os.WriteFile(*out, table, 0o644); err != nil {
+		log.Fatalf("write table: %v", err)
+	}
+}
+
+func open(index string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(index, "http://") && !strings.HasPrefix(index, "https://") {
+		return os.Open(index)
+	}
+	resp, err := http.Get(index)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", index, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// build maps each index line, "pointer<TAB>0xCODE<TAB>...", to its byte
+// pair. WHATWG pointers skip the trail byte 0x7F, which the table keeps
+// as an unassigned column.
+func build(r io.Reader) ([]byte, error) {
+	table := make([]byte, leads*trails*2)
+	for i := 0; i < len(table); i += 2 {
+		table[i], table[i+1] = 0xFF, 0xFD
+	}
+	assigned := 0
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("malformed line %q", line)
+		}
+		pointer, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("pointer in %q: %v", line, err)
+		}
+		code, err := strconv.ParseUint(strings.TrimPrefix(fields[1], "0x"), 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("code point in %q: %v", line, err)
+		}
+		if pointer < 0 || pointer >= leads*190 {
+			return nil, fmt.Errorf("pointer %d out of range", pointer)
+		}
+		if code > 0xFFFF {
+			return nil, fmt.Errorf("pointer %d maps outside the BMP", pointer)
+		}
+		trail := pointer % 190
+		if trail >= 0x7F-0x40 {
+			trail++
+		}
+		i := (pointer/190*trails + trail) * 2
+		table[i], table[i+1] = byte(code>>8), byte(code)
+		assigned++
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if assigned == 0 {
+		return nil, fmt.Errorf("index has no entries")
+	}
+	return table, nil
+}
@@ -0,0 +1,308 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"news-go/internal/news"
+)
+
+// maxJSONBytes bounds how much of a JSON API response is read.
+const maxJSONBytes = 8 << 20
+
+func init() {
+	Register(Kind{
+		Name:     KindJSONAPI,
+		New:      func(c *http.Client, _ FetcherOptions) Fetcher { return &JSONFetcher{client: c} },
+		Validate: validateJSONAPI,
+	})
+}
+
+// JSONAPIConfig is the options object of a json-api source. Each field is
+// a dot-separated path: Items points at the array of items in the
+// response, the others at values inside one item. A path that crosses an
+// array collects from every element, so "authors.name" reads all author
+// names, and "." is the response itself. Empty fields fall back to the
+// JSON Feed layout.
+type JSONAPIConfig struct {
+	Items     string `json:"items,omitempty"`
+	ID        string `json:"id,omitempty"`
+	Title     string `json:"title,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Content   string `json:"content,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Summary   string `json:"summary,omitempty"`
+	Published string `json:"published,omitempty"`
+	Authors   string `json:"authors,omitempty"`
+	Tags      string `json:"tags,omitempty"`
+	Image     string `json:"image,omitempty"`
+	Language  string `json:"language,omitempty"`
+}
+
+// JSONFeedConfig maps JSON Feed 1.0 and 1.1 documents.
+func JSONFeedConfig() JSONAPIConfig {
+	return JSONAPIConfig{
+		Items:     "items",
+		ID:        "id",
+		Title:     "title",
+		URL:       "url",
+		Content:   "content_html",
+		Text:      "content_text",
+		Summary:   "summary",
+		Published: "date_published",
+		Authors:   "authors.name",
+		Tags:      "tags",
+		Image:     "image",
+		Language:  "language",
+	}
+}
+
+func (c JSONAPIConfig) withDefaults() JSONAPIConfig {
+	d := JSONFeedConfig()
+	for _, f := range []struct{ v, def *string }{
+		{&c.Items, &d.Items}, {&c.ID, &d.ID}, {&c.Title, &d.Title}, {&c.URL, &d.URL},
+		{&c.Content, &d.Content}, {&c.Text, &d.Text}, {&c.Summary, &d.Summary},
+		{&c.Published, &d.Published}, {&c.Authors, &d.Authors}, {&c.Tags, &d.Tags},
+		{&c.Image, &d.Image}, {&c.Language, &d.Language},
+	} {
+		if strings.TrimSpace(*f.v) == "" {
+			*f.v = *f.def
+		}
+	}
+	return c
+}
+
+func validateJSONAPI(src news.Source, _ LocalAccess) error {
+	if err := requireHTTP(src); err != nil {
+		return err
+	}
+	var c JSONAPIConfig
+	return src.DecodeOptions(&c)
+}
+
+// JSONFetcher reads articles from a JSON API or a JSON Feed.
+type JSONFetcher struct {
+	client *http.Client
+}
+
+func (f *JSONFetcher) Fetch(ctx context.Context, src news.Source, userAgent string) (Feed, error) {
+	var cfg JSONAPIConfig
+	if err := src.DecodeOptions(&cfg); err != nil {
+		return Feed{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.FeedURL, nil)
+	if err != nil {
+		return Feed{}, err
+	}
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	req.Header.Set("Accept", "application/feed+json, application/json")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Feed{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Feed{}, newStatusError(resp, time.Now())
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxJSONBytes))
+	if err != nil {
+		return Feed{}, err
+	}
+	return parseJSON(body, cfg, resp.Request.URL, time.Now().UTC())
+}
+
+// parseJSON maps the items found at cfg.Items to articles. Relative links
+// are resolved against base when it is set. A JSON Feed's title, feed_url
+// and WebSub hub are read as well.
+func parseJSON(body []byte, cfg JSONAPIConfig, base *url.URL, now time.Time) (Feed, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return Feed{}, err
+	}
+	if base == nil {
+		base = &url.URL{}
+	}
+	cfg = cfg.withDefaults()
+	feed := Feed{
+		Title: jsonString(doc, "title"),
+		Self:  jsonString(doc, "feed_url"),
+	}
+	if top, ok := doc.(map[string]any); ok {
+		if hubs, ok := top["hubs"].([]any); ok {
+			for _, h := range hubs {
+				if strings.EqualFold(jsonString(h, "type"), "websub") && feed.Hub == "" {
+					feed.Hub = jsonString(h, "url")
+				}
+			}
+		}
+	}
+	var items []any
+	for _, v := range jsonPath(doc, cfg.Items) {
+		if list, ok := v.([]any); ok {
+			items = append(items, list...)
+		} else {
+			items = append(items, v)
+		}
+	}
+	lang := jsonString(doc, cfg.Language)
+	feed.Items = make([]news.Article, 0, len(items))
+	for _, it := range items {
+		if _, ok := it.(map[string]any); !ok {
+			continue
+		}
+		a := news.Article{
+			Title:     jsonString(it, cfg.Title),
+			URL:       resolveLink(base, jsonString(it, cfg.URL)),
+			Source:    "json",
+			GUID:      jsonString(it, cfg.ID),
+			ImageURL:  resolveLink(base, jsonString(it, cfg.Image)),
+			FetchedAt: now,
+		}
+		if a.Title == "" || a.URL == "" {
+			continue
+		}
+		switch {
+		case jsonString(it, cfg.Content) != "":
+			a.Content = jsonString(it, cfg.Content)
+		case jsonString(it, cfg.Text) != "":
+			a.Content = html.EscapeString(jsonString(it, cfg.Text))
+		default:
+			a.Content = html.EscapeString(jsonString(it, cfg.Summary))
+		}
+		if vals := jsonPath(it, cfg.Published); len(vals) > 0 {
+			if t, ok := jsonTime(vals[0]); ok {
+				a.PublishedAt = t
+			}
+		}
+		a.Authors = uniqueTrimmed(jsonStrings(it, cfg.Authors))
+		a.Categories = uniqueTrimmed(jsonStrings(it, cfg.Tags))
+		itemLang := jsonString(it, cfg.Language)
+		if itemLang == "" {
+			itemLang = lang
+		}
+		a.Language = news.NormalizeLanguage(itemLang)
+		a.Media = jsonAttachments(it, base)
+		feed.Items = append(feed.Items, a)
+	}
+	return feed, nil
+}
+
+// jsonAttachments reads the audio and video of a JSON Feed item's
+// attachments.
+func jsonAttachments(it any, base *url.URL) []news.Media {
+	var out []news.Media
+	for _, att := range jsonPath(it, "attachments") {
+		list, ok := att.([]any)
+		if !ok {
+			list = []any{att}
+		}
+		for _, v := range list {
+			out = addMedia(out, resolveLink(base, jsonString(v, "url")), jsonString(v, "mime_type"), "",
+				jsonString(v, "size_in_bytes"), jsonString(v, "duration_in_seconds"))
+		}
+	}
+	return out
+}
+
+// jsonPath follows a dot-separated path, fanning out over arrays. A
+// numeric segment indexes into an array instead.
+func jsonPath(v any, path string) []any {
+	path = strings.TrimSpace(path)
+	if path == "" || path == "." {
+		return []any{v}
+	}
+	cur := []any{v}
+	for _, seg := range strings.Split(path, ".") {
+		var next []any
+		for _, c := range cur {
+			switch t := c.(type) {
+			case map[string]any:
+				if child, ok := t[seg]; ok && child != nil {
+					next = append(next, child)
+				}
+			case []any:
+				if i, err := strconv.Atoi(seg); err == nil {
+					if i >= 0 && i < len(t) {
+						next = append(next, t[i])
+					}
+					continue
+				}
+				for _, el := range t {
+					if m, ok := el.(map[string]any); ok && m[seg] != nil {
+						next = append(next, m[seg])
+					}
+				}
+			}
+		}
+		cur = next
+	}
+	return cur
+}
+
+func jsonStrings(v any, path string) []string {
+	var out []string
+	for _, val := range jsonPath(v, path) {
+		switch t := val.(type) {
+		case string:
+			out = append(out, t)
+		case json.Number:
+			out = append(out, t.String())
+		case []any:
+			for _, el := range t {
+				if s, ok := el.(string); ok {
+					out = append(out, s)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func jsonString(v any, path string) string {
+	if vals := jsonStrings(v, path); len(vals) > 0 {
+		return strings.TrimSpace(vals[0])
+	}
+	return ""
+}
+
+// jsonTime reads a date string in any layout parseLooseDate knows, or a
+// Unix timestamp in seconds or milliseconds.
+func jsonTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case string:
+		if n, err := strconv.ParseInt(strings.TrimSpace(t), 10, 64); err == nil {
+			return unixTime(n), true
+		}
+		return parseLooseDate(t, "")
+	case json.Number:
+		n, err := t.Int64()
+		if err != nil {
+			f, ferr := t.Float64()
+			if ferr != nil {
+				return time.Time{}, false
+			}
+			n = int64(f)
+		}
+		return unixTime(n), true
+	}
+	return time.Time{}, false
+}
+
+func unixTime(n int64) time.Time {
+	if n > 1e12 {
+		return time.UnixMilli(n).UTC()
+	}
+	return time.Unix(n, 0).UTC()
+}
@@ -0,0 +1,200 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"news-go/internal/news"
+)
+
+// ErrFeedRejected marks a response the crawler refuses to parse: too
+// large, of a non-feed content type, or declaring XML entities. Retrying
+// will not help, so the syncer gives up on the first attempt.
+var ErrFeedRejected = errors.New("feed rejected")
+
+const (
+	defaultFeedMaxBytes      = 16 << 20
+	defaultFeedMaxItems      = 1000
+	defaultFeedMaxFieldBytes = 1 << 20
+	// maxShortFieldBytes bounds titles, GUIDs, author and category names;
+	// items whose link is longer are dropped.
+	maxShortFieldBytes = 4 << 10
+)
+
+// FeedLimits bounds what parsing one feed document may cost. MaxBytes
+// caps the document, MaxItems the items kept (later ones are skipped and
+// counted in Feed.Truncated) and MaxFieldBytes an item's content. Zero
+// fields use the defaults.
+type FeedLimits struct {
+	MaxBytes      int64
+	MaxItems      int
+	MaxFieldBytes int
+}
+
+func DefaultFeedLimits() FeedLimits {
+	return FeedLimits{MaxBytes: defaultFeedMaxBytes, MaxItems: defaultFeedMaxItems, MaxFieldBytes: defaultFeedMaxFieldBytes}
+}
+
+func (l FeedLimits) withDefaults() FeedLimits {
+	d := DefaultFeedLimits()
+	if l.MaxBytes <= 0 {
+		l.MaxBytes = d.MaxBytes
+	}
+	if l.MaxItems <= 0 {
+		l.MaxItems = d.MaxItems
+	}
+	if l.MaxFieldBytes <= 0 {
+		l.MaxFieldBytes = d.MaxFieldBytes
+	}
+	return l
+}
+
+// Parse reads an RSS, Atom or JSON Feed document from r. XML documents
+// are decoded one item at a time; JSON Feeds are read whole, up to
+// MaxBytes.
+func (l FeedLimits) Parse(r io.Reader, now time.Time) (Feed, error) {
+	l = l.withDefaults()
+	capped := &cappedReader{r: r, left: l.MaxBytes, limit: l.MaxBytes}
+	br := bufio.NewReader(capped)
+	var feed Feed
+	var err error
+	if firstByte(br) == '{' {
+		feed, err = l.parseJSONFeed(br, now)
+	} else {
+		feed, err = decodeXMLFeed(br, l, now)
+	}
+	if capped.err != nil {
+		return Feed{}, capped.err
+	}
+	return feed, err
+}
+
+func (l FeedLimits) parseJSONFeed(r io.Reader, now time.Time) (Feed, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return Feed{}, err
+	}
+	if sniffFeed(body) != FormatJSON {
+		return Feed{}, errors.New("not a feed: JSON document without a JSON Feed version")
+	}
+	parsed, err := parseJSON(body, JSONFeedConfig(), nil, now)
+	if err != nil {
+		return Feed{}, err
+	}
+	items := parsed.Items
+	parsed.Items = make([]news.Article, 0, len(items))
+	for _, a := range items {
+		parsed.keep(a, l)
+	}
+	return parsed, nil
+}
+
+// keep appends a to the feed's items unless MaxItems is reached or its
+// link is unreasonably long, clamping oversized fields.
+func (f *Feed) keep(a news.Article, l FeedLimits) {
+	if len(f.Items) >= l.MaxItems {
+		f.Truncated++
+		return
+	}
+	if len(a.URL) > maxShortFieldBytes {
+		return
+	}
+	a.Title = truncateBytes(a.Title, maxShortFieldBytes)
+	a.GUID = truncateBytes(a.GUID, maxShortFieldBytes)
+	a.ImageURL = truncateBytes(a.ImageURL, maxShortFieldBytes)
+	a.Content = truncateBytes(a.Content, l.MaxFieldBytes)
+	for i := range a.Authors {
+		a.Authors[i] = truncateBytes(a.Authors[i], maxShortFieldBytes)
+	}
+	for i := range a.Categories {
+		a.Categories[i] = truncateBytes(a.Categories[i], maxShortFieldBytes)
+	}
+	f.Items = append(f.Items, a)
+}
+
+// truncateBytes cuts s to at most n bytes without splitting a rune.
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// firstByte peeks past leading whitespace and a byte order mark.
+func firstByte(br *bufio.Reader) byte {
+	head, _ := br.Peek(512)
+	rest := bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if len(rest) == 0 {
+		return 0
+	}
+	return rest[0]
+}
+
+// cappedReader fails with ErrFeedRejected once more than limit bytes come
+// from r; a body of exactly limit bytes is fine.
+type cappedReader struct {
+	r     io.Reader
+	left  int64
+	limit int64
+	err   error
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	if c.left <= 0 {
+		var one [1]byte
+		if n, _ := io.ReadFull(c.r, one[:]); n == 0 {
+			return 0, io.EOF
+		}
+		c.err = fmt.Errorf("%w: body exceeds %d bytes", ErrFeedRejected, c.limit)
+		return 0, c.err
+	}
+	if int64(len(p)) > c.left {
+		p = p[:c.left]
+	}
+	n, err := c.r.Read(p)
+	c.left -= int64(n)
+	return n, err
+}
+
+// checkContentType rejects responses whose Content-Type says they are
+// not a feed. HTML, plain text and octet-stream are let through when the
+// body starts like one, since servers mislabel feeds that way.
+func checkContentType(header string, head []byte) error {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if header == "" || err != nil {
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "font/"),
+		mediaType == "application/pdf", mediaType == "application/zip",
+		mediaType == "application/gzip", mediaType == "application/x-gzip":
+	case mediaType == "text/html", mediaType == "text/plain", mediaType == "application/octet-stream":
+		if looksLikeFeed(head) {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return fmt.Errorf("%w: content type %s is not a feed", ErrFeedRejected, mediaType)
+}
+
+// looksLikeFeed judges the first bytes of a body: a JSON object, or XML
+// whose root element is a feed's.
+func looksLikeFeed(head []byte) bool {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")))
+	return bytes.HasPrefix(trimmed, []byte("{")) || sniffFeed(trimmed) != ""
+}
@@ -0,0 +1,106 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func rssWithItems(n int) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?><rss version="2.0"><channel><title>T</title>`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<item><title>Item %d</title><link>https://ex.test/%d</link></item>`, i, i)
+	}
+	b.WriteString(`<language>de</language></channel></rss>`)
+	return b.String()
+}
+
+func TestFeedLimitsBodySize(t *testing.T) {
+	body := rssWithItems(3)
+	now := time.Now()
+	if _, err := (FeedLimits{MaxBytes: int64(len(body))}).Parse(strings.NewReader(body), now); err != nil {
+		t.Fatalf("body at the limit: %v", err)
+	}
+	_, err := (FeedLimits{MaxBytes: int64(len(body) - 1)}).Parse(strings.NewReader(body), now)
+	if !errors.Is(err, ErrFeedRejected) || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("oversized body: %v", err)
+	}
+	json := `{"version":"https://jsonfeed.org/version/1.1","items":[{"id":"1","url":"https://ex.test/1","title":"One"}]}`
+	if _, err := (FeedLimits{MaxBytes: 20}).Parse(strings.NewReader(json), now); !errors.Is(err, ErrFeedRejected) {
+		t.Fatalf("oversized JSON Feed: %v", err)
+	}
+}
+
+func TestFeedLimitsItemsAndFields(t *testing.T) {
+	feed, err := (FeedLimits{MaxItems: 2}).Parse(strings.NewReader(rssWithItems(5)), time.Now())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(feed.Items) != 2 || feed.Truncated != 3 || feed.Items[1].Title != "Item 1" {
+		t.Fatalf("items = %d truncated = %d", len(feed.Items), feed.Truncated)
+	}
+	// The channel language follows the items.
+	if feed.Items[0].Language != "de" {
+		t.Errorf("language = %q", feed.Items[0].Language)
+	}
+
+	long := `<?xml version="1.0"?><rss version="2.0"><channel>
+<item><title>` + strings.Repeat("é", 3000) + `</title><link>https://ex.test/a</link><description>` + strings.Repeat("x", 100) + `</description></item>
+<item><title>Long link</title><link>https://ex.test/` + strings.Repeat("a", 5000) + `</link></item>
+</channel></rss>`
+	feed, err = (FeedLimits{MaxFieldBytes: 10}).Parse(strings.NewReader(long), time.Now())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(feed.Items) != 1 {
+		t.Fatalf("item with oversized link kept: %d items", len(feed.Items))
+	}
+	a := feed.Items[0]
+	if len(a.Title) != maxShortFieldBytes || a.Title != strings.Repeat("é", maxShortFieldBytes/2) || a.Content != strings.Repeat("x", 10) {
+		t.Errorf("title %d bytes, content %q", len(a.Title), a.Content)
+	}
+}
+
+func TestParseFeedRejectsEntities(t *testing.T) {
+	body := `<?xml version="1.0"?>
+<!DOCTYPE rss [<!ENTITY a "aaaaaaaaaa"><!ENTITY b "&a;&a;&a;&a;&a;">]>
+<rss version="2.0"><channel><item><title>&b;</title><link>https://ex.test/1</link></item></channel></rss>`
+	if _, err := ParseFeed([]byte(body), time.Now()); !errors.Is(err, ErrFeedRejected) {
+		t.Fatalf("entity declarations: %v", err)
+	}
+}
+
+func TestFetchFeedContentTypes(t *testing.T) {
+	feed := rssWithItems(1)
+	cases := []struct {
+		contentType, body string
+		rejected          bool
+	}{
+		{"application/rss+xml; charset=utf-8", feed, false},
+		{"text/html; charset=utf-8", feed, false},
+		{"text/html", "<!doctype html><html><body>Sign in</body></html>", true},
+		{"image/png", feed, true},
+		{"", feed, false},
+	}
+	for _, tc := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if tc.contentType != "" {
+				w.Header().Set("Content-Type", tc.contentType)
+			} else {
+				w.Header()["Content-Type"] = nil
+			}
+			fmt.Fprint(w, tc.body)
+		}))
+		_, err := NewRSSFetcherWithClient(srv.Client()).FetchFeed(context.Background(), srv.URL, "")
+		srv.Close()
+		if got := errors.Is(err, ErrFeedRejected); got != tc.rejected {
+			t.Errorf("%q: err = %v, want rejected %v", tc.contentType, err, tc.rejected)
+		}
+	}
+}
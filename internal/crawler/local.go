@@ -0,0 +1,122 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalAccess is what the operator lets sources reach on the crawler's own
+// host. It is handed to ValidateSource, NewFetchers, NewFileFetcher,
+// NewEmailFetcher and NewSourceTransport. Sources are created through an
+// unauthenticated API, so the zero value reaches nothing.
+type LocalAccess struct {
+	// FileRoot is the directory that file:// sources must stay within; ""
+	// refuses them.
+	FileRoot string
+	// CADir holds the PEM bundles sources may name as ca_file; "" refuses
+	// ca_file.
+	CADir string
+	// SecretEnvPrefix starts the names of the environment variables that
+	// sources may read passwords and tokens from; "" allows none.
+	SecretEnvPrefix string
+}
+
+// localPath returns the path of a file:// URL with symlinks resolved,
+// provided it lies within FileRoot.
+func (a LocalAccess) localPath(raw string) (string, error) {
+	path, err := filePath(raw)
+	if err != nil {
+		return "", err
+	}
+	return confine(a.FileRoot, "FILE_SOURCE_ROOT", path)
+}
+
+// errCAFile is the only ca_file error, so it cannot be used to probe
+// which files exist.
+var errCAFile = errors.New("ca_file must name a PEM certificate file in CA_FILE_DIR")
+
+// caPath resolves a ca_file, relative to CADir unless absolute.
+func (a LocalAccess) caPath(name string) (string, error) {
+	dir := a.CADir
+	if dir == "" {
+		return "", errCAFile
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+	path, err := confine(dir, "CA_FILE_DIR", name)
+	if err != nil {
+		return "", errCAFile
+	}
+	return path, nil
+}
+
+// checkSecretEnv reports whether a source may read the environment variable
+// name, so that a source cannot send, say, DB credentials to its own host.
+func (a LocalAccess) checkSecretEnv(name string) error {
+	if !validEnvName(name) {
+		return fmt.Errorf("invalid environment variable %q", name)
+	}
+	prefix := a.SecretEnvPrefix
+	if prefix == "" {
+		return errors.New("secrets from the environment are disabled; set SECRET_ENV_PREFIX")
+	}
+	if len(name) <= len(prefix) || !strings.HasPrefix(name, prefix) {
+		return fmt.Errorf("environment variable %s does not start with %s", name, prefix)
+	}
+	return nil
+}
+
+// secretEnv reads a password or token from an environment variable that
+// carries the SecretEnvPrefix.
+func (a LocalAccess) secretEnv(name string) (string, error) {
+	if err := a.checkSecretEnv(name); err != nil {
+		return "", err
+	}
+	v := os.Getenv(name)
+	if v == "" {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return v, nil
+}
+
+// confine returns path with symlinks resolved, provided it lies within
+// root, the directory configured by setting. Paths outside root are refused
+// before the filesystem is consulted, so the error does not tell whether
+// they exist.
+func confine(root, setting, path string) (string, error) {
+	if root == "" {
+		return "", fmt.Errorf("%s is not set", setting)
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	realRoot, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", setting, err)
+	}
+	outside := fmt.Errorf("path is outside %s", setting)
+	path = filepath.Clean(path)
+	if !within(abs, path) && !within(realRoot, path) {
+		return "", outside
+	}
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	if !within(realRoot, real) {
+		return "", outside
+	}
+	return real, nil
+}
+
+// within reports whether path is dir or inside it; both must be clean
+// absolute paths.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,212 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// maxMailBytes bounds one message and maxMailboxBytes an mbox file.
+	maxMailBytes    = 8 << 20
+	maxMailboxBytes = 256 << 20
+	// maxMIMEDepth bounds nested multipart bodies.
+	maxMIMEDepth = 5
+)
+
+// mailMessage is the part of an email newsletter the email kind reads.
+type mailMessage struct {
+	ID       string
+	Subject  string
+	FromName string
+	FromAddr string
+	Date     time.Time
+	HTML     string
+	Text     string
+}
+
+// author is the sender's display name, or the address without one.
+func (m mailMessage) author() string {
+	if m.FromName != "" {
+		return m.FromName
+	}
+	return m.FromAddr
+}
+
+var headerDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// parseMail reads an RFC 5322 message, keeping the first text/html and
+// text/plain bodies that are not attachments.
+func parseMail(raw []byte) (mailMessage, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return mailMessage{}, err
+	}
+	m := mailMessage{ID: strings.Trim(strings.TrimSpace(msg.Header.Get("Message-Id")), "<>")}
+	if s, err := headerDecoder.DecodeHeader(msg.Header.Get("Subject")); err == nil {
+		m.Subject = strings.Join(strings.Fields(s), " ")
+	} else {
+		m.Subject = strings.Join(strings.Fields(msg.Header.Get("Subject")), " ")
+	}
+	if addr, err := (&mail.AddressParser{WordDecoder: headerDecoder}).Parse(msg.Header.Get("From")); err == nil {
+		m.FromName, m.FromAddr = strings.TrimSpace(addr.Name), strings.ToLower(addr.Address)
+	}
+	if d, err := msg.Header.Date(); err == nil {
+		m.Date = d.UTC()
+	}
+	if err := m.readPart(textproto.MIMEHeader(msg.Header), msg.Body, 0); err != nil {
+		return mailMessage{}, err
+	}
+	return m, nil
+}
+
+func (m *mailMessage) readPart(h textproto.MIMEHeader, body io.Reader, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	if disp, _, _ := mime.ParseMediaType(h.Get("Content-Disposition")); disp == "attachment" {
+		return nil
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxMIMEDepth || params["boundary"] == "" {
+			return nil
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.readPart(p.Header, p, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	if mediaType != "text/html" && mediaType != "text/plain" {
+		return nil
+	}
+	if mediaType == "text/html" && m.HTML != "" || mediaType == "text/plain" && m.Text != "" {
+		return nil
+	}
+	var r io.Reader = io.LimitReader(body, maxMailBytes)
+	switch strings.ToLower(strings.TrimSpace(h.Get("Content-Transfer-Encoding"))) {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+	if r, err = charsetReader(params["charset"], r); err != nil {
+		return err
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if mediaType == "text/html" {
+		m.HTML = string(b)
+	} else {
+		m.Text = string(b)
+	}
+	return nil
+}
+
+// readMbox splits an mbox file into raw messages. "From " lines start a
+// message; mboxrd quoting (">From ") is undone.
+func readMbox(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(io.LimitReader(f, maxMailboxBytes))
+	sc.Buffer(make([]byte, 64<<10), maxMailBytes)
+	var msgs [][]byte
+	var cur *bytes.Buffer
+	prevBlank := true
+	for sc.Scan() {
+		line := sc.Bytes()
+		if prevBlank && bytes.HasPrefix(line, []byte("From ")) {
+			if cur != nil {
+				msgs = append(msgs, cur.Bytes())
+			}
+			cur = &bytes.Buffer{}
+			prevBlank = false
+			continue
+		}
+		prevBlank = len(bytes.TrimRight(line, "\r")) == 0
+		if cur == nil {
+			continue
+		}
+		if unquoted := bytes.TrimLeft(line, ">"); len(unquoted) < len(line) && bytes.HasPrefix(unquoted, []byte("From ")) {
+			line = line[1:]
+		}
+		cur.Write(line)
+		cur.WriteString("\n")
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if cur != nil {
+		msgs = append(msgs, cur.Bytes())
+	}
+	return msgs, nil
+}
+
+// readMailDir reads a Maildir's new and cur folders, or every regular file
+// of a plain directory of .eml files.
+func readMailDir(dir string) ([][]byte, error) {
+	folders := []string{dir}
+	if isDir(filepath.Join(dir, "cur")) || isDir(filepath.Join(dir, "new")) {
+		folders = []string{filepath.Join(dir, "new"), filepath.Join(dir, "cur")}
+	}
+	var msgs [][]byte
+	for _, folder := range folders {
+		entries, err := os.ReadDir(folder)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			b, err := readLimited(filepath.Join(folder, e.Name()), maxMailBytes)
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, b)
+		}
+	}
+	return msgs, nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func readLimited(path string, limit int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, limit))
+}
@@ -0,0 +1,260 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job is one unit of crawl work. Host groups jobs for politeness limits and
+// fair scheduling; use HostOf to derive it from a feed URL. Run starts
+// holding slot and should release it once it is done with the host.
+type Job struct {
+	Host string
+	Run  func(ctx context.Context, slot *HostSlot)
+}
+
+// HostOf returns the lower-cased host[:port] of rawURL, or rawURL itself if
+// it does not parse, so malformed URLs still get their own bucket.
+func HostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return strings.ToLower(u.Host)
+}
+
+// HostLimiter enforces a per-host cap on in-flight work and a minimum gap
+// between consecutive starts on the same host.
+type HostLimiter struct {
+	MaxInFlight int
+	MinDelay    time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+	// freed is closed and replaced whenever any host's slot is released.
+	freed chan struct{}
+}
+
+type hostState struct {
+	inFlight  int
+	lastStart time.Time
+	// wake is closed and replaced whenever a slot is released.
+	wake chan struct{}
+}
+
+func NewHostLimiter(maxInFlight int, minDelay time.Duration) *HostLimiter {
+	return &HostLimiter{MaxInFlight: maxInFlight, MinDelay: minDelay, hosts: map[string]*hostState{}, freed: make(chan struct{})}
+}
+
+// Acquire blocks until host may be hit again or ctx is done. The returned
+// func releases the slot and must be called exactly once.
+func (l *HostLimiter) Acquire(ctx context.Context, host string) (func(), error) {
+	for {
+		release, wait, wake := l.tryAcquire(host)
+		if release != nil {
+			return release, nil
+		}
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-wake:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// tryAcquire takes a slot of host if one is free now. Otherwise it returns
+// how long until the minimum delay has passed, 0 when the host is full,
+// and a channel closed when the host releases a slot.
+func (l *HostLimiter) tryAcquire(host string) (func(), time.Duration, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st, ok := l.hosts[host]
+	if !ok {
+		st = &hostState{wake: make(chan struct{})}
+		l.hosts[host] = st
+	}
+	now := time.Now()
+	if l.MaxInFlight > 0 && st.inFlight >= l.MaxInFlight {
+		return nil, 0, st.wake
+	}
+	if !st.lastStart.IsZero() {
+		if wait := st.lastStart.Add(l.MinDelay).Sub(now); wait > 0 {
+			return nil, wait, st.wake
+		}
+	}
+	st.inFlight++
+	st.lastStart = now
+	return func() { l.release(host) }, 0, nil
+}
+
+func (l *HostLimiter) release(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st := l.hosts[host]
+	st.inFlight--
+	close(st.wake)
+	st.wake = make(chan struct{})
+	close(l.freed)
+	l.freed = make(chan struct{})
+}
+
+// anyFreed returns a channel closed when any host next releases a slot.
+func (l *HostLimiter) anyFreed() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.freed
+}
+
+// HostSlot is a job's claim on its host. A job releases it as soon as it
+// is done with the host, e.g. after fetching, so backoff sleeps and work
+// elsewhere do not keep the host from other jobs, and acquires it again
+// before the next request, e.g. a retry. A nil HostSlot limits nothing.
+type HostSlot struct {
+	limiter *HostLimiter
+	host    string
+	release func()
+}
+
+// Acquire waits for a slot of the host unless the job already holds one.
+func (s *HostSlot) Acquire(ctx context.Context) error {
+	if s == nil || s.limiter == nil || s.release != nil {
+		return nil
+	}
+	release, err := s.limiter.Acquire(ctx, s.host)
+	if err != nil {
+		return err
+	}
+	s.release = release
+	return nil
+}
+
+// Release gives the slot back; releasing a slot not held does nothing.
+func (s *HostSlot) Release() {
+	if s == nil || s.release == nil {
+		return
+	}
+	s.release()
+	s.release = nil
+}
+
+// Pool runs jobs with a global concurrency cap. Dispatch rotates across
+// hosts so many sections of one publisher cannot starve everyone else, and
+// a worker only takes a job whose host has a free HostLimiter slot, so
+// jobs waiting for a busy host never occupy workers.
+type Pool struct {
+	Workers int
+	Limiter *HostLimiter
+}
+
+func NewPool(workers int, limiter *HostLimiter) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Pool{Workers: workers, Limiter: limiter}
+}
+
+// Run blocks until every job finished or ctx is done. Jobs not yet started
+// when ctx is cancelled are dropped.
+func (p *Pool) Run(ctx context.Context, jobs []Job) {
+	sem := make(chan struct{}, p.Workers)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	pending := fairOrder(jobs)
+	for len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case sem <- struct{}{}:
+		}
+		i, slot, err := p.claim(ctx, pending)
+		if err != nil {
+			<-sem
+			return
+		}
+		job := pending[i]
+		pending = append(pending[:i:i], pending[i+1:]...)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer slot.Release()
+			job.Run(ctx, slot)
+		}()
+	}
+}
+
+// claim waits until one of pending can start and takes its host slot,
+// preferring jobs earlier in pending.
+func (p *Pool) claim(ctx context.Context, pending []Job) (int, *HostSlot, error) {
+	if p.Limiter == nil {
+		return 0, nil, nil
+	}
+	for {
+		freed := p.Limiter.anyFreed()
+		var soonest time.Duration
+		for i, job := range pending {
+			release, wait, _ := p.Limiter.tryAcquire(job.Host)
+			if release != nil {
+				return i, &HostSlot{limiter: p.Limiter, host: job.Host, release: release}, nil
+			}
+			if wait > 0 && (soonest == 0 || wait < soonest) {
+				soonest = wait
+			}
+		}
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if soonest > 0 {
+			timer = time.NewTimer(soonest)
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-freed:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+// fairOrder interleaves jobs round-robin by host, keeping the original order
+// within a host and the order in which hosts first appear.
+func fairOrder(jobs []Job) []Job {
+	queues := map[string][]Job{}
+	hosts := []string{}
+	for _, j := range jobs {
+		if _, ok := queues[j.Host]; !ok {
+			hosts = append(hosts, j.Host)
+		}
+		queues[j.Host] = append(queues[j.Host], j)
+	}
+	out := make([]Job, 0, len(jobs))
+	for len(out) < len(jobs) {
+		for _, h := range hosts {
+			if q := queues[h]; len(q) > 0 {
+				out = append(out, q[0])
+				queues[h] = q[1:]
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,143 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFairOrder(t *testing.T) {
+	jobs := []Job{{Host: "a"}, {Host: "a"}, {Host: "a"}, {Host: "b"}, {Host: "c"}, {Host: "b"}}
+	got := ""
+	for _, j := range fairOrder(jobs) {
+		got += j.Host
+	}
+	if got != "abcaba" {
+		t.Fatalf("expected round-robin order abcaba, got %s", got)
+	}
+}
+
+func TestPoolRespectsLimits(t *testing.T) {
+	var mu sync.Mutex
+	inFlight := map[string]int{}
+	starts := map[string][]time.Time{}
+	var global, maxGlobal int32
+	var maxPerHost int
+
+	job := func(host string) Job {
+		return Job{Host: host, Run: func(context.Context, *HostSlot) {
+			n := atomic.AddInt32(&global, 1)
+			for {
+				m := atomic.LoadInt32(&maxGlobal)
+				if n <= m || atomic.CompareAndSwapInt32(&maxGlobal, m, n) {
+					break
+				}
+			}
+			mu.Lock()
+			inFlight[host]++
+			if inFlight[host] > maxPerHost {
+				maxPerHost = inFlight[host]
+			}
+			starts[host] = append(starts[host], time.Now())
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			inFlight[host]--
+			mu.Unlock()
+			atomic.AddInt32(&global, -1)
+		}}
+	}
+	jobs := []Job{}
+	for i := 0; i < 3; i++ {
+		jobs = append(jobs, job("bbc"), job("reuters"), job("nyt"))
+	}
+	NewPool(2, NewHostLimiter(1, 20*time.Millisecond)).Run(context.Background(), jobs)
+
+	if maxGlobal > 2 {
+		t.Fatalf("expected at most 2 concurrent jobs, saw %d", maxGlobal)
+	}
+	if maxPerHost > 1 {
+		t.Fatalf("expected at most 1 in-flight job per host, saw %d", maxPerHost)
+	}
+	for host, ts := range starts {
+		if len(ts) != 3 {
+			t.Fatalf("host %s ran %d jobs, expected 3", host, len(ts))
+		}
+		for i := 1; i < len(ts); i++ {
+			if gap := ts[i].Sub(ts[i-1]); gap < 20*time.Millisecond {
+				t.Fatalf("host %s started jobs %s apart, expected >= 20ms", host, gap)
+			}
+		}
+	}
+}
+
+func TestHostLimiterHonorsContext(t *testing.T) {
+	l := NewHostLimiter(1, 0)
+	release, err := l.Acquire(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, "a"); err == nil {
+		t.Fatalf("expected busy host to block until context deadline")
+	}
+}
+
+// A slow host must not tie up the workers other hosts could use.
+func TestPoolDoesNotParkWorkersOnBusyHost(t *testing.T) {
+	var mu sync.Mutex
+	var firstSlow, lastFast time.Time
+	jobs := []Job{}
+	for i := 0; i < 4; i++ {
+		jobs = append(jobs, Job{Host: "slow", Run: func(context.Context, *HostSlot) {
+			time.Sleep(50 * time.Millisecond)
+			mu.Lock()
+			if firstSlow.IsZero() {
+				firstSlow = time.Now()
+			}
+			mu.Unlock()
+		}}, Job{Host: "fast", Run: func(context.Context, *HostSlot) {
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			lastFast = time.Now()
+			mu.Unlock()
+		}})
+	}
+	NewPool(2, NewHostLimiter(1, 0)).Run(context.Background(), jobs)
+	if !lastFast.Before(firstSlow) {
+		t.Fatalf("fast host finished %s after the first slow job", lastFast.Sub(firstSlow))
+	}
+}
+
+func TestPoolJobReleasesHostEarly(t *testing.T) {
+	started := make(chan struct{}, 2)
+	var overlapped atomic.Bool
+	job := Job{Host: "a", Run: func(ctx context.Context, slot *HostSlot) {
+		started <- struct{}{}
+		slot.Release()
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+		}
+		if len(started) == 2 {
+			overlapped.Store(true)
+		}
+		// Taking the slot again waits for the other job to let go.
+		if err := slot.Acquire(ctx); err != nil {
+			t.Error(err)
+		}
+	}}
+	job2 := job
+	job2.Run = func(ctx context.Context, slot *HostSlot) {
+		started <- struct{}{}
+		time.Sleep(10 * time.Millisecond)
+	}
+	NewPool(2, NewHostLimiter(1, 0)).Run(context.Background(), []Job{job, job2})
+	if !overlapped.Load() {
+		t.Fatal("second job waited for the first to finish")
+	}
+}
@@ -0,0 +1,186 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatusError is returned by fetchers for non-2xx responses. RetryAfter is
+// set when a 429 or 503 carried a parseable Retry-After header.
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rss status: %d (retry after %s)", e.StatusCode, e.RetryAfter)
+	}
+	return fmt.Sprintf("rss status: %d", e.StatusCode)
+}
+
+func newStatusError(resp *http.Response, now time.Time) *StatusError {
+	e := &StatusError{StatusCode: resp.StatusCode}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		e.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	}
+	return e
+}
+
+// parseRetryAfter accepts both delay-seconds and HTTP-date forms.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// Backoff computes exponential retry delays with jitter. Jitter is the
+// fraction of each delay that is randomised, e.g. 0.5 yields a delay in
+// [d/2, d].
+type Backoff struct {
+	Base   time.Duration
+	Max    time.Duration
+	Jitter float64
+}
+
+// Delay returns the wait before retry number attempt (1-based).
+func (b Backoff) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := b.Base
+	for i := 1; i < attempt && (b.Max <= 0 || d < b.Max); i++ {
+		d *= 2
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	if b.Jitter > 0 && d > 0 {
+		j := b.Jitter
+		if j > 1 {
+			j = 1
+		}
+		spread := time.Duration(float64(d) * j)
+		d = d - spread + time.Duration(rand.Int63n(int64(spread)+1))
+	}
+	return d
+}
+
+// RetryDelay prefers the server's Retry-After hint over the computed
+// backoff. The second result is false when the hint exceeds b.Max, in which
+// case the caller should give up and park the source instead of waiting.
+func (b Backoff) RetryDelay(err error, attempt int) (time.Duration, bool) {
+	var se *StatusError
+	if errors.As(err, &se) && se.RetryAfter > 0 {
+		if b.Max > 0 && se.RetryAfter > b.Max {
+			return se.RetryAfter, false
+		}
+		return se.RetryAfter, true
+	}
+	return b.Delay(attempt), true
+}
+
+// Sleep waits for d or until ctx is done, whichever comes first.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Breaker is a per-key circuit breaker. After Threshold consecutive failures
+// a key is parked for Cooldown; once that passes one trial is allowed and
+// its outcome closes or re-opens the circuit.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu    sync.Mutex
+	state map[string]*breakerState
+}
+
+type breakerState struct {
+	failures  int
+	openUntil time.Time
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{Threshold: threshold, Cooldown: cooldown, state: map[string]*breakerState{}}
+}
+
+// Allow reports whether key may be crawled at now, and if not, until when
+// it is parked.
+func (b *Breaker) Allow(key string, now time.Time) (bool, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st, ok := b.state[key]
+	if !ok || !now.Before(st.openUntil) {
+		return true, time.Time{}
+	}
+	return false, st.openUntil
+}
+
+func (b *Breaker) Success(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.state, key)
+}
+
+// Failure records a failed run and returns the time the key is parked
+// until, or the zero time if the circuit is still closed.
+func (b *Breaker) Failure(key string, now time.Time) time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := b.entry(key)
+	st.failures++
+	if b.Threshold > 0 && st.failures >= b.Threshold {
+		st.openUntil = now.Add(b.Cooldown)
+		return st.openUntil
+	}
+	return time.Time{}
+}
+
+// Trip parks key until the given time regardless of its failure count, for
+// example when the server asked us to back off for longer than we retry.
+func (b *Breaker) Trip(key string, until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := b.entry(key)
+	if until.After(st.openUntil) {
+		st.openUntil = until
+	}
+}
+
+func (b *Breaker) entry(key string) *breakerState {
+	st, ok := b.state[key]
+	if !ok {
+		st = &breakerState{}
+		b.state[key] = st
+	}
+	return st
+}
@@ -0,0 +1,90 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Base: time.Second, Max: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := b.Delay(i + 1); got != w {
+			t.Fatalf("attempt %d: expected %s, got %s", i+1, w, got)
+		}
+	}
+	b.Jitter = 0.5
+	for i := 0; i < 50; i++ {
+		if d := b.Delay(3); d < 2*time.Second || d > 4*time.Second {
+			t.Fatalf("jittered delay out of range: %s", d)
+		}
+	}
+}
+
+func TestRetryDelayHonorsRetryAfter(t *testing.T) {
+	b := Backoff{Base: time.Second, Max: time.Minute}
+	if d, ok := b.RetryDelay(&StatusError{StatusCode: 429, RetryAfter: 30 * time.Second}, 1); !ok || d != 30*time.Second {
+		t.Fatalf("expected 30s retry, got %s ok=%v", d, ok)
+	}
+	if _, ok := b.RetryDelay(&StatusError{StatusCode: 503, RetryAfter: time.Hour}, 1); ok {
+		t.Fatalf("expected hint above cap to abort retries")
+	}
+	if d, _ := b.RetryDelay(errors.New("boom"), 2); d != 2*time.Second {
+		t.Fatalf("expected backoff delay, got %s", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if d := parseRetryAfter("120", now); d != 2*time.Minute {
+		t.Fatalf("expected 2m, got %s", d)
+	}
+	if d := parseRetryAfter("Thu, 01 Jan 2026 00:00:30 GMT", now); d != 30*time.Second {
+		t.Fatalf("expected 30s, got %s", d)
+	}
+	if d := parseRetryAfter("soon", now); d != 0 {
+		t.Fatalf("expected 0 for garbage, got %s", d)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker(2, time.Minute)
+	if until := b.Failure("bbc", now); !until.IsZero() {
+		t.Fatalf("expected circuit closed after one failure")
+	}
+	until := b.Failure("bbc", now)
+	if !until.Equal(now.Add(time.Minute)) {
+		t.Fatalf("expected circuit open until %s, got %s", now.Add(time.Minute), until)
+	}
+	if ok, _ := b.Allow("bbc", now.Add(30*time.Second)); ok {
+		t.Fatalf("expected parked source to be refused")
+	}
+	if ok, _ := b.Allow("reuters", now); !ok {
+		t.Fatalf("expected other sources unaffected")
+	}
+	if ok, _ := b.Allow("bbc", now.Add(time.Minute)); !ok {
+		t.Fatalf("expected trial run after cooldown")
+	}
+	if until := b.Failure("bbc", now.Add(time.Minute)); until.IsZero() {
+		t.Fatalf("expected failed trial to re-open circuit")
+	}
+	b.Success("bbc")
+	if ok, _ := b.Allow("bbc", now.Add(time.Minute)); !ok {
+		t.Fatalf("expected success to close circuit")
+	}
+}
+
+func TestSleepHonorsContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := Sleep(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("sleep did not return promptly")
+	}
+}
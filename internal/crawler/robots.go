@@ -0,0 +1,344 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDisallowed is wrapped by every PolicyError so callers can tell a refusal
+// apart from a network failure and skip pointless retries.
+var ErrDisallowed = errors.New("disallowed by crawl policy")
+
+type PolicyError struct {
+	URL    string
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%v: %s (%s)", ErrDisallowed, e.URL, e.Reason)
+}
+
+func (e *PolicyError) Unwrap() error { return ErrDisallowed }
+
+// RobotsRules is the merged rule set of the robots.txt groups that apply to
+// one user agent.
+type RobotsRules struct {
+	rules      []robotsRule
+	CrawlDelay time.Duration
+	Sitemaps   []string
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// allowAll and disallowAll stand in for missing (4xx) and unreachable (5xx
+// or network error) robots.txt files, following RFC 9309.
+var (
+	allowAll    = &RobotsRules{}
+	disallowAll = &RobotsRules{rules: []robotsRule{{allow: false, pattern: "/", re: compileRobotsPattern("/")}}}
+)
+
+// ParseRobots reads a robots.txt body and keeps the groups addressed to
+// agent, falling back to the "*" group when none names it. agent is a
+// product token, e.g. "news-go", and must equal a group's token up to case:
+// a "news" group does not apply to "newsbot".
+func ParseRobots(r io.Reader, agent string) *RobotsRules {
+	agent = strings.ToLower(agent)
+	type group struct {
+		agents []string
+		rules  []robotsRule
+		delay  time.Duration
+	}
+	var groups []*group
+	var cur *group
+	inAgents := false
+	out := &RobotsRules{}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				cur = &group{}
+				groups = append(groups, cur)
+				inAgents = true
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inAgents = false
+			if cur == nil || value == "" {
+				continue
+			}
+			cur.rules = append(cur.rules, robotsRule{allow: key == "allow", pattern: value, re: compileRobotsPattern(value)})
+		case "crawl-delay":
+			inAgents = false
+			if cur == nil {
+				continue
+			}
+			if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+				cur.delay = time.Duration(secs * float64(time.Second))
+			}
+		case "sitemap":
+			out.Sitemaps = append(out.Sitemaps, value)
+		}
+	}
+
+	var matched, wildcard []*group
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a == "*" {
+				wildcard = append(wildcard, g)
+				break
+			}
+			if agent != "" && productToken(a) == agent {
+				matched = append(matched, g)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		matched = wildcard
+	}
+	for _, g := range matched {
+		out.rules = append(out.rules, g.rules...)
+		if g.delay > out.CrawlDelay {
+			out.CrawlDelay = g.delay
+		}
+	}
+	return out
+}
+
+// Allowed evaluates path (with query) using longest-match precedence; on a
+// tie Allow wins.
+func (r *RobotsRules) Allowed(path string) (bool, string) {
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true, ""
+	}
+	best, allowed, rule := -1, true, ""
+	for _, rl := range r.rules {
+		if !rl.re.MatchString(path) {
+			continue
+		}
+		n := len(rl.pattern)
+		if n > best || (n == best && rl.allow && !allowed) {
+			best, allowed = n, rl.allow
+			rule = rl.pattern
+		}
+	}
+	return allowed, rule
+}
+
+// compileRobotsPattern supports the "*" wildcard and a trailing "$" anchor;
+// everything else matches literally as a path prefix.
+func compileRobotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// RobotsCache fetches and caches robots.txt per scheme and host.
+type RobotsCache struct {
+	client *http.Client
+	agent  string
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	entries map[string]robotsEntry
+}
+
+type robotsEntry struct {
+	rules   *RobotsRules
+	expires time.Time
+}
+
+// robotsErrorTTL bounds how long an unreachable robots.txt blocks a host.
+const robotsErrorTTL = 10 * time.Minute
+
+// robotsMaxBytes follows the RFC 9309 minimum parsing limit.
+const robotsMaxBytes = 500 * 1024
+
+// NewRobotsCache uses client for robots.txt requests; it must not itself be
+// wrapped in a PolicyTransport. userAgent is sent as-is and its product
+// token selects the rule group.
+func NewRobotsCache(client *http.Client, userAgent string, ttl time.Duration) *RobotsCache {
+	return &RobotsCache{client: client, agent: userAgent, ttl: ttl, entries: map[string]robotsEntry{}}
+}
+
+func (c *RobotsCache) Rules(ctx context.Context, u *url.URL) *RobotsRules {
+	key := u.Scheme + "://" + strings.ToLower(u.Host)
+	now := time.Now()
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && now.Before(e.expires) {
+		c.mu.Unlock()
+		return e.rules
+	}
+	c.mu.Unlock()
+
+	rules, ttl := c.fetch(ctx, key)
+	if ctx.Err() != nil {
+		// A cancelled caller says nothing about the host; don't cache it.
+		return rules
+	}
+	c.mu.Lock()
+	c.entries[key] = robotsEntry{rules: rules, expires: now.Add(ttl)}
+	c.mu.Unlock()
+	return rules
+}
+
+func (c *RobotsCache) fetch(ctx context.Context, base string) (*RobotsRules, time.Duration) {
+	errTTL := robotsErrorTTL
+	if c.ttl < errTTL {
+		errTTL = c.ttl
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/robots.txt", nil)
+	if err != nil {
+		return disallowAll, errTTL
+	}
+	if c.agent != "" {
+		req.Header.Set("User-Agent", c.agent)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return disallowAll, errTTL
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return ParseRobots(io.LimitReader(resp.Body, robotsMaxBytes), productToken(c.agent)), c.ttl
+	case resp.StatusCode >= 400 && resp.StatusCode <= 499:
+		return allowAll, c.ttl
+	default:
+		return disallowAll, errTTL
+	}
+}
+
+// productToken turns "news-go/1.0 (+https://x)" into "news-go".
+func productToken(userAgent string) string {
+	tok := strings.Fields(userAgent)
+	if len(tok) == 0 {
+		return ""
+	}
+	name, _, _ := strings.Cut(tok[0], "/")
+	return strings.ToLower(name)
+}
+
+// CrawlPolicy decides whether a URL may be fetched and paces requests to a
+// host according to its Crawl-delay.
+type CrawlPolicy struct {
+	robots *RobotsCache
+
+	mu       sync.Mutex
+	nextSlot map[string]time.Time
+}
+
+func NewCrawlPolicy(robots *RobotsCache) *CrawlPolicy {
+	return &CrawlPolicy{robots: robots, nextSlot: map[string]time.Time{}}
+}
+
+// Check returns a *PolicyError when robots.txt disallows rawURL; the
+// refusal and its reason are logged. Otherwise it waits out any Crawl-delay
+// for the host before returning. A caller whose ctx ends before its slot
+// gives the slot back, so timed-out requests do not push the host's queue
+// ever further out.
+func (p *CrawlPolicy) Check(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+	rules := p.robots.Rules(ctx, u)
+	if ok, rule := rules.Allowed(u.RequestURI()); !ok {
+		reason := "robots.txt unreachable"
+		if rules != disallowAll {
+			reason = "robots.txt disallows " + rule
+		}
+		log.Printf("event=crawl_policy status=refused url=%s reason=%q", rawURL, reason)
+		return &PolicyError{URL: rawURL, Reason: reason}
+	}
+	if rules.CrawlDelay <= 0 {
+		return nil
+	}
+	host := strings.ToLower(u.Host)
+	p.mu.Lock()
+	now := time.Now()
+	slot := p.nextSlot[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	if deadline, ok := ctx.Deadline(); ok && slot.After(deadline) {
+		p.mu.Unlock()
+		return fmt.Errorf("crawl-delay for %s: next slot in %s: %w", host, slot.Sub(now).Round(time.Millisecond), context.DeadlineExceeded)
+	}
+	end := slot.Add(rules.CrawlDelay)
+	p.nextSlot[host] = end
+	p.mu.Unlock()
+	if err := Sleep(ctx, slot.Sub(now)); err != nil {
+		p.mu.Lock()
+		// Later callers already hold slots after ours; only the last
+		// reservation can be returned without overlapping theirs.
+		if p.nextSlot[host].Equal(end) {
+			p.nextSlot[host] = slot
+		}
+		p.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// PolicyTransport applies a CrawlPolicy to every request made through it,
+// so any client built on it is compliant by construction.
+type PolicyTransport struct {
+	Base   http.RoundTripper
+	Policy *CrawlPolicy
+}
+
+func NewPolicyTransport(base http.RoundTripper, policy *CrawlPolicy) *PolicyTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &PolicyTransport{Base: base, Policy: policy}
+}
+
+func (t *PolicyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Policy != nil {
+		if err := t.Policy.Check(req.Context(), req.URL.String()); err != nil {
+			return nil, err
+		}
+	}
+	return t.Base.RoundTrip(req)
+}
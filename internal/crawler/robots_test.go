@@ -0,0 +1,178 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleRobots = `
+# comment
+User-agent: *
+Disallow: /private/
+Allow: /private/feed.xml
+
+User-agent: news-go
+User-agent: otherbot
+Disallow: /search
+Disallow: /*.pdf$
+Allow: /search/rss
+Crawl-delay: 0.05
+
+Sitemap: https://example.test/sitemap.xml
+`
+
+func TestParseRobots(t *testing.T) {
+	rules := ParseRobots(strings.NewReader(sampleRobots), "news-go")
+	cases := map[string]bool{
+		"/":                 true,
+		"/private/x":        true, // the news-go group replaces "*"
+		"/search?q=ai":      false,
+		"/search/rss":       true,
+		"/docs/report.pdf":  false,
+		"/docs/report.pdfx": true,
+		"/robots.txt":       true,
+	}
+	for path, want := range cases {
+		if got, _ := rules.Allowed(path); got != want {
+			t.Errorf("%s: expected allowed=%v", path, want)
+		}
+	}
+	if rules.CrawlDelay != 50*time.Millisecond {
+		t.Fatalf("expected 50ms crawl delay, got %s", rules.CrawlDelay)
+	}
+	if len(rules.Sitemaps) != 1 {
+		t.Fatalf("expected sitemap to be collected")
+	}
+
+	for _, agent := range []string{"news", "news-gobot", "NEWS-GO"} {
+		r := ParseRobots(strings.NewReader(sampleRobots), strings.ToLower(agent))
+		allowed, _ := r.Allowed("/search")
+		if applied := !allowed; applied != (agent == "NEWS-GO") {
+			t.Errorf("%s: news-go group applied=%v", agent, applied)
+		}
+	}
+
+	generic := ParseRobots(strings.NewReader(sampleRobots), "someone-else")
+	if ok, _ := generic.Allowed("/private/x"); ok {
+		t.Fatalf("expected * group to disallow /private/x")
+	}
+	if ok, _ := generic.Allowed("/private/feed.xml"); !ok {
+		t.Fatalf("expected longer Allow to win")
+	}
+}
+
+func TestPolicyTransport(t *testing.T) {
+	var robotsStatus = http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(robotsStatus)
+			fmt.Fprint(w, sampleRobots)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	newClient := func() *http.Client {
+		robots := NewRobotsCache(srv.Client(), "news-go/1.0", time.Hour)
+		return &http.Client{Transport: NewPolicyTransport(srv.Client().Transport, NewCrawlPolicy(robots))}
+	}
+	client := newClient()
+	get := func(path string) error {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL+path, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get("/search/rss"); err != nil {
+		t.Fatalf("expected allowed url to pass: %v", err)
+	}
+	err := get("/search?q=x")
+	var pe *PolicyError
+	if !errors.Is(err, ErrDisallowed) || !errors.As(err, &pe) || !strings.Contains(pe.Reason, "/search") {
+		t.Fatalf("expected PolicyError naming the rule, got %v", err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := get("/news"); err != nil {
+			t.Fatalf("get: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("expected crawl-delay pacing, 3 requests took %s", elapsed)
+	}
+
+	robotsStatus = http.StatusNotFound
+	client = newClient()
+	if err := get("/search?q=x"); err != nil {
+		t.Fatalf("expected missing robots.txt to allow everything: %v", err)
+	}
+
+	robotsStatus = http.StatusInternalServerError
+	client = newClient()
+	if err := get("/news"); !errors.Is(err, ErrDisallowed) {
+		t.Fatalf("expected unreachable robots.txt to disallow, got %v", err)
+	}
+}
+
+func TestCrawlDelayTimeoutKeepsHostUsable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nCrawl-delay: 0.3\n")
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	robots := NewRobotsCache(srv.Client(), "news-go/1.0", time.Hour)
+	client := &http.Client{Transport: NewPolicyTransport(srv.Client().Transport, NewCrawlPolicy(robots))}
+	get := func(timeout time.Duration) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/news", nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(time.Second); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := get(100 * time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("request %d: expected deadline error, got %v", i, err)
+		}
+	}
+	time.Sleep(300 * time.Millisecond)
+	if err := get(100 * time.Millisecond); err != nil {
+		t.Fatalf("expected the host to be usable once the delay passed: %v", err)
+	}
+
+	// A caller cancelled while waiting returns its slot.
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/news", nil)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := client.Do(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if err := get(100 * time.Millisecond); err != nil {
+		t.Fatalf("expected the cancelled slot to be returned: %v", err)
+	}
+}
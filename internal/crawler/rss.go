@@ -1,74 +1,497 @@
 package crawler
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"news-go/internal/news"
 )
 
+func init() {
+	// Atom sources share the RSS fetcher, which tells the formats apart;
+	// the kind only records what the source is.
+	newFeed := func(c *http.Client, o FetcherOptions) Fetcher { return NewRSSFetcherWithLimits(c, o.FeedLimits) }
+	Register(Kind{Name: news.KindRSS, New: newFeed})
+	Register(Kind{Name: KindAtom, New: newFeed})
+}
+
+// RSSFetcher reads RSS 2.0 and 1.0, Atom and JSON Feed documents.
 type RSSFetcher struct {
 	client *http.Client
+	limits FeedLimits
 }
 
 func NewRSSFetcher(timeout time.Duration) *RSSFetcher {
-	return &RSSFetcher{client: &http.Client{Timeout: timeout}}
+	return &RSSFetcher{client: &http.Client{Timeout: timeout}, limits: DefaultFeedLimits()}
+}
+
+// NewRSSFetcherWithClient lets callers supply the transport, e.g. one
+// wrapped in a PolicyTransport.
+func NewRSSFetcherWithClient(client *http.Client) *RSSFetcher {
+	return &RSSFetcher{client: client, limits: DefaultFeedLimits()}
+}
+
+// NewRSSFetcherWithLimits is NewRSSFetcherWithClient with limits other
+// than DefaultFeedLimits.
+func NewRSSFetcherWithLimits(client *http.Client, limits FeedLimits) *RSSFetcher {
+	return &RSSFetcher{client: client, limits: limits.withDefaults()}
+}
+
+type atomLink struct {
+	Rel    string `xml:"rel,attr"`
+	Href   string `xml:"href,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// Feed is a parsed feed document. Hub and Self come from <atom:link
+// rel="hub"> and rel="self", or from the response's Link header, and are
+// what a WebSub subscription needs. Refresh is the publisher's polling
+// hint from <ttl> or <sy:updatePeriod>, zero when the feed gives none.
+type Feed struct {
+	Title   string
+	Items   []news.Article
+	Hub     string
+	Self    string
+	Refresh time.Duration
+	// Commit, when set, must be called once Items are stored. Directory
+	// file sources use it to record and move the files they read.
+	Commit func(ctx context.Context) error
+	// Truncated counts items skipped for exceeding FeedLimits.MaxItems.
+	Truncated int
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Author      string   `xml:"author"`
+	Creators    []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Language    string   `xml:"http://purl.org/dc/elements/1.1/ language"`
+	Categories  []string `xml:"category"`
+	GUID        string   `xml:"guid"`
+	Enclosures  []struct {
+		URL    string `xml:"url,attr"`
+		Type   string `xml:"type,attr"`
+		Length string `xml:"length,attr"`
+	} `xml:"enclosure"`
+	Media      []rssMedia `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails []rssMedia `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	Groups     []struct {
+		Media      []rssMedia `xml:"http://search.yahoo.com/mrss/ content"`
+		Thumbnails []rssMedia `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+	ItunesDuration string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ItunesImage    struct {
+		Href string `xml:"href,attr"`
+	} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
 }
 
-type rssDocument struct {
-	Channel struct {
-		Items []struct {
-			Title       string `xml:"title"`
-			Link        string `xml:"link"`
-			Description string `xml:"description"`
-			PubDate     string `xml:"pubDate"`
-		} `xml:"item"`
-	} `xml:"channel"`
+type rssMedia struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Medium   string `xml:"medium,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Duration string `xml:"duration,attr"`
 }
 
-func (f *RSSFetcher) Fetch(ctx context.Context, feedURL, userAgent string) ([]news.Article, error) {
+func (m rssMedia) isImage() bool {
+	return m.URL != "" && (m.Medium == "image" || strings.HasPrefix(m.Type, "image/"))
+}
+
+func (f *RSSFetcher) Fetch(ctx context.Context, src news.Source, userAgent string) (Feed, error) {
+	return f.FetchFeed(ctx, src.FeedURL, userAgent)
+}
+
+func (f *RSSFetcher) FetchFeed(ctx context.Context, feedURL, userAgent string) (Feed, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
 	if err != nil {
-		return nil, err
+		return Feed{}, err
 	}
 	if userAgent != "" {
 		req.Header.Set("User-Agent", userAgent)
 	}
 	resp, err := f.client.Do(req)
 	if err != nil {
-		return nil, err
+		return Feed{}, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return nil, fmt.Errorf("rss status: %d", resp.StatusCode)
+		return Feed{}, newStatusError(resp, time.Now())
+	}
+	body := bufio.NewReader(resp.Body)
+	head, _ := body.Peek(512)
+	if err := checkContentType(resp.Header.Get("Content-Type"), head); err != nil {
+		return Feed{}, err
 	}
-	body, err := io.ReadAll(resp.Body)
+	feed, err := f.limits.Parse(body, time.Now().UTC())
 	if err != nil {
-		return nil, err
-	}
-	var doc rssDocument
-	if err := xml.Unmarshal(body, &doc); err != nil {
-		return nil, err
-	}
-	out := make([]news.Article, 0, len(doc.Channel.Items))
-	for _, it := range doc.Channel.Items {
-		published := time.Now().UTC()
-		if t, err := time.Parse(time.RFC1123Z, it.PubDate); err == nil {
-			published = t.UTC()
-		} else if t, err := time.Parse(time.RFC1123, it.PubDate); err == nil {
-			published = t.UTC()
-		}
-		out = append(out, news.Article{
-			Title:       it.Title,
-			URL:         it.Link,
-			Source:      "rss",
-			Content:     it.Description,
-			PublishedAt: published,
-		})
-	}
-	return out, nil
+		return Feed{}, err
+	}
+	hub, self := linkHeader(resp.Header.Values("Link"))
+	if feed.Hub == "" {
+		feed.Hub = hub
+	}
+	if feed.Self == "" {
+		feed.Self = self
+	}
+	return feed, nil
+}
+
+// ParseFeed maps the items of an RSS, Atom or JSON Feed document to
+// articles within DefaultFeedLimits. now is recorded as each article's
+// FetchedAt; items without a date are left undated for storage to date by
+// their first fetch.
+func ParseFeed(body []byte, now time.Time) (Feed, error) {
+	return DefaultFeedLimits().Parse(bytes.NewReader(body), now)
+}
+
+const atomNS = "http://www.w3.org/2005/Atom"
+
+// decodeXMLFeed walks an RSS 2.0, RSS 1.0 or Atom document token by token
+// and decodes one item at a time, so only the item being read is held
+// beyond the articles kept. Documents declaring entities are rejected.
+func decodeXMLFeed(r io.Reader, l FeedLimits, now time.Time) (Feed, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charsetReader
+	var feed Feed
+	var stack []string
+	var atom bool
+	var lang, ttl, period, frequency string
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Feed{}, err
+		}
+		switch t := tok.(type) {
+		case xml.Directive:
+			if bytes.Contains(t, []byte("<!ENTITY")) {
+				return Feed{}, fmt.Errorf("%w: document declares XML entities", ErrFeedRejected)
+			}
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.StartElement:
+			parent, name := "", t.Name.Local
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			} else if name == "feed" {
+				atom = true
+				lang = attrValue(t, xml.Name{Space: "http://www.w3.org/XML/1998/namespace", Local: "lang"})
+			}
+			var err error
+			switch {
+			case atom && parent == "feed" && name == "entry":
+				if len(feed.Items) >= l.MaxItems {
+					feed.Truncated++
+					err = dec.Skip()
+					break
+				}
+				var e atomEntry
+				if err = dec.DecodeElement(&e, &t); err == nil {
+					feed.keep(e.article(now), l)
+				}
+			case !atom && name == "item" && (parent == "channel" || parent == "RDF"):
+				if len(feed.Items) >= l.MaxItems {
+					feed.Truncated++
+					err = dec.Skip()
+					break
+				}
+				var it rssItem
+				if err = dec.DecodeElement(&it, &t); err == nil {
+					feed.keep(it.article(now), l)
+				}
+			case name == "link" && (atom && parent == "feed" || !atom && parent == "channel" && t.Name.Space == atomNS):
+				var link atomLink
+				if err = dec.DecodeElement(&link, &t); err == nil {
+					feed.addLink(link)
+				}
+			case atom && parent == "feed" && name == "title":
+				var title atomText
+				if err = dec.DecodeElement(&title, &t); err == nil {
+					feed.Title = truncateBytes(title.plain(), maxShortFieldBytes)
+				}
+			case !atom && parent == "channel" && (name == "title" || name == "language" || name == "ttl" || name == "updatePeriod" || name == "updateFrequency"):
+				var v string
+				if err = dec.DecodeElement(&v, &t); err != nil {
+					break
+				}
+				v = truncateBytes(v, maxShortFieldBytes)
+				switch name {
+				case "title":
+					feed.Title = strings.TrimSpace(v)
+				case "language":
+					lang = v
+				case "ttl":
+					ttl = v
+				case "updatePeriod":
+					period = v
+				case "updateFrequency":
+					frequency = v
+				}
+			default:
+				stack = append(stack, name)
+			}
+			if err != nil {
+				return Feed{}, err
+			}
+		}
+	}
+	if !atom {
+		feed.Refresh = refreshHint(ttl, period, frequency)
+	}
+	// The channel's language may come after its items.
+	for i := range feed.Items {
+		if feed.Items[i].Language == "" {
+			feed.Items[i].Language = news.NormalizeLanguage(lang)
+		}
+	}
+	if feed.Items == nil {
+		feed.Items = []news.Article{}
+	}
+	return feed, nil
+}
+
+// addLink keeps the first rel="hub" and rel="self" links.
+func (f *Feed) addLink(l atomLink) {
+	switch {
+	case hasToken(l.Rel, "hub") && f.Hub == "":
+		f.Hub = strings.TrimSpace(l.Href)
+	case hasToken(l.Rel, "self") && f.Self == "":
+		f.Self = strings.TrimSpace(l.Href)
+	}
+}
+
+func attrValue(el xml.StartElement, name xml.Name) string {
+	for _, a := range el.Attr {
+		if a.Name == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// article maps an RSS item; the channel language is filled in by the
+// caller. An undated item keeps a zero PublishedAt, so the repository
+// dates it when first seen instead of at every poll.
+func (it rssItem) article(now time.Time) news.Article {
+	var published time.Time
+	if t, err := time.Parse(time.RFC1123Z, it.PubDate); err == nil {
+		published = t.UTC()
+	} else if t, err := time.Parse(time.RFC1123, it.PubDate); err == nil {
+		published = t.UTC()
+	} else if t, ok := parseW3CDate(it.Date); ok {
+		published = t
+	}
+	return news.Article{
+		Title:       it.Title,
+		URL:         strings.TrimSpace(it.Link),
+		Source:      "rss",
+		Content:     it.Description,
+		PublishedAt: published,
+		Authors:     rssAuthors(it),
+		Categories:  uniqueTrimmed(it.Categories),
+		ImageURL:    leadImage(it),
+		Media:       rssAttachments(it),
+		Language:    news.NormalizeLanguage(it.Language),
+		GUID:        strings.TrimSpace(it.GUID),
+		FetchedAt:   now,
+	}
+}
+
+// refreshHint reads <ttl> (minutes) or, failing that, <sy:updatePeriod>
+// divided by <sy:updateFrequency>.
+func refreshHint(ttl, period, frequency string) time.Duration {
+	if n, err := strconv.Atoi(strings.TrimSpace(ttl)); err == nil && n > 0 {
+		return time.Duration(n) * time.Minute
+	}
+	var d time.Duration
+	switch strings.ToLower(strings.TrimSpace(period)) {
+	case "hourly":
+		d = time.Hour
+	case "daily":
+		d = 24 * time.Hour
+	case "weekly":
+		d = 7 * 24 * time.Hour
+	case "monthly":
+		d = 30 * 24 * time.Hour
+	case "yearly":
+		d = 365 * 24 * time.Hour
+	default:
+		return 0
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(frequency)); err == nil && n > 1 {
+		d /= time.Duration(n)
+	}
+	return d
+}
+
+// linkHeader finds rel="hub" and rel="self" in HTTP Link headers, e.g.
+// `<https://hub.example/>; rel="hub"`.
+func linkHeader(values []string) (hub, self string) {
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			target, params, ok := strings.Cut(part, ";")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = target[1 : len(target)-1]
+			for _, p := range strings.Split(params, ";") {
+				k, val, _ := strings.Cut(strings.TrimSpace(p), "=")
+				if !strings.EqualFold(k, "rel") {
+					continue
+				}
+				rel := strings.Trim(val, `"`)
+				if hasToken(rel, "hub") && hub == "" {
+					hub = target
+				}
+				if hasToken(rel, "self") && self == "" {
+					self = target
+				}
+			}
+		}
+	}
+	return hub, self
+}
+
+// rssAuthors prefers dc:creator; the RSS <author> element is an e-mail
+// address, optionally followed by the name in parentheses.
+func rssAuthors(it rssItem) []string {
+	names := append([]string(nil), it.Creators...)
+	if len(uniqueTrimmed(names)) == 0 && it.Author != "" {
+		a := strings.TrimSpace(it.Author)
+		if open := strings.Index(a, "("); open >= 0 && strings.HasSuffix(a, ")") {
+			a = a[open+1 : len(a)-1]
+		}
+		names = []string{a}
+	}
+	return uniqueTrimmed(names)
+}
+
+// leadImage picks the first image among media:content, media:thumbnail
+// (both also inside media:group) and image enclosures, in that order.
+func leadImage(it rssItem) string {
+	content, thumbs := it.Media, it.Thumbnails
+	for _, g := range it.Groups {
+		content = append(content, g.Media...)
+		thumbs = append(thumbs, g.Thumbnails...)
+	}
+	for _, m := range content {
+		if m.isImage() {
+			return strings.TrimSpace(m.URL)
+		}
+	}
+	for _, m := range thumbs {
+		if m.URL != "" {
+			return strings.TrimSpace(m.URL)
+		}
+	}
+	for _, e := range it.Enclosures {
+		if e.URL != "" && strings.HasPrefix(e.Type, "image/") {
+			return strings.TrimSpace(e.URL)
+		}
+	}
+	return strings.TrimSpace(it.ItunesImage.Href)
+}
+
+// rssAttachments collects audio and video from enclosures and media:content,
+// merging entries for the same URL. itunes:duration applies to the first
+// attachment when none states its own.
+func rssAttachments(it rssItem) []news.Media {
+	var out []news.Media
+	for _, e := range it.Enclosures {
+		out = addMedia(out, e.URL, e.Type, "", e.Length, "")
+	}
+	content := it.Media
+	for _, g := range it.Groups {
+		content = append(content, g.Media...)
+	}
+	for _, m := range content {
+		out = addMedia(out, m.URL, m.Type, m.Medium, m.FileSize, m.Duration)
+	}
+	if len(out) > 0 && out[0].DurationSec == 0 {
+		out[0].DurationSec = parseDuration(it.ItunesDuration)
+	}
+	return out
+}
+
+// addMedia appends an audio or video attachment, or fills in the missing
+// fields of an earlier one with the same URL, which may come untyped.
+func addMedia(out []news.Media, rawURL, mimeType, medium, length, duration string) []news.Media {
+	m := news.Media{
+		URL:         strings.TrimSpace(rawURL),
+		Type:        strings.TrimSpace(mimeType),
+		Medium:      news.MediaMedium(medium, mimeType),
+		DurationSec: parseDuration(duration),
+	}
+	if n, err := strconv.ParseInt(strings.TrimSpace(length), 10, 64); err == nil && n > 0 {
+		m.Length = n
+	}
+	for i := range out {
+		if out[i].URL != m.URL {
+			continue
+		}
+		if out[i].Type == "" {
+			out[i].Type = m.Type
+		}
+		if out[i].Length == 0 {
+			out[i].Length = m.Length
+		}
+		if out[i].DurationSec == 0 {
+			out[i].DurationSec = m.DurationSec
+		}
+		return out
+	}
+	if m.URL == "" || m.Medium == "" {
+		return out
+	}
+	return append(out, m)
+}
+
+// parseDuration reads seconds ("1830", "1830.5") or the [[HH:]MM:]SS form
+// iTunes uses, returning 0 when it cannot.
+func parseDuration(s string) int {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	total := 0.0
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		total = total*60 + n
+	}
+	return int(total)
+}
+
+func uniqueTrimmed(values []string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, v := range values {
+		v = strings.Join(strings.Fields(v), " ")
+		if v == "" || seen[strings.ToLower(v)] {
+			continue
+		}
+		seen[strings.ToLower(v)] = true
+		out = append(out, v)
+	}
+	return out
 }
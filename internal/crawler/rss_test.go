@@ -0,0 +1,194 @@
+package crawler
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"news-go/internal/news"
+)
+
+const richFeed = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:media="http://search.yahoo.com/mrss/">
+<channel><title>T</title><language>en-US</language>
+<item>
+  <title>One</title><link> https://ex.test/1 </link><description>d</description>
+  <pubDate>Mon, 19 Oct 2026 01:00:00 +0000</pubDate>
+  <guid isPermaLink="false">tag:ex.test,2026:1</guid>
+  <dc:creator>Ada Lovelace</dc:creator><dc:creator>Alan Turing</dc:creator>
+  <author>desk@ex.test (News Desk)</author>
+  <category>Science</category><category>science</category><category> Space </category>
+  <enclosure url="https://ex.test/a.mp3" type="audio/mpeg" length="1"/>
+  <media:group><media:content url="https://ex.test/v.mp4" medium="video"/><media:thumbnail url="https://ex.test/t.jpg"/></media:group>
+</item>
+<item>
+  <title>Two</title><link>https://ex.test/2</link>
+  <author>desk@ex.test (News Desk)</author>
+  <dc:language>fr</dc:language>
+  <enclosure url="https://ex.test/b.png" type="image/png" length="1"/>
+</item>
+</channel></rss>`
+
+func TestParseRSSMetadata(t *testing.T) {
+	now := time.Date(2026, 10, 19, 5, 0, 0, 0, time.UTC)
+	feed, err := ParseFeed([]byte(richFeed), now)
+	if err != nil {
+		t.Fatalf("ParseFeed: %v", err)
+	}
+	items := feed.Items
+	if len(items) != 2 {
+		t.Fatalf("got %d items", len(items))
+	}
+	one, two := items[0], items[1]
+	if one.URL != "https://ex.test/1" || one.GUID != "tag:ex.test,2026:1" || one.Language != "en-us" {
+		t.Errorf("unexpected item one: %+v", one)
+	}
+	if !reflect.DeepEqual(one.Authors, []string{"Ada Lovelace", "Alan Turing"}) {
+		t.Errorf("authors = %v", one.Authors)
+	}
+	if !reflect.DeepEqual(one.Categories, []string{"Science", "Space"}) {
+		t.Errorf("categories = %v", one.Categories)
+	}
+	if one.ImageURL != "https://ex.test/t.jpg" {
+		t.Errorf("image = %q", one.ImageURL)
+	}
+	if !reflect.DeepEqual(two.Authors, []string{"News Desk"}) || two.Language != "fr" || two.ImageURL != "https://ex.test/b.png" {
+		t.Errorf("unexpected item two: %+v", two)
+	}
+	if !two.PublishedAt.IsZero() || !two.FetchedAt.Equal(now) {
+		t.Errorf("times = %v / %v", two.PublishedAt, two.FetchedAt)
+	}
+}
+
+func TestParseFeedHubLinks(t *testing.T) {
+	body := `<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>T</title>
+<atom:link rel="hub" href="https://hub.test/"/>
+<atom:link rel="self" type="application/rss+xml" href="https://ex.test/feed.xml"/>
+<item><title>One</title><link>https://ex.test/1</link></item>
+</channel></rss>`
+	feed, err := ParseFeed([]byte(body), time.Now())
+	if err != nil {
+		t.Fatalf("ParseFeed: %v", err)
+	}
+	if feed.Hub != "https://hub.test/" || feed.Self != "https://ex.test/feed.xml" || len(feed.Items) != 1 {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+	hub, self := linkHeader([]string{`<https://ex.test/feed>; rel="self", <https://pubsubhubbub.test/>; rel="hub"`})
+	if hub != "https://pubsubhubbub.test/" || self != "https://ex.test/feed" {
+		t.Fatalf("linkHeader = %q, %q", hub, self)
+	}
+}
+
+func TestParseRSSMedia(t *testing.T) {
+	body := `<?xml version="1.0"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:media="http://search.yahoo.com/mrss/">
+<channel><title>Pod</title>
+<item>
+  <title>Episode 1</title><link>https://ex.test/ep1</link>
+  <enclosure url="https://cdn.ex.test/ep1.mp3" type="audio/mpeg" length="24986239"/>
+  <media:content url="https://cdn.ex.test/ep1.mp3" fileSize="1" duration="1561"/>
+  <itunes:duration>00:30:00</itunes:duration>
+  <itunes:image href="https://cdn.ex.test/ep1.jpg"/>
+</item>
+<item>
+  <title>Clip</title><link>https://ex.test/clip</link>
+  <media:group>
+    <media:content url="https://cdn.ex.test/clip.mp4" type="video/mp4" fileSize="1024"/>
+    <media:content url="https://cdn.ex.test/clip.jpg" medium="image"/>
+  </media:group>
+  <itunes:duration>2:05</itunes:duration>
+</item>
+</channel></rss>`
+	feed, err := ParseFeed([]byte(body), time.Now())
+	if err != nil {
+		t.Fatalf("ParseFeed: %v", err)
+	}
+	items := feed.Items
+	want := []news.Media{{URL: "https://cdn.ex.test/ep1.mp3", Type: "audio/mpeg", Medium: "audio", Length: 24986239, DurationSec: 1561}}
+	if !reflect.DeepEqual(items[0].Media, want) || items[0].ImageURL != "https://cdn.ex.test/ep1.jpg" {
+		t.Errorf("episode: media %+v image %q", items[0].Media, items[0].ImageURL)
+	}
+	want = []news.Media{{URL: "https://cdn.ex.test/clip.mp4", Type: "video/mp4", Medium: "video", Length: 1024, DurationSec: 125}}
+	if !reflect.DeepEqual(items[1].Media, want) || items[1].ImageURL != "https://cdn.ex.test/clip.jpg" {
+		t.Errorf("clip: media %+v image %q", items[1].Media, items[1].ImageURL)
+	}
+	// richFeed's first item has an mp3 enclosure and a grouped video.
+	feed, _ = ParseFeed([]byte(richFeed), time.Now())
+	items = feed.Items
+	if len(items[0].Media) != 2 || items[0].Media[0].Medium != "audio" || items[0].Media[1].Medium != "video" || len(items[1].Media) != 0 {
+		t.Errorf("richFeed media = %+v / %+v", items[0].Media, items[1].Media)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for in, want := range map[string]int{"": 0, "90": 90, "1830.6": 1830, "2:05": 125, "01:02:03": 3723, "abc": 0, "1:-2": 0} {
+		if got := parseDuration(in); got != want {
+			t.Errorf("parseDuration(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestParseFeedLegacyCharsets(t *testing.T) {
+	cases := map[string]struct{ body, title string }{
+		"gb2312": {
+			body: "<?xml version=\"1.0\" encoding=\"GB2312\"?><rss version=\"2.0\"><channel><title>\xd0\xc2\xbb\xaa\xc9\xe7</title>" +
+				"<item><title>\xd6\xd0\xb9\xfa\xbe\xad\xbc\xc3\xce\xc8\xb2\xbd\xd4\xf6\xb3\xa4 AI</title><link>https://ex.test/zh</link></item></channel></rss>",
+			title: "中国经济稳步增长 AI",
+		},
+		"windows-1252": {
+			body: "<?xml version=\"1.0\" encoding=\"windows-1252\"?><rss version=\"2.0\"><channel><title>x</title>" +
+				"<item><title>\x93Quoted\x94 \x96 caf\xe9</title><link>https://ex.test/en</link></item></channel></rss>",
+			title: "“Quoted” – café",
+		},
+	}
+	for name, c := range cases {
+		feed, err := ParseFeed([]byte(c.body), time.Now())
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(feed.Items) != 1 || feed.Items[0].Title != c.title {
+			t.Errorf("%s: items %+v", name, feed.Items)
+		}
+	}
+	feed, _ := ParseFeed([]byte(cases["gb2312"].body), time.Now())
+	if feed.Title != "新华社" {
+		t.Errorf("channel title %q", feed.Title)
+	}
+	if _, err := ParseFeed([]byte(`<?xml version="1.0" encoding="EBCDIC"?><rss/>`), time.Now()); err == nil {
+		t.Error("expected an unsupported charset to fail")
+	}
+}
+
+// TestGBKTable spot-checks gbk.bin, which `go generate` builds from the
+// WHATWG index, across GB2312, the GBK extensions and the gaps.
+func TestGBKTable(t *testing.T) {
+	if len(gbkTable) != 126*191*2 {
+		t.Fatalf("table has %d bytes", len(gbkTable))
+	}
+	for in, want := range map[string]string{
+		"\xb0\xa1":         "啊", // first GB2312 hanzi
+		"\xd6\xd0\xce\xc4": "中文",
+		"\xa1\xa1":         "　",
+		"\xa3\xa1":         "！",
+		"\xa2\xe3":         "€",
+		"\xa8\xbf":         "ǹ",
+		"\x81\x40":         "丂", // first GBK extension
+		"\xfe\x50":         "⺁",
+		"\x80":             "€",
+		"\x81\x7f":         "�",
+		"\x81\x30\x81\x30": "�",
+	} {
+		r, err := charsetReader("gbk", strings.NewReader(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil || string(got) != want {
+			t.Errorf("% x = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
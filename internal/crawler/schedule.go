@@ -0,0 +1,62 @@
+package crawler
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// cadenceWindow limits the publish history used to learn a cadence.
+	cadenceWindow = 30 * 24 * time.Hour
+	// minCadenceGaps is how many gaps between articles are needed before
+	// the history is trusted over the default interval.
+	minCadenceGaps = 3
+)
+
+// Schedule picks a per-source polling interval. Sources without enough
+// history are polled every Default; learned intervals stay within
+// [Min, Max].
+type Schedule struct {
+	Default time.Duration
+	Min     time.Duration
+	Max     time.Duration
+}
+
+// Interval polls about twice per typical gap between the given publish
+// times, backs off while a source has been quiet for longer than usual, and
+// never polls more often than the feed's refresh hint asks.
+func (s Schedule) Interval(published []time.Time, hint time.Duration, now time.Time) time.Duration {
+	times := make([]time.Time, 0, len(published))
+	for _, t := range published {
+		if !t.IsZero() && !t.After(now) && now.Sub(t) <= cadenceWindow {
+			times = append(times, t)
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].After(times[j]) })
+	var gaps []time.Duration
+	for i := 1; i < len(times); i++ {
+		// Items stamped with the same fetch time say nothing about cadence.
+		if g := times[i-1].Sub(times[i]); g > 0 {
+			gaps = append(gaps, g)
+		}
+	}
+	d := s.Default
+	if len(gaps) >= minCadenceGaps {
+		sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+		typical := gaps[len(gaps)/2]
+		if quiet := now.Sub(times[0]); quiet > typical {
+			typical = quiet
+		}
+		d = typical / 2
+	}
+	if hint > d {
+		d = hint
+	}
+	if s.Min > 0 && d < s.Min {
+		d = s.Min
+	}
+	if s.Max > 0 && d > s.Max {
+		d = s.Max
+	}
+	return d
+}
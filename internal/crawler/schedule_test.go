@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleInterval(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	s := Schedule{Default: 5 * time.Minute, Min: 2 * time.Minute, Max: 6 * time.Hour}
+	every := func(gap time.Duration, n int) []time.Time {
+		out := make([]time.Time, n)
+		for i := range out {
+			out[i] = now.Add(-time.Duration(i) * gap)
+		}
+		return out
+	}
+	cases := []struct {
+		name      string
+		published []time.Time
+		hint      time.Duration
+		want      time.Duration
+	}{
+		{"no history", nil, 0, 5 * time.Minute},
+		{"too little history", every(time.Hour, 3), 0, 5 * time.Minute},
+		{"busy wire", every(3*time.Minute, 20), 0, 2 * time.Minute},
+		{"hourly blog", every(time.Hour, 10), 0, 30 * time.Minute},
+		{"quiet blog", every(24*time.Hour, 10), 0, 6 * time.Hour},
+		{"gone quiet", every(10*time.Minute, 10)[5:], 0, 25 * time.Minute},
+		{"ttl hint", every(10*time.Minute, 10), time.Hour, time.Hour},
+		{"same fetch time", []time.Time{now, now, now, now, now}, 0, 5 * time.Minute},
+	}
+	for _, c := range cases {
+		if got := s.Interval(c.published, c.hint, now); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRefreshHint(t *testing.T) {
+	cases := []struct {
+		ttl, period, freq string
+		want              time.Duration
+	}{
+		{"", "", "", 0},
+		{"90", "daily", "", 90 * time.Minute},
+		{"", "hourly", "", time.Hour},
+		{"", "daily", "4", 6 * time.Hour},
+		{"x", "sometimes", "2", 0},
+	}
+	for _, c := range cases {
+		if got := refreshHint(c.ttl, c.period, c.freq); got != c.want {
+			t.Errorf("refreshHint(%q, %q, %q) = %s, want %s", c.ttl, c.period, c.freq, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"news-go/internal/htmldoc"
+	"news-go/internal/news"
+)
+
+// maxListingBytes bounds how much of a listing page is read.
+const maxListingBytes = 4 << 20
+
+func init() {
+	Register(Kind{
+		Name:     KindHTML,
+		New:      func(c *http.Client, _ FetcherOptions) Fetcher { return NewHTMLScraper(c) },
+		Validate: validateScrape,
+	})
+}
+
+// ScrapeConfig is the options object of an html source. Item matches one
+// container per article; the others are evaluated inside it. A field
+// selector may end in "@attr" to read an attribute instead of the text;
+// Link defaults to the href of the first <a>, Date to a datetime attribute
+// when present. DateLayout is an optional Go time layout for Date.
+type ScrapeConfig struct {
+	Item       string `json:"item"`
+	Title      string `json:"title"`
+	Link       string `json:"link,omitempty"`
+	Date       string `json:"date,omitempty"`
+	Summary    string `json:"summary,omitempty"`
+	DateLayout string `json:"date_layout,omitempty"`
+}
+
+func validateScrape(src news.Source, _ LocalAccess) error {
+	if err := requireHTTP(src); err != nil {
+		return err
+	}
+	var c ScrapeConfig
+	if err := src.DecodeOptions(&c); err != nil {
+		return err
+	}
+	if strings.TrimSpace(c.Item) == "" || strings.TrimSpace(c.Title) == "" {
+		return fmt.Errorf("%w: html sources need item and title selectors in options", news.ErrInvalidSource)
+	}
+	for _, sel := range []struct{ name, value string }{
+		{"item", c.Item}, {"title", c.Title}, {"link", c.Link}, {"date", c.Date}, {"summary", c.Summary},
+	} {
+		if sel.value == "" {
+			continue
+		}
+		if _, err := compileField(sel.value); err != nil {
+			return fmt.Errorf("%w: options.%s: %v", news.ErrInvalidSource, sel.name, err)
+		}
+	}
+	return nil
+}
+
+// HTMLScraper reads articles from listing pages of sources without a feed,
+// using the CSS selectors in the source's ScrapeConfig.
+type HTMLScraper struct {
+	client *http.Client
+}
+
+func NewHTMLScraper(client *http.Client) *HTMLScraper {
+	return &HTMLScraper{client: client}
+}
+
+func (f *HTMLScraper) Fetch(ctx context.Context, src news.Source, userAgent string) (Feed, error) {
+	var cfg ScrapeConfig
+	if err := src.DecodeOptions(&cfg); err != nil {
+		return Feed{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.FeedURL, nil)
+	if err != nil {
+		return Feed{}, err
+	}
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Feed{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Feed{}, newStatusError(resp, time.Now())
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxListingBytes))
+	if err != nil {
+		return Feed{}, err
+	}
+	if !utf8.Valid(body) {
+		return Feed{}, errors.New("listing page is not valid UTF-8")
+	}
+	items, err := scrapeListing(string(body), resp.Request.URL, cfg, time.Now().UTC())
+	return Feed{Items: items}, err
+}
+
+type fieldSelector struct {
+	sel  *htmldoc.Selector
+	attr string
+}
+
+// compileField compiles a field selector and splits off its "@attr"
+// suffix. An attribute alone, such as "@href", applies to the item element
+// itself.
+func compileField(field string) (fieldSelector, error) {
+	sel, attr := strings.TrimSpace(field), ""
+	if at := strings.LastIndexByte(sel, '@'); at >= 0 && isAttrName(sel[at+1:]) {
+		sel, attr = strings.TrimSpace(sel[:at]), strings.ToLower(sel[at+1:])
+	}
+	if sel == "" {
+		return fieldSelector{attr: attr}, nil
+	}
+	compiled, err := htmldoc.Compile(sel)
+	return fieldSelector{sel: compiled, attr: attr}, err
+}
+
+func isAttrName(s string) bool {
+	for _, ch := range s {
+		if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '-' || ch == '_' || ch == ':') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// node returns the element a field reads from: the item itself when the
+// field has no selector part.
+func (f fieldSelector) node(item *htmldoc.Node) *htmldoc.Node {
+	if f.sel == nil {
+		return item
+	}
+	if f.sel.Match(item) {
+		return item
+	}
+	return item.SelectFirst(f.sel)
+}
+
+var anchorSelector, _ = htmldoc.Compile("a[href]")
+var timeSelector, _ = htmldoc.Compile("time[datetime]")
+
+// scrapeListing maps each item container to an article. Items without a
+// title or an http(s) link are skipped; an undated item has a zero
+// PublishedAt so the repository keeps the date it was first seen.
+func scrapeListing(page string, pageURL *url.URL, cfg ScrapeConfig, now time.Time) ([]news.Article, error) {
+	var fields [5]fieldSelector
+	for i, raw := range []string{cfg.Item, cfg.Title, cfg.Link, cfg.Date, cfg.Summary} {
+		if raw == "" {
+			continue
+		}
+		f, err := compileField(raw)
+		if err != nil {
+			return nil, fmt.Errorf("scrape selector %q: %w", raw, err)
+		}
+		fields[i] = f
+	}
+	item, title, link, date, summary := fields[0], fields[1], fields[2], fields[3], fields[4]
+	if item.sel == nil {
+		return nil, errors.New("scrape config has no item selector")
+	}
+	doc := htmldoc.ParseString(page)
+	base := pageURL
+	if b := doc.Find("base"); b != nil {
+		if u, err := pageURL.Parse(strings.TrimSpace(b.AttrOr("href"))); err == nil {
+			base = u
+		}
+	}
+	out := []news.Article{}
+	seen := map[string]bool{}
+	for _, it := range doc.Select(item.sel) {
+		a := news.Article{Source: "html", FetchedAt: now}
+		if n := title.node(it); n != nil {
+			a.Title = fieldText(n, title.attr)
+		}
+		a.URL = resolveLink(base, itemLink(it, link, cfg.Link != ""))
+		if a.Title == "" || a.URL == "" || seen[a.URL] {
+			continue
+		}
+		seen[a.URL] = true
+		if cfg.Summary != "" {
+			if n := summary.node(it); n != nil {
+				a.Content = html.EscapeString(n.InnerText())
+			}
+		}
+		a.PublishedAt = itemDate(it, date, cfg.Date != "", cfg.DateLayout)
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func fieldText(n *htmldoc.Node, attr string) string {
+	if attr != "" {
+		return strings.Join(strings.Fields(n.AttrOr(attr)), " ")
+	}
+	return strings.Join(strings.Fields(n.InnerText()), " ")
+}
+
+// itemLink reads the link field, falling back to the href of the element
+// or of its first anchor.
+func itemLink(it *htmldoc.Node, f fieldSelector, configured bool) string {
+	n := it
+	if configured {
+		if n = f.node(it); n == nil {
+			return ""
+		}
+		if f.attr != "" {
+			return n.AttrOr(f.attr)
+		}
+	}
+	if href, ok := n.Attr("href"); ok {
+		return href
+	}
+	if a := n.SelectFirst(anchorSelector); a != nil {
+		return a.AttrOr("href")
+	}
+	return ""
+}
+
+func resolveLink(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return ""
+	}
+	u, err := base.Parse(href)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	u.Fragment = ""
+	return u.String()
+}
+
+// itemDate reads the date field, preferring a datetime attribute, and
+// without a date selector looks for a <time datetime> in the item.
+func itemDate(it *htmldoc.Node, f fieldSelector, configured bool, layout string) time.Time {
+	var n *htmldoc.Node
+	if configured {
+		n = f.node(it)
+	} else {
+		n = it.SelectFirst(timeSelector)
+	}
+	if n == nil {
+		return time.Time{}
+	}
+	var raw string
+	switch {
+	case f.attr != "":
+		raw = n.AttrOr(f.attr)
+	case n.AttrOr("datetime") != "":
+		raw = n.AttrOr("datetime")
+	default:
+		raw = n.InnerText()
+	}
+	t, _ := parseLooseDate(raw, layout)
+	return t
+}
+
+var looseDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"2006/01/02",
+	"2006.01.02",
+	"2006年1月2日",
+	"2006年1月2日 15:04",
+}
+
+// parseLooseDate tries layout first, then common formats found on news
+// listings. Dates without a zone are taken as UTC.
+func parseLooseDate(raw, layout string) (time.Time, bool) {
+	raw = strings.Join(strings.Fields(raw), " ")
+	if raw == "" {
+		return time.Time{}, false
+	}
+	layouts := looseDateLayouts
+	if layout != "" {
+		layouts = append([]string{layout}, layouts...)
+	}
+	for _, l := range layouts {
+		if t, err := time.Parse(l, raw); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+const listingPage = `<html><head><base href="https://ministry.example/press/"></head><body>
+<nav><a href="/">Home</a></nav>
+<div class="releases">
+  <article class="release">
+    <h2><a href="2024/rates.html#top">Interest &amp; rates   update</a></h2>
+    <time datetime="2024-05-01T09:30:00+02:00">1 May</time>
+    <p class="lead">The board <b>raised</b> rates.</p>
+  </article>
+  <article class="release">
+    <h2>Undated note</h2><a class="more" href="https://ministry.example/notes/7">Read</a>
+    <span class="date">2024年5月3日</span>
+  </article>
+  <article class="release"><h2>No link</h2></article>
+  <article class="release"><h2><a href="javascript:void(0)">Script</a></h2></article>
+  <article class="release"><h2><a href="2024/rates.html">Duplicate</a></h2></article>
+</div></body></html>`
+
+func TestScrapeListing(t *testing.T) {
+	now := time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC)
+	page, _ := url.Parse("https://ministry.example/press/index.html")
+	cfg := ScrapeConfig{Item: "div.releases > article.release", Title: "h2", Summary: "p.lead"}
+	items, err := scrapeListing(listingPage, page, cfg, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items: %+v", len(items), items)
+	}
+	first := items[0]
+	if first.Title != "Interest & rates update" || first.URL != "https://ministry.example/press/2024/rates.html" {
+		t.Errorf("first: %+v", first)
+	}
+	if want := time.Date(2024, 5, 1, 7, 30, 0, 0, time.UTC); !first.PublishedAt.Equal(want) {
+		t.Errorf("first published %v, want %v", first.PublishedAt, want)
+	}
+	if first.Content != "The board raised rates." || !first.FetchedAt.Equal(now) {
+		t.Errorf("first content/fetched: %q %v", first.Content, first.FetchedAt)
+	}
+	if second := items[1]; second.URL != "https://ministry.example/notes/7" || !second.PublishedAt.IsZero() {
+		t.Errorf("second: %+v", second)
+	}
+
+	cfg.Link, cfg.Date = "a.more@href", "span.date"
+	items, err = scrapeListing(listingPage, page, cfg, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || !items[0].PublishedAt.Equal(time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("explicit link and date selectors: %+v", items)
+	}
+}
+
+func TestParseLooseDate(t *testing.T) {
+	want := time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)
+	for _, raw := range []string{"2024-03-09", "March 9, 2024", " 9 Mar 2024 ", "2024/03/09", "2024年3月9日"} {
+		if got, ok := parseLooseDate(raw, ""); !ok || !got.Equal(want) {
+			t.Errorf("parseLooseDate(%q) = %v, %v", raw, got, ok)
+		}
+	}
+	if got, ok := parseLooseDate("09.03.24", "02.01.06"); !ok || !got.Equal(want) {
+		t.Errorf("custom layout: %v, %v", got, ok)
+	}
+	if _, ok := parseLooseDate("yesterday", ""); ok {
+		t.Error("relative dates are not supported")
+	}
+}
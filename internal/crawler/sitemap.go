@@ -0,0 +1,387 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"news-go/internal/news"
+)
+
+const (
+	// maxSitemapBytes is the protocol's limit on an uncompressed sitemap;
+	// anything past it is ignored.
+	maxSitemapBytes = 50 << 20
+	// maxSitemapEntries is the protocol's limit on <url> or <sitemap>
+	// entries per file.
+	maxSitemapEntries = 50000
+	// maxSitemapDepth bounds nested sitemap indexes.
+	maxSitemapDepth = 3
+)
+
+func init() {
+	Register(Kind{
+		Name:     KindSitemap,
+		New:      func(c *http.Client, _ FetcherOptions) Fetcher { return NewSitemapFetcher(c) },
+		Validate: validateSitemap,
+	})
+}
+
+// SitemapConfig is the options object of a sitemap source; missing
+// options use the defaults.
+type SitemapConfig struct {
+	// WindowHours drops entries published, or modified when undated, longer
+	// ago, and index children not modified within it. 0 means 48.
+	WindowHours int `json:"window_hours,omitempty"`
+	// MaxSitemaps caps how many sitemaps are read per run, newest first.
+	// 0 means 10.
+	MaxSitemaps int `json:"max_sitemaps,omitempty"`
+}
+
+func (c SitemapConfig) Window() time.Duration {
+	if c.WindowHours <= 0 {
+		return 48 * time.Hour
+	}
+	return time.Duration(c.WindowHours) * time.Hour
+}
+
+func (c SitemapConfig) SitemapLimit() int {
+	if c.MaxSitemaps <= 0 {
+		return 10
+	}
+	return c.MaxSitemaps
+}
+
+func validateSitemap(src news.Source, _ LocalAccess) error {
+	if err := requireHTTP(src); err != nil {
+		return err
+	}
+	var c SitemapConfig
+	if err := src.DecodeOptions(&c); err != nil {
+		return err
+	}
+	if c.WindowHours < 0 || c.MaxSitemaps < 0 {
+		return fmt.Errorf("%w: sitemap limits must not be negative", news.ErrInvalidSource)
+	}
+	return nil
+}
+
+// SitemapFetcher reads articles from a sitemap or sitemap index. Entries
+// with a Google News <news:news> block give their title and date; plain
+// entries need a <lastmod> to be placed in the window.
+type SitemapFetcher struct {
+	client *http.Client
+}
+
+func NewSitemapFetcher(client *http.Client) *SitemapFetcher {
+	return &SitemapFetcher{client: client}
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+	News    *struct {
+		Language        string `xml:"publication>language"`
+		PublicationDate string `xml:"publication_date"`
+		Title           string `xml:"title"`
+		Keywords        string `xml:"keywords"`
+	} `xml:"http://www.google.com/schemas/sitemap-news/0.9 news"`
+	Images []struct {
+		Loc string `xml:"loc"`
+	} `xml:"http://www.google.com/schemas/sitemap-image/1.1 image"`
+}
+
+type sitemapRef struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// sitemapDoc is one parsed file: an index lists Refs, a urlset URLs.
+type sitemapDoc struct {
+	Refs      []sitemapRef
+	URLs      []sitemapURL
+	Truncated bool
+}
+
+// Fetch walks the sitemap tree breadth-first. Index children outside the
+// window are not fetched, and at most SitemapLimit files are read.
+func (f *SitemapFetcher) Fetch(ctx context.Context, src news.Source, userAgent string) (Feed, error) {
+	var cfg SitemapConfig
+	if err := src.DecodeOptions(&cfg); err != nil {
+		return Feed{}, err
+	}
+	now := time.Now().UTC()
+	since := now.Add(-cfg.Window())
+	limit := cfg.SitemapLimit()
+	type pending struct {
+		url   string
+		depth int
+	}
+	queue := []pending{{url: src.FeedURL}}
+	visited := map[string]bool{}
+	out := []news.Article{}
+	seen := map[string]bool{}
+	skipped := 0
+	for len(queue) > 0 && len(visited) < limit {
+		p := queue[0]
+		queue = queue[1:]
+		if visited[p.url] {
+			continue
+		}
+		visited[p.url] = true
+		doc, err := f.get(ctx, p.url, userAgent)
+		if err != nil {
+			if p.depth == 0 {
+				return Feed{}, err
+			}
+			log.Printf("event=sitemap status=error source=%s url=%s err=%v", src.ID, p.url, err)
+			continue
+		}
+		if doc.Truncated {
+			log.Printf("event=sitemap status=truncated source=%s url=%s", src.ID, p.url)
+		}
+		if p.depth+1 < maxSitemapDepth {
+			for _, ref := range freshRefs(doc.Refs, since) {
+				if u := absoluteURL(ref); u != "" {
+					queue = append(queue, pending{url: u, depth: p.depth + 1})
+				}
+			}
+		}
+		for _, u := range doc.URLs {
+			a, ok := sitemapArticle(u, since, now)
+			if !ok {
+				skipped++
+				continue
+			}
+			if !seen[a.URL] {
+				seen[a.URL] = true
+				out = append(out, a)
+			}
+		}
+	}
+	if skipped > 0 {
+		log.Printf("event=sitemap status=ok source=%s kept=%d skipped=%d", src.ID, len(out), skipped)
+	}
+	return Feed{Items: out}, nil
+}
+
+// absoluteURL returns raw when it is an absolute http(s) URL, as the
+// sitemap protocol requires for every <loc>, and "" otherwise.
+func absoluteURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" || u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+// freshRefs orders index children newest first and drops those last
+// modified before since; children without lastmod are kept at the end.
+func freshRefs(refs []sitemapRef, since time.Time) []string {
+	type dated struct {
+		loc string
+		at  time.Time
+	}
+	var keep []dated
+	for _, r := range refs {
+		at, ok := parseW3CDate(r.LastMod)
+		if ok && at.Before(since) {
+			continue
+		}
+		keep = append(keep, dated{loc: strings.TrimSpace(r.Loc), at: at})
+	}
+	sort.SliceStable(keep, func(i, j int) bool { return keep[i].at.After(keep[j].at) })
+	out := make([]string, 0, len(keep))
+	for _, d := range keep {
+		out = append(out, d.loc)
+	}
+	return out
+}
+
+// sitemapArticle maps a <url> entry to an article. The publication date,
+// or lastmod when it is missing, must be within the window. A plain entry
+// has no title, so one is made from its URL, and without lastmod it is
+// skipped: undated pages of a site-wide sitemap would otherwise all be
+// ingested.
+func sitemapArticle(u sitemapURL, since, now time.Time) (news.Article, bool) {
+	link := absoluteURL(u.Loc)
+	if link == "" {
+		return news.Article{}, false
+	}
+	if u.News == nil || strings.TrimSpace(u.News.Title) == "" {
+		published, ok := parseW3CDate(u.LastMod)
+		if !ok || published.Before(since) {
+			return news.Article{}, false
+		}
+		return news.Article{Title: titleFromURL(link), URL: link, Source: "sitemap", PublishedAt: published, FetchedAt: now}, true
+	}
+	published, ok := parseW3CDate(u.News.PublicationDate)
+	if !ok {
+		published, ok = parseW3CDate(u.LastMod)
+	}
+	if ok && published.Before(since) {
+		return news.Article{}, false
+	}
+	a := news.Article{
+		Title:       strings.TrimSpace(u.News.Title),
+		URL:         link,
+		Source:      "sitemap",
+		Language:    news.NormalizeLanguage(u.News.Language),
+		Categories:  uniqueTrimmed(strings.Split(u.News.Keywords, ",")),
+		PublishedAt: published,
+		FetchedAt:   now,
+	}
+	for _, img := range u.Images {
+		if l := absoluteURL(img.Loc); l != "" {
+			a.ImageURL = l
+			break
+		}
+	}
+	return a, true
+}
+
+// titleFromURL stands in for the title of a plain sitemap entry: the last
+// path segment with its extension dropped and dashes and underscores read
+// as spaces, e.g. "storm-hits-coast.html" gives "storm hits coast". When
+// that leaves no letters the URL itself is used.
+func titleFromURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	slug, err := url.PathUnescape(segments[len(segments)-1])
+	if err != nil {
+		return link
+	}
+	if i := strings.LastIndexByte(slug, '.'); i > 0 {
+		slug = slug[:i]
+	}
+	title := strings.Join(strings.Fields(strings.NewReplacer("-", " ", "_", " ", "+", " ").Replace(slug)), " ")
+	if !strings.ContainsFunc(title, unicode.IsLetter) {
+		return link
+	}
+	return title
+}
+
+// parseW3CDate accepts the W3C datetime profile used by sitemaps: a date,
+// or a date and time with zone, optionally with seconds and fractions.
+func parseW3CDate(raw string) (time.Time, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (f *SitemapFetcher) get(ctx context.Context, rawURL, userAgent string) (sitemapDoc, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return sitemapDoc{}, err
+	}
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return sitemapDoc{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return sitemapDoc{}, newStatusError(resp, time.Now())
+	}
+	body := bufio.NewReader(resp.Body)
+	var r io.Reader = body
+	// Sitemaps are often served as .xml.gz; Go only undoes
+	// Content-Encoding, not a gzipped file.
+	if magic, _ := body.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return sitemapDoc{}, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return parseSitemap(io.LimitReader(r, maxSitemapBytes))
+}
+
+var errNotSitemap = errors.New("not a sitemap")
+
+// parseSitemap decodes entries one at a time so a large file is never held
+// in memory as a whole. A file cut short by the size limit yields the
+// entries read so far with Truncated set.
+func parseSitemap(r io.Reader) (sitemapDoc, error) {
+	var doc sitemapDoc
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charsetReader
+	dec.Strict = false
+	root := ""
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			if root == "" {
+				return doc, errNotSitemap
+			}
+			return doc, nil
+		}
+		if err != nil {
+			if root == "" {
+				return doc, fmt.Errorf("%w: %v", errNotSitemap, err)
+			}
+			doc.Truncated = true
+			return doc, nil
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root == "" {
+			root = el.Name.Local
+			if root != "urlset" && root != "sitemapindex" {
+				return doc, fmt.Errorf("%w: root element <%s>", errNotSitemap, root)
+			}
+			continue
+		}
+		if len(doc.URLs)+len(doc.Refs) >= maxSitemapEntries {
+			doc.Truncated = true
+			return doc, nil
+		}
+		switch {
+		case root == "urlset" && el.Name.Local == "url":
+			var u sitemapURL
+			if err := dec.DecodeElement(&u, &el); err != nil {
+				doc.Truncated = true
+				return doc, nil
+			}
+			doc.URLs = append(doc.URLs, u)
+		case root == "sitemapindex" && el.Name.Local == "sitemap":
+			var ref sitemapRef
+			if err := dec.DecodeElement(&ref, &el); err != nil {
+				doc.Truncated = true
+				return doc, nil
+			}
+			doc.Refs = append(doc.Refs, ref)
+		default:
+			if err := dec.Skip(); err != nil {
+				doc.Truncated = true
+				return doc, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"news-go/internal/news"
+)
+
+func TestSitemapFetcherWalksIndex(t *testing.T) {
+	now := time.Now().UTC()
+	recent := now.Add(-2 * time.Hour).Format(time.RFC3339)
+	old := now.Add(-30 * 24 * time.Hour).Format("2006-01-02")
+	mux := http.NewServeMux()
+	var srvURL string
+	var oldFetched bool
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/archive.xml</loc><lastmod>%[2]s</lastmod></sitemap>
+  <sitemap><loc>%[1]s/news.xml.gz</loc><lastmod>%[3]s</lastmod></sitemap>
+</sitemapindex>`, srvURL, old, recent)
+	})
+	mux.HandleFunc("/archive.xml", func(w http.ResponseWriter, r *http.Request) {
+		oldFetched = true
+	})
+	mux.HandleFunc("/news.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		gz := gzip.NewWriter(w)
+		fmt.Fprintf(gz, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"
+        xmlns:news="http://www.google.com/schemas/sitemap-news/0.9"
+        xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+  <url>
+    <loc>https://pub.example/2024/story</loc>
+    <news:news>
+      <news:publication><news:name>Pub</news:name><news:language>en</news:language></news:publication>
+      <news:publication_date>%s</news:publication_date>
+      <news:title>Storm hits coast</news:title>
+      <news:keywords>weather, storms ,Weather</news:keywords>
+    </news:news>
+    <image:image><image:loc>https://pub.example/i.jpg</image:loc></image:image>
+  </url>
+  <url><loc>https://pub.example/2024/harbour-reopens_after-storm.html</loc><lastmod>%s</lastmod></url>
+  <url><loc>https://pub.example/2024/12345/</loc><lastmod>%[2]s</lastmod></url>
+  <url><loc>https://pub.example/about</loc></url>
+  <url><loc>https://pub.example/2020/plain-old</loc><lastmod>%[3]s</lastmod></url>
+  <url>
+    <loc>https://pub.example/2020/old</loc>
+    <news:news><news:publication_date>%[3]s</news:publication_date><news:title>Old</news:title></news:news>
+  </url>
+</urlset>`, recent, recent, old)
+		gz.Close()
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	srvURL = srv.URL
+
+	src := news.Source{ID: "pub", FeedURL: srv.URL + "/sitemap.xml", Kind: KindSitemap}
+	feed, err := NewSitemapFetcher(srv.Client()).Fetch(context.Background(), src, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := feed.Items
+	if oldFetched {
+		t.Error("index child outside the window was fetched")
+	}
+	if len(items) != 3 {
+		t.Fatalf("got %d items: %+v", len(items), items)
+	}
+	if plain := items[1]; plain.Title != "harbour reopens after storm" || plain.PublishedAt.Format(time.RFC3339) != recent {
+		t.Errorf("plain entry: %+v", plain)
+	}
+	if numeric := items[2]; numeric.Title != "https://pub.example/2024/12345/" {
+		t.Errorf("entry without a readable slug: %+v", numeric)
+	}
+	a := items[0]
+	if a.Title != "Storm hits coast" || a.URL != "https://pub.example/2024/story" || a.Language != "en" || a.ImageURL != "https://pub.example/i.jpg" {
+		t.Errorf("article: %+v", a)
+	}
+	if strings.Join(a.Categories, "|") != "weather|storms" || a.PublishedAt.Format(time.RFC3339) != recent {
+		t.Errorf("categories/date: %v %v", a.Categories, a.PublishedAt)
+	}
+}
+
+func TestParseSitemapTruncated(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&b, `<url><loc>https://ex.test/%d</loc></url>`, i)
+	}
+	b.WriteString(`</urlset>`)
+	doc, err := parseSitemap(io.LimitReader(&b, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !doc.Truncated || len(doc.URLs) == 0 || len(doc.URLs) >= 100 {
+		t.Errorf("truncated=%v urls=%d", doc.Truncated, len(doc.URLs))
+	}
+	if _, err := parseSitemap(strings.NewReader(`<rss><channel/></rss>`)); err == nil {
+		t.Error("rss accepted as sitemap")
+	}
+}
@@ -0,0 +1,177 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+
+	"news-go/internal/news"
+)
+
+// Fetcher reads the current items of one source.
+type Fetcher interface {
+	Fetch(ctx context.Context, src news.Source, userAgent string) (Feed, error)
+}
+
+// FetcherFunc adapts a function to Fetcher.
+type FetcherFunc func(ctx context.Context, src news.Source, userAgent string) (Feed, error)
+
+func (f FetcherFunc) Fetch(ctx context.Context, src news.Source, userAgent string) (Feed, error) {
+	return f(ctx, src, userAgent)
+}
+
+// Kind is one way of ingesting sources. New builds the kind's fetcher
+// around the shared crawl client and the FetcherOptions given to
+// NewFetchers; Validate checks a source's URL and Options and may be nil
+// when any http(s) URL will do. Sources use http or https URLs unless the
+// kind lists other Schemes.
+type Kind struct {
+	Name     string
+	New      func(client *http.Client, opts FetcherOptions) Fetcher
+	Validate func(src news.Source, access LocalAccess) error
+	Schemes  []string
+}
+
+const (
+	KindAtom    = "atom"
+	KindHTML    = "html"
+	KindSitemap = "sitemap"
+	KindJSONAPI = "json-api"
+	KindFile    = "file"
+)
+
+var (
+	kindsMu sync.RWMutex
+	kinds   = map[string]Kind{}
+)
+
+// Register makes a source kind available to ValidateSource and
+// NewFetchers. Kinds register themselves from init; registering a name
+// twice panics.
+func Register(k Kind) {
+	kindsMu.Lock()
+	defer kindsMu.Unlock()
+	if k.Name == "" || k.New == nil {
+		panic("crawler: kind needs a name and a constructor")
+	}
+	if _, dup := kinds[k.Name]; dup {
+		panic("crawler: kind registered twice: " + k.Name)
+	}
+	kinds[k.Name] = k
+}
+
+// KindNames lists the registered kinds in alphabetical order.
+func KindNames() []string {
+	kindsMu.RLock()
+	defer kindsMu.RUnlock()
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupKind(name string) (Kind, bool) {
+	kindsMu.RLock()
+	defer kindsMu.RUnlock()
+	if name == "" {
+		name = news.KindRSS
+	}
+	k, ok := kinds[name]
+	return k, ok
+}
+
+// ValidateSource runs src.Validate and then the checks of its kind; local
+// files, CA bundles and secrets must be within access.
+func ValidateSource(src news.Source, access LocalAccess) error {
+	k, ok := lookupKind(src.Kind)
+	if err := src.Validate(k.Schemes...); err != nil {
+		return err
+	}
+	if err := validateSourceHTTP(src, access); err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: unknown kind %q", news.ErrInvalidSource, src.Kind)
+	}
+	if k.Validate == nil {
+		return requireHTTP(src)
+	}
+	return k.Validate(src, access)
+}
+
+// requireHTTP is the URL check of kinds that fetch over the network.
+func requireHTTP(src news.Source) error {
+	u, err := url.Parse(src.FeedURL)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: rss must use http or https", news.ErrInvalidSource)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%w: rss must include a host", news.ErrInvalidSource)
+	}
+	return nil
+}
+
+// FetcherOptions is what kinds may need beyond the crawl client. The zero
+// value parses feeds within DefaultFeedLimits, reads file sources without
+// a ledger and reaches nothing on the local host.
+type FetcherOptions struct {
+	FeedLimits FeedLimits
+	Files      FileLedger
+	Access     LocalAccess
+}
+
+// FetcherOption sets one of the FetcherOptions.
+type FetcherOption func(*FetcherOptions)
+
+// WithFeedLimits parses feed documents, fetched or read from files,
+// within l instead of DefaultFeedLimits.
+func WithFeedLimits(l FeedLimits) FetcherOption {
+	return func(o *FetcherOptions) { o.FeedLimits = l }
+}
+
+// WithFileLedger lets file sources skip the files they have read before.
+func WithFileLedger(ledger FileLedger) FetcherOption {
+	return func(o *FetcherOptions) { o.Files = ledger }
+}
+
+// WithLocalAccess lets file and email sources read within a.
+func WithLocalAccess(a LocalAccess) FetcherOption {
+	return func(o *FetcherOptions) { o.Access = a }
+}
+
+// Fetchers holds one fetcher per registered kind, all sharing a client.
+type Fetchers map[string]Fetcher
+
+func NewFetchers(client *http.Client, opts ...FetcherOption) Fetchers {
+	var o FetcherOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.FeedLimits = o.FeedLimits.withDefaults()
+	kindsMu.RLock()
+	defer kindsMu.RUnlock()
+	f := make(Fetchers, len(kinds))
+	for name, k := range kinds {
+		f[name] = k.New(client, o)
+	}
+	return f
+}
+
+// Fetch dispatches to the fetcher of src's kind, with src attached to ctx
+// for SourceTransport.
+func (f Fetchers) Fetch(ctx context.Context, src news.Source, userAgent string) (Feed, error) {
+	kind := src.Kind
+	if kind == "" {
+		kind = news.KindRSS
+	}
+	fetcher, ok := f[kind]
+	if !ok {
+		return Feed{}, fmt.Errorf("no fetcher for source kind %q", kind)
+	}
+	return fetcher.Fetch(WithSource(ctx, src), src, userAgent)
+}
@@ -0,0 +1,106 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"news-go/internal/news"
+)
+
+func TestKindRegistry(t *testing.T) {
+	want := []string{"atom", "email", "file", "html", "json-api", "rss", "sitemap"}
+	if got := KindNames(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("KindNames() = %v", got)
+	}
+	fetchers := NewFetchers(nil)
+	for _, name := range want {
+		if fetchers[name] == nil {
+			t.Errorf("no fetcher for %s", name)
+		}
+	}
+	if _, err := fetchers.Fetch(context.Background(), news.Source{Kind: "gopher"}, ""); err == nil {
+		t.Error("fetched an unknown kind")
+	}
+
+	ledger := mapLedger{}
+	access := LocalAccess{FileRoot: "/srv/drop"}
+	fetchers = NewFetchers(nil, WithFeedLimits(FeedLimits{MaxItems: 3}), WithFileLedger(ledger), WithLocalAccess(access))
+	for _, name := range []string{"rss", "atom"} {
+		if rss, ok := fetchers[name].(*RSSFetcher); !ok || rss.limits.MaxItems != 3 || rss.limits.MaxBytes != DefaultFeedLimits().MaxBytes {
+			t.Errorf("%s fetcher = %#v", name, fetchers[name])
+		}
+	}
+	if file, ok := fetchers["file"].(*FileFetcher); !ok || file.Limits.MaxItems != 3 || file.Ledger == nil || file.Access != access {
+		t.Errorf("file fetcher = %#v", fetchers["file"])
+	}
+	if email, ok := fetchers["email"].(EmailFetcher); !ok || email.Access != access {
+		t.Errorf("email fetcher = %#v", fetchers["email"])
+	}
+}
+
+func TestValidateSourceByKind(t *testing.T) {
+	root := t.TempDir()
+	access := LocalAccess{FileRoot: root}
+	if err := os.WriteFile(filepath.Join(root, "feed.xml"), []byte(richFeed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	base := news.Source{ID: "x", Name: "X", FeedURL: "https://x.test/feed"}
+	with := func(kind, url, options string) news.Source {
+		s := base
+		s.Kind, s.Options = kind, json.RawMessage(options)
+		if url != "" {
+			s.FeedURL = url
+		}
+		s.Normalize()
+		return s
+	}
+	valid := map[string]news.Source{
+		"rss":      with("", "", ""),
+		"atom":     with("atom", "", ""),
+		"html":     with("html", "", `{"item":"li","title":"a"}`),
+		"sitemap":  with("sitemap", "", `{"window_hours":12}`),
+		"json-api": with("json-api", "", `{"items":"data.posts","url":"link"}`),
+		"file":     with("file", "file://"+filepath.ToSlash(root)+"/feed.xml", ""),
+	}
+	for name, src := range valid {
+		if err := ValidateSource(src, access); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	invalid := map[string]news.Source{
+		"ftp rss":         with("rss", "ftp://x.test/feed", ""),
+		"file rss":        with("rss", "file:///srv/drop/feed.xml", ""),
+		"file sitemap":    with("sitemap", "file:///srv/sitemap.xml", ""),
+		"imap file":       with("file", "imap://u@mail.test/", ""),
+		"unknown kind":    with("gopher", "", ""),
+		"html no options": with("html", "", ""),
+		"bad selector":    with("html", "", `{"item":"li[","title":"a"}`),
+		"negative window": with("sitemap", "", `{"window_hours":-1}`),
+		"options array":   with("json-api", "", `[1]`),
+		"http file":       with("file", "", ""),
+		"remote file":     with("file", "file://nas/feed.xml", ""),
+	}
+	for name, src := range invalid {
+		if err := ValidateSource(src, access); !errors.Is(err, news.ErrInvalidSource) {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+}
+
+func TestFileFetcher(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "feed.xml")
+	if err := os.WriteFile(path, []byte(richFeed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src := news.Source{ID: "drop", Kind: KindFile, FeedURL: "file://" + filepath.ToSlash(path)}
+	feed, err := NewFetchers(nil, WithLocalAccess(LocalAccess{FileRoot: root})).Fetch(context.Background(), src, "")
+	if err != nil || len(feed.Items) != 2 {
+		t.Fatalf("got %d items, %v", len(feed.Items), err)
+	}
+}
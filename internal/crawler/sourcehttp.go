@@ -0,0 +1,329 @@
+package crawler
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"news-go/internal/news"
+)
+
+// maxSourceTimeout bounds SourceHTTP.TimeoutSec.
+const maxSourceTimeout = 300 * time.Second
+
+type sourceKey struct{}
+
+// WithSource attaches src to ctx, so a SourceTransport applies the
+// source's HTTP settings to requests made under it. Fetchers.Fetch does
+// this for every fetch.
+func WithSource(ctx context.Context, src news.Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, src)
+}
+
+func sourceFromContext(ctx context.Context) (news.Source, bool) {
+	src, ok := ctx.Value(sourceKey{}).(news.Source)
+	return src, ok
+}
+
+// SourceTransport applies the HTTP settings of the source found in a
+// request's context: proxy and CA bundle pick the connection, headers and
+// auth are added, and TimeoutSec replaces the Default timeout that bounds
+// each request until its body is closed. Headers and credentials are only
+// sent to the host of the source's URL and its subdomains, never to other
+// hosts a page links or redirects to. Secrets and CA bundles are looked up
+// within Access.
+type SourceTransport struct {
+	Base    http.RoundTripper
+	Default time.Duration
+	Access  LocalAccess
+
+	mu         sync.Mutex
+	transports map[string]*http.Transport
+}
+
+func NewSourceTransport(base http.RoundTripper, timeout time.Duration, access LocalAccess) *SourceTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &SourceTransport{Base: base, Default: timeout, Access: access, transports: map[string]*http.Transport{}}
+}
+
+func (t *SourceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	src, _ := sourceFromContext(req.Context())
+	h := src.HTTP
+	rt, timeout := t.Base, t.Default
+	if h != nil {
+		if h.TimeoutSec > 0 {
+			timeout = time.Duration(h.TimeoutSec) * time.Second
+		}
+		if h.Proxy != "" || h.CAFile != "" {
+			var err error
+			if rt, err = t.transport(h); err != nil {
+				return nil, fmt.Errorf("source %s: %w", src.ID, err)
+			}
+		}
+		if sameSite(req.URL, src.FeedURL) {
+			req = req.Clone(req.Context())
+			if err := applySourceHeaders(req.Header, h, t.Access); err != nil {
+				return nil, fmt.Errorf("source %s: %w", src.ID, err)
+			}
+		}
+	}
+	if timeout <= 0 {
+		return rt.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := rt.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// transport returns the cached transport for a proxy and CA bundle. The
+// proxy password is read on every request and the cache keyed on a hash
+// of the resulting URL, so a rotated password takes effect at once
+// without the password itself being kept as a key.
+func (t *SourceTransport) transport(h *news.SourceHTTP) (*http.Transport, error) {
+	var proxy *url.URL
+	if h.Proxy != "" {
+		var err error
+		if proxy, err = sourceProxy(h, t.Access); err != nil {
+			return nil, err
+		}
+	}
+	key := h.CAFile
+	if proxy != nil {
+		sum := sha256.Sum256([]byte(proxy.String()))
+		key = hex.EncodeToString(sum[:]) + "\x00" + key
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if tr, ok := t.transports[key]; ok {
+		return tr, nil
+	}
+	// Cloning Base keeps its dialer, e.g. the PublicOnly check.
+	base, ok := t.Base.(*http.Transport)
+	if !ok {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	tr := base.Clone()
+	if proxy != nil {
+		tr.Proxy = http.ProxyURL(proxy)
+	}
+	if h.CAFile != "" {
+		pool, err := loadCAFile(h.CAFile, t.Access)
+		if err != nil {
+			return nil, err
+		}
+		tr.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	t.transports[key] = tr
+	return tr, nil
+}
+
+func sourceProxy(h *news.SourceHTTP, access LocalAccess) (*url.URL, error) {
+	u, err := url.Parse(h.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("proxy: %v", err)
+	}
+	if h.ProxyPasswordEnv != "" {
+		password, err := access.secretEnv(h.ProxyPasswordEnv)
+		if err != nil {
+			return nil, fmt.Errorf("proxy: %w", err)
+		}
+		u.User = url.UserPassword(u.User.Username(), password)
+	}
+	return u, nil
+}
+
+// loadCAFile returns the system roots plus the certificates of a PEM file
+// in CADir.
+func loadCAFile(name string, access LocalAccess) (*x509.CertPool, error) {
+	path, err := access.caPath(name)
+	if err != nil {
+		return nil, err
+	}
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errCAFile
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errCAFile
+	}
+	return pool, nil
+}
+
+func applySourceHeaders(header http.Header, h *news.SourceHTTP, access LocalAccess) error {
+	for k, v := range h.Headers {
+		header.Set(k, v)
+	}
+	for k, env := range h.HeaderEnv {
+		v, err := access.secretEnv(env)
+		if err != nil {
+			return fmt.Errorf("header %s: %w", k, err)
+		}
+		header.Set(k, v)
+	}
+	if h.Auth == nil {
+		return nil
+	}
+	switch h.Auth.Type {
+	case news.AuthBasic:
+		password, err := access.secretEnv(h.Auth.PasswordEnv)
+		if err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(h.Auth.Username+":"+password)))
+	case news.AuthBearer:
+		token, err := access.secretEnv(h.Auth.TokenEnv)
+		if err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// sameSite reports whether u is on the host of feedURL or a subdomain.
+func sameSite(u *url.URL, feedURL string) bool {
+	feed, err := url.Parse(feedURL)
+	if err != nil || feed.Hostname() == "" {
+		return false
+	}
+	host, base := strings.ToLower(u.Hostname()), strings.ToLower(feed.Hostname())
+	return host == base || strings.HasSuffix(host, "."+base)
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// validateSourceHTTP checks SourceHTTP without resolving secrets, which
+// may only be set where the crawler runs.
+func validateSourceHTTP(src news.Source, access LocalAccess) error {
+	h := src.HTTP
+	if h.IsZero() {
+		return nil
+	}
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: http: "+format, append([]any{news.ErrInvalidSource}, args...)...)
+	}
+	if u, err := url.Parse(src.FeedURL); err != nil || u.Scheme != "http" && u.Scheme != "https" {
+		return invalid("settings need an http or https source")
+	}
+	if h.Proxy != "" {
+		u, err := url.Parse(h.Proxy)
+		if err != nil || u.Host == "" || u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+			return invalid("proxy must be an http, https or socks5 URL")
+		}
+		if _, ok := u.User.Password(); ok {
+			return invalid("proxy password must come from proxy_password_env")
+		}
+	}
+	if h.ProxyPasswordEnv != "" {
+		if err := access.checkSecretEnv(h.ProxyPasswordEnv); err != nil {
+			return invalid("proxy_password_env: %v", err)
+		}
+	}
+	for k, v := range h.Headers {
+		if err := checkHeaderName(k); err != nil {
+			return invalid("%v", err)
+		}
+		switch http.CanonicalHeaderKey(k) {
+		case "Authorization", "Proxy-Authorization", "Cookie":
+			return invalid("header %s carries a secret, use header_env or auth", k)
+		}
+		if strings.ContainsAny(v, "\r\n") {
+			return invalid("header %s has a line break", k)
+		}
+	}
+	for k, env := range h.HeaderEnv {
+		if err := checkHeaderName(k); err != nil {
+			return invalid("%v", err)
+		}
+		if err := access.checkSecretEnv(env); err != nil {
+			return invalid("header_env %s: %v", k, err)
+		}
+	}
+	if a := h.Auth; a != nil {
+		switch {
+		case a.Type == news.AuthBasic && (a.Username == "" || a.PasswordEnv == ""):
+			return invalid("basic auth needs username and password_env")
+		case a.Type == news.AuthBearer && a.TokenEnv == "":
+			return invalid("bearer auth needs token_env")
+		case a.Type != news.AuthBasic && a.Type != news.AuthBearer:
+			return invalid("auth type must be basic or bearer")
+		}
+		env, field := a.PasswordEnv, "password_env"
+		if a.Type == news.AuthBearer {
+			env, field = a.TokenEnv, "token_env"
+		}
+		if err := access.checkSecretEnv(env); err != nil {
+			return invalid("auth %s: %v", field, err)
+		}
+	}
+	if h.CAFile != "" {
+		if _, err := loadCAFile(h.CAFile, access); err != nil {
+			return invalid("%v", err)
+		}
+	}
+	if h.TimeoutSec < 0 || time.Duration(h.TimeoutSec)*time.Second > maxSourceTimeout {
+		return invalid("timeout_sec must be within [0,%d]", int(maxSourceTimeout/time.Second))
+	}
+	return nil
+}
+
+// checkHeaderName accepts RFC 9110 tokens other than the headers the
+// client manages itself.
+func checkHeaderName(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty header name")
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", c)) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	switch http.CanonicalHeaderKey(name) {
+	case "Host", "Content-Length", "Transfer-Encoding", "Connection":
+		return fmt.Errorf("header %s is set by the client", name)
+	}
+	return nil
+}
+
+func validEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if !(c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,244 @@
+package crawler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"news-go/internal/news"
+)
+
+func TestSourceTransportHeadersAndAuth(t *testing.T) {
+	t.Setenv("NEWS_TEST_TOKEN", "s3cret")
+	t.Setenv("NEWS_TEST_KEY", "k1")
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewSourceTransport(srv.Client().Transport, time.Second, LocalAccess{SecretEnvPrefix: "NEWS_TEST_"})}
+	src := news.Source{ID: "s", FeedURL: srv.URL + "/feed", HTTP: &news.SourceHTTP{
+		Headers:   map[string]string{"X-Client": "news-go"},
+		HeaderEnv: map[string]string{"X-Api-Key": "NEWS_TEST_KEY"},
+		Auth:      &news.SourceAuth{Type: news.AuthBearer, TokenEnv: "NEWS_TEST_TOKEN"},
+	}}
+	get := func(ctx context.Context, url string) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(WithSource(context.Background(), src), srv.URL+"/other"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Get("Authorization") != "Bearer s3cret" || got.Get("X-Api-Key") != "k1" || got.Get("X-Client") != "news-go" {
+		t.Fatalf("headers = %v", got)
+	}
+
+	// Another host never sees the source's credentials.
+	src.FeedURL = "https://feeds.example.test/rss"
+	if err := get(WithSource(context.Background(), src), srv.URL); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Get("Authorization") != "" || got.Get("X-Client") != "" {
+		t.Fatalf("credentials sent to another host: %v", got)
+	}
+
+	src.FeedURL = srv.URL
+	src.HTTP.Auth = &news.SourceAuth{Type: news.AuthBasic, Username: "u", PasswordEnv: "NEWS_TEST_UNSET"}
+	if err := get(WithSource(context.Background(), src), srv.URL); err == nil || !strings.Contains(err.Error(), "NEWS_TEST_UNSET") {
+		t.Fatalf("unset secret: %v", err)
+	}
+
+	// A stored source naming a variable outside the prefix sends nothing.
+	t.Setenv("DB_PASSWORD", "hunter2")
+	src.HTTP.Auth = &news.SourceAuth{Type: news.AuthBearer, TokenEnv: "DB_PASSWORD"}
+	got = nil
+	if err := get(WithSource(context.Background(), src), srv.URL); err == nil || got != nil {
+		t.Fatalf("read DB_PASSWORD: %v, headers %v", err, got)
+	}
+}
+
+func TestSourceTransportTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewSourceTransport(srv.Client().Transport, time.Minute, LocalAccess{})}
+	src := news.Source{ID: "slow", FeedURL: srv.URL, HTTP: &news.SourceHTTP{TimeoutSec: 1}}
+	req, _ := http.NewRequestWithContext(WithSource(context.Background(), src), http.MethodGet, srv.URL, nil)
+	started := time.Now()
+	_, err := client.Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 1800*time.Millisecond {
+		t.Fatalf("per-source timeout ignored: %v", elapsed)
+	}
+}
+
+func TestSourceTransportProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	client := &http.Client{Transport: NewSourceTransport(nil, time.Second, LocalAccess{})}
+	src := news.Source{ID: "p", FeedURL: "http://feeds.example.test/rss", HTTP: &news.SourceHTTP{Proxy: proxy.URL}}
+	req, _ := http.NewRequestWithContext(WithSource(context.Background(), src), http.MethodGet, src.FeedURL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if proxied != src.FeedURL {
+		t.Fatalf("proxy saw %q", proxied)
+	}
+}
+
+func TestSourceTransportProxyPasswordRotation(t *testing.T) {
+	var auth string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Proxy-Authorization")
+	}))
+	defer proxy.Close()
+
+	client := &http.Client{Transport: NewSourceTransport(nil, time.Second, LocalAccess{SecretEnvPrefix: "NEWS_TEST_"})}
+	src := news.Source{ID: "p", FeedURL: "http://feeds.example.test/rss", HTTP: &news.SourceHTTP{
+		Proxy: strings.Replace(proxy.URL, "http://", "http://u@", 1), ProxyPasswordEnv: "NEWS_TEST_PROXY"}}
+	get := func() error {
+		req, _ := http.NewRequestWithContext(WithSource(context.Background(), src), http.MethodGet, src.FeedURL, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	basic := func(password string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte("u:"+password))
+	}
+
+	// A password set after the first attempt is picked up.
+	if err := get(); err == nil || !strings.Contains(err.Error(), "NEWS_TEST_PROXY") {
+		t.Fatalf("unset password: %v", err)
+	}
+	for _, password := range []string{"first", "rotated"} {
+		t.Setenv("NEWS_TEST_PROXY", password)
+		if err := get(); err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if auth != basic(password) {
+			t.Fatalf("proxy saw %q, want password %q", auth, password)
+		}
+	}
+}
+
+func TestValidateSourceHTTP(t *testing.T) {
+	access := LocalAccess{SecretEnvPrefix: "NEWS_SECRET_"}
+	cases := map[string]news.SourceHTTP{
+		"proxy scheme":       {Proxy: "ftp://proxy.test:21"},
+		"proxy password":     {Proxy: "http://u:p@proxy.test:3128"},
+		"static auth header": {Headers: map[string]string{"authorization": "Bearer x"}},
+		"host header":        {Headers: map[string]string{"Host": "x.test"}},
+		"header line break":  {Headers: map[string]string{"X-A": "a\r\nX-B: b"}},
+		"bad env name":       {HeaderEnv: map[string]string{"X-Key": "1KEY"}},
+		"header env prefix":  {HeaderEnv: map[string]string{"X-Key": "DB_PASSWORD"}},
+		"bare prefix":        {HeaderEnv: map[string]string{"X-Key": "NEWS_SECRET_"}},
+		"token env prefix":   {Auth: &news.SourceAuth{Type: news.AuthBearer, TokenEnv: "HOME"}},
+		"basic env prefix":   {Auth: &news.SourceAuth{Type: news.AuthBasic, Username: "u", PasswordEnv: "PGPASSWORD"}},
+		"proxy env prefix":   {Proxy: "http://u@proxy.test:3128", ProxyPasswordEnv: "AWS_SECRET_ACCESS_KEY"},
+		"basic without env":  {Auth: &news.SourceAuth{Type: news.AuthBasic, Username: "u"}},
+		"unknown auth":       {Auth: &news.SourceAuth{Type: "digest"}},
+		"missing ca file":    {CAFile: "/nonexistent/ca.pem"},
+		"timeout":            {TimeoutSec: 301},
+	}
+	for name, h := range cases {
+		h := h
+		src := news.Source{ID: "x", Name: "X", FeedURL: "https://x.test/feed", HTTP: &h}
+		if err := validateSourceHTTP(src, access); !errors.Is(err, news.ErrInvalidSource) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+	ok := news.Source{ID: "x", FeedURL: "https://x.test/feed", HTTP: &news.SourceHTTP{
+		Proxy:     "socks5://proxy.test:1080",
+		Headers:   map[string]string{"Accept-Language": "de"},
+		HeaderEnv: map[string]string{"Cookie": "NEWS_SECRET_COOKIE"},
+		Auth:      &news.SourceAuth{Type: news.AuthBasic, Username: "u", PasswordEnv: "NEWS_SECRET_PASSWORD"},
+	}}
+	if err := validateSourceHTTP(ok, access); err != nil {
+		t.Fatalf("valid settings: %v", err)
+	}
+}
+
+func TestValidateSourceCAFile(t *testing.T) {
+	tls := httptest.NewTLSServer(http.NotFoundHandler())
+	tls.Close()
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tls.Certificate().Raw})
+	dir, outside := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(dir, "partner.pem"), cert, 0o644)
+	os.WriteFile(filepath.Join(outside, "ca.pem"), cert, 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a certificate"), 0o644)
+	os.Symlink(filepath.Join(outside, "ca.pem"), filepath.Join(dir, "link.pem"))
+	var access LocalAccess
+	validate := func(caFile string) error {
+		return validateSourceHTTP(news.Source{ID: "x", FeedURL: "https://x.test/feed", HTTP: &news.SourceHTTP{CAFile: caFile}}, access)
+	}
+	if err := validate("partner.pem"); !errors.Is(err, news.ErrInvalidSource) {
+		t.Errorf("accepted without CA_FILE_DIR: %v", err)
+	}
+
+	access.CADir = dir
+	for _, ok := range []string{"partner.pem", filepath.Join(dir, "partner.pem")} {
+		if err := validate(ok); err != nil {
+			t.Errorf("%s: %v", ok, err)
+		}
+	}
+	var msgs []string
+	for _, bad := range []string{filepath.Join(outside, "ca.pem"), filepath.Join(outside, "missing.pem"), "../" + filepath.Base(outside) + "/ca.pem", "link.pem", "notes.txt", "/etc/passwd"} {
+		err := validate(bad)
+		if !errors.Is(err, news.ErrInvalidSource) {
+			t.Errorf("%s: err = %v", bad, err)
+			continue
+		}
+		msgs = append(msgs, err.Error())
+	}
+	// Every refusal reads the same, whatever exists on disk.
+	for _, m := range msgs {
+		if m != msgs[0] {
+			t.Errorf("errors differ: %q / %q", m, msgs[0])
+		}
+	}
+}
+
+func TestSourceTransportKeepsPublicOnlyDialer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	client := &http.Client{Transport: NewSourceTransport(NewPublicTransport(), time.Second, LocalAccess{})}
+	for name, h := range map[string]*news.SourceHTTP{
+		"plain": nil,
+		"proxy": {Proxy: srv.URL},
+	} {
+		src := news.Source{ID: "p", FeedURL: srv.URL, HTTP: h}
+		req, _ := http.NewRequestWithContext(WithSource(context.Background(), src), http.MethodGet, srv.URL, nil)
+		if _, err := client.Do(req); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
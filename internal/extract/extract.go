@@ -0,0 +1,90 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"news-go/internal/htmldoc"
+)
+
+var (
+	// ErrNoContent means the page was fetched but no article body was found.
+	ErrNoContent = errors.New("no article body found")
+	// ErrUnsupportedPage covers non-HTML responses and pages that are not
+	// UTF-8, which would otherwise be stored as mojibake.
+	ErrUnsupportedPage = errors.New("unsupported page")
+)
+
+// MinBodyRunes is the shortest text accepted as an article body; anything
+// shorter is usually a paywall stub or a cookie notice.
+const MinBodyRunes = 140
+
+// maxPageBytes bounds how much of an article page is read.
+const maxPageBytes = 4 << 20
+
+// Extractor fetches article pages and returns their readable text. The
+// client should be the crawl client so robots.txt rules apply.
+type Extractor struct {
+	client    *http.Client
+	userAgent string
+}
+
+func NewExtractor(client *http.Client, userAgent string) *Extractor {
+	return &Extractor{client: client, userAgent: userAgent}
+}
+
+func (e *Extractor) Extract(ctx context.Context, pageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if e.userAgent != "" {
+		req.Header.Set("User-Agent", e.userAgent)
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("article status: %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		mt, params, _ := mime.ParseMediaType(ct)
+		if mt != "text/html" && mt != "application/xhtml+xml" {
+			return "", fmt.Errorf("%w: content type %s", ErrUnsupportedPage, mt)
+		}
+		if cs := strings.ToLower(params["charset"]); cs != "" && cs != "utf-8" && cs != "utf8" {
+			return "", fmt.Errorf("%w: charset %s", ErrUnsupportedPage, cs)
+		}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	if err != nil {
+		return "", err
+	}
+	return Text(body)
+}
+
+// Text runs Readable over an HTML page and applies MinBodyRunes.
+func Text(page []byte) (string, error) {
+	if !utf8.Valid(page) {
+		return "", fmt.Errorf("%w: page is not valid UTF-8", ErrUnsupportedPage)
+	}
+	doc, err := htmldoc.Parse(bytes.NewReader(page))
+	if err != nil {
+		return "", err
+	}
+	text := Readable(doc)
+	if utf8.RuneCountInString(text) < MinBodyRunes {
+		return "", ErrNoContent
+	}
+	return text, nil
+}
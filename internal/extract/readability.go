@@ -0,0 +1,213 @@
+// Package extract pulls the main article text out of a web page.
+package extract
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"news-go/internal/htmldoc"
+)
+
+// boilerplateTags never hold article text.
+var boilerplateTags = []string{
+	"script", "style", "noscript", "iframe", "form", "nav", "header", "footer",
+	"aside", "svg", "button", "select", "input", "textarea", "menu", "dialog",
+}
+
+var (
+	unlikelyRe = regexp.MustCompile(`(?i)comment|sidebar|footer|header|menu|nav|share|social|related|promo|sponsor|advert|\bads?\b|banner|breadcrumb|popup|modal|cookie|subscribe|newsletter|recommend|hot-?list|rank`)
+	likelyRe   = regexp.MustCompile(`(?i)article|body|content|entry|main|post|story|text|detail`)
+	positiveRe = regexp.MustCompile(`(?i)article|body|content|entry|main|post|story|text|detail|page`)
+	negativeRe = regexp.MustCompile(`(?i)comment|sidebar|footer|meta|menu|share|social|related|promo|sponsor|advert|widget|tag|byline|caption|hidden`)
+)
+
+// minParagraphRunes drops captions, buttons and other fragments from scoring.
+const minParagraphRunes = 25
+
+// Readable returns the main text of doc as paragraphs separated by blank
+// lines, or "" when no content block stands out. doc is modified.
+func Readable(doc *htmldoc.Node) string {
+	stripBoilerplate(doc)
+
+	scores := map[*htmldoc.Node]float64{}
+	var candidates []*htmldoc.Node
+	addScore := func(n *htmldoc.Node, v float64) {
+		if n == nil || n.Type != htmldoc.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = tagWeight(n.Tag) + classWeight(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += v
+	}
+	for _, p := range doc.FindAll("p", "pre", "td", "blockquote") {
+		text := p.InnerText()
+		n := utf8.RuneCountInString(text)
+		if n < minParagraphRunes {
+			continue
+		}
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")+strings.Count(text, "、"))
+		score += min(float64(n)/100, 3)
+		addScore(p.Parent, score)
+		if p.Parent != nil {
+			addScore(p.Parent.Parent, score/2)
+		}
+	}
+
+	var top *htmldoc.Node
+	best := 0.0
+	for _, c := range candidates {
+		s := scores[c] * (1 - linkDensity(c))
+		scores[c] = s
+		if s > best {
+			top, best = c, s
+		}
+	}
+	if top == nil {
+		return ""
+	}
+
+	// Siblings sharing the parent often hold the rest of the story, e.g.
+	// when a lead paragraph sits outside the main body div.
+	threshold := max(10, best*0.2)
+	var blocks []*htmldoc.Node
+	if top.Parent != nil {
+		for _, sib := range top.Parent.Children {
+			if sib.Type != htmldoc.ElementNode {
+				continue
+			}
+			if sib == top || scores[sib] >= threshold || isProseParagraph(sib) {
+				blocks = append(blocks, sib)
+			}
+		}
+	} else {
+		blocks = []*htmldoc.Node{top}
+	}
+
+	var paras []string
+	for _, b := range blocks {
+		paras = append(paras, paragraphs(b)...)
+	}
+	return strings.Join(paras, "\n\n")
+}
+
+func stripBoilerplate(doc *htmldoc.Node) {
+	for _, n := range doc.FindAll(boilerplateTags...) {
+		n.Remove()
+	}
+	var unlikely []*htmldoc.Node
+	doc.Walk(func(n *htmldoc.Node) bool {
+		if n.Type != htmldoc.ElementNode || n.Tag == "html" || n.Tag == "body" || n.Tag == "article" || n.Tag == "main" {
+			return true
+		}
+		if n.AttrOr("hidden") != "" || strings.Contains(strings.ReplaceAll(n.AttrOr("style"), " ", ""), "display:none") {
+			unlikely = append(unlikely, n)
+			return false
+		}
+		switch n.AttrOr("role") {
+		case "navigation", "banner", "contentinfo", "complementary", "dialog":
+			unlikely = append(unlikely, n)
+			return false
+		}
+		id := n.AttrOr("class") + " " + n.AttrOr("id")
+		if unlikelyRe.MatchString(id) && !likelyRe.MatchString(id) {
+			unlikely = append(unlikely, n)
+			return false
+		}
+		return true
+	})
+	for _, n := range unlikely {
+		n.Remove()
+	}
+}
+
+func tagWeight(tag string) float64 {
+	switch tag {
+	case "article":
+		return 10
+	case "div", "section", "main":
+		return 5
+	case "pre", "td", "blockquote":
+		return 3
+	case "ol", "ul", "dl", "dd", "dt", "li", "form":
+		return -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		return -5
+	}
+	return 0
+}
+
+func classWeight(n *htmldoc.Node) float64 {
+	w := 0.0
+	for _, v := range []string{n.AttrOr("class"), n.AttrOr("id")} {
+		if v == "" {
+			continue
+		}
+		if negativeRe.MatchString(v) {
+			w -= 25
+		}
+		if positiveRe.MatchString(v) {
+			w += 25
+		}
+	}
+	return w
+}
+
+// linkDensity is the share of n's text that sits inside links.
+func linkDensity(n *htmldoc.Node) float64 {
+	total := utf8.RuneCountInString(n.InnerText())
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	for _, a := range n.FindAll("a") {
+		linked += utf8.RuneCountInString(a.InnerText())
+	}
+	return float64(linked) / float64(total)
+}
+
+func isProseParagraph(n *htmldoc.Node) bool {
+	if n.Tag != "p" {
+		return false
+	}
+	text := n.InnerText()
+	runes := utf8.RuneCountInString(text)
+	d := linkDensity(n)
+	return runes >= 80 && d < 0.25 || runes > 0 && runes < 80 && d == 0 && strings.ContainsAny(text, ".。!?！？")
+}
+
+var textBlockTags = map[string]bool{
+	"p": true, "pre": true, "blockquote": true, "li": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// paragraphs lists the text blocks under n in document order, skipping
+// link-heavy ones. A node without block children yields its own text.
+func paragraphs(n *htmldoc.Node) []string {
+	if textBlockTags[n.Tag] {
+		if text := n.InnerText(); text != "" && linkDensity(n) < 0.5 {
+			return []string{text}
+		}
+		return nil
+	}
+	var out []string
+	found := false
+	n.Walk(func(c *htmldoc.Node) bool {
+		if c == n || c.Type != htmldoc.ElementNode || !textBlockTags[c.Tag] {
+			return true
+		}
+		found = true
+		if text := c.InnerText(); text != "" && linkDensity(c) < 0.5 {
+			out = append(out, text)
+		}
+		return false
+	})
+	if !found {
+		if text := n.InnerText(); text != "" && linkDensity(n) < 0.5 {
+			out = append(out, strings.Split(text, "\n")...)
+		}
+	}
+	return out
+}
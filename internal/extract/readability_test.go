@@ -0,0 +1,71 @@
+package extract
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const articlePage = `<!DOCTYPE html>
+<html><head><title>Example</title><script>var x = "<p>not text</p>";</script></head>
+<body>
+<header><nav><a href="/">Home</a> <a href="/world">World</a></nav></header>
+<div class="sidebar"><p>Most read: a very long list of other stories, each one a link, to pad the sidebar.</p></div>
+<div id="main">
+  <h1>Harbour reopens after storm</h1>
+  <div class="article-body">
+    <p>The harbour reopened on Tuesday morning, three days after the storm forced authorities to close it to all shipping traffic.</p>
+    <p>Port officials said divers had cleared debris from the main channel, and that ferries would resume their normal timetable by the weekend.</p>
+    <p>Fishermen, who lost several days of work, welcomed the news but said repairs to the breakwater would take months.</p>
+    <div class="share">Share this on <a href="#">X</a>, <a href="#">Facebook</a></div>
+  </div>
+</div>
+<div class="comments"><p>Great article, thanks for writing this, I really enjoyed it a lot!</p></div>
+<footer><p>Copyright 2024 Example News, all rights reserved, no reproduction.</p></footer>
+</body></html>`
+
+func TestTextKeepsArticleParagraphs(t *testing.T) {
+	text, err := Text([]byte(articlePage))
+	if err != nil {
+		t.Fatalf("Text: %v", err)
+	}
+	paras := strings.Split(text, "\n\n")
+	if len(paras) != 3 {
+		t.Fatalf("got %d paragraphs, want 3:\n%s", len(paras), text)
+	}
+	if !strings.HasPrefix(paras[0], "The harbour reopened") || !strings.HasPrefix(paras[2], "Fishermen") {
+		t.Fatalf("unexpected paragraphs:\n%s", text)
+	}
+	for _, junk := range []string{"Most read", "Share this", "Great article", "Copyright", "not text", "Home"} {
+		if strings.Contains(text, junk) {
+			t.Errorf("body contains boilerplate %q:\n%s", junk, text)
+		}
+	}
+}
+
+func TestTextChineseArticle(t *testing.T) {
+	page := `<html><body><ul class="nav"><li><a href="/">首页</a></li></ul>
+<div class="content">
+<p>新华社北京电，国家统计局今天发布数据，今年前三季度国内生产总值同比增长，经济运行总体平稳、稳中有进。</p>
+<p>国家统计局新闻发言人表示，前三季度，消费需求持续恢复，投资规模稳步扩大，外贸进出口保持增长，就业形势总体稳定。</p>
+<p>专家认为，随着一系列政策措施落地见效，经济回升向好的态势将进一步巩固，全年目标任务有望顺利完成。</p>
+</div></body></html>`
+	text, err := Text([]byte(page))
+	if err != nil {
+		t.Fatalf("Text: %v", err)
+	}
+	if strings.Contains(text, "首页") || !strings.HasPrefix(text, "新华社北京电") {
+		t.Fatalf("unexpected body:\n%s", text)
+	}
+}
+
+func TestTextRejectsTeaserPages(t *testing.T) {
+	_, err := Text([]byte(`<html><body><p>Subscribe to read the full story.</p></body></html>`))
+	if !errors.Is(err, ErrNoContent) {
+		t.Fatalf("err = %v, want ErrNoContent", err)
+	}
+	_, err = Text([]byte{0xff, 0xfe, '<', 'p', '>'})
+	if !errors.Is(err, ErrUnsupportedPage) {
+		t.Fatalf("err = %v, want ErrUnsupportedPage", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Package health turns per-source crawl state into staleness verdicts for
+// /v1/sources/health and /readyz.
+package health
+
+import (
+	"time"
+
+	"news-go/internal/news"
+)
+
+const (
+	StatusHealthy = "healthy"
+	StatusStale   = "stale"
+	StatusPending = "pending"
+)
+
+// Policy decides when a source counts as stale. Zero fields disable the
+// corresponding check.
+type Policy struct {
+	MaxArticleAge time.Duration
+	MaxFailures   int
+	MaxStaleRatio float64
+}
+
+type SourceReport struct {
+	news.SourceHealth
+	Enabled          bool   `json:"enabled"`
+	Status           string `json:"status"`
+	Reason           string `json:"reason,omitempty"`
+	NewestArticleAge int64  `json:"newest_article_age_sec,omitempty"`
+}
+
+type Report struct {
+	Sources    []SourceReport `json:"sources"`
+	Enabled    int            `json:"enabled"`
+	Stale      int            `json:"stale"`
+	StaleRatio float64        `json:"stale_ratio"`
+	Degraded   bool           `json:"degraded"`
+}
+
+// Evaluate reports on every source. Only enabled sources count towards the
+// stale ratio; disabled ones are listed for visibility.
+func (p Policy) Evaluate(sources []news.Source, records []news.SourceHealth, now time.Time) Report {
+	byID := make(map[string]news.SourceHealth, len(records))
+	for _, r := range records {
+		byID[r.SourceID] = r
+	}
+	rep := Report{Sources: make([]SourceReport, 0, len(sources))}
+	for _, src := range sources {
+		h, ok := byID[src.ID]
+		if !ok {
+			h = news.SourceHealth{SourceID: src.ID}
+		}
+		sr := SourceReport{SourceHealth: h, Enabled: src.Enabled, Status: StatusHealthy}
+		if h.NewestArticleAt != nil {
+			sr.NewestArticleAge = int64(now.Sub(*h.NewestArticleAt).Seconds())
+		}
+		switch {
+		case h.LastAttemptAt == nil:
+			sr.Status = StatusPending
+		case p.MaxFailures > 0 && h.ConsecutiveFailures >= p.MaxFailures:
+			sr.Status, sr.Reason = StatusStale, "consecutive_failures"
+		case p.MaxArticleAge > 0 && h.NewestArticleAt != nil && now.Sub(*h.NewestArticleAt) > p.MaxArticleAge:
+			sr.Status, sr.Reason = StatusStale, "no_recent_articles"
+		}
+		if src.Enabled {
+			rep.Enabled++
+			if sr.Status == StatusStale {
+				rep.Stale++
+			}
+		}
+		rep.Sources = append(rep.Sources, sr)
+	}
+	if rep.Enabled > 0 {
+		rep.StaleRatio = float64(rep.Stale) / float64(rep.Enabled)
+	}
+	rep.Degraded = p.MaxStaleRatio > 0 && rep.Stale > 0 && rep.StaleRatio >= p.MaxStaleRatio
+	return rep
+}
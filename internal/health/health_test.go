@@ -0,0 +1,53 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"news-go/internal/news"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	fresh := now.Add(-time.Hour)
+	old := now.Add(-72 * time.Hour)
+	sources := []news.Source{
+		{ID: "fresh", Enabled: true},
+		{ID: "failing", Enabled: true},
+		{ID: "quiet", Enabled: true},
+		{ID: "new", Enabled: true},
+		{ID: "off", Enabled: false},
+	}
+	records := []news.SourceHealth{
+		{SourceID: "fresh", LastAttemptAt: &fresh, LastSuccessAt: &fresh, NewestArticleAt: &fresh},
+		{SourceID: "failing", LastAttemptAt: &fresh, ConsecutiveFailures: 3, LastError: "attempt 3: timeout"},
+		{SourceID: "quiet", LastAttemptAt: &fresh, LastSuccessAt: &fresh, NewestArticleAt: &old},
+		{SourceID: "off", LastAttemptAt: &old, ConsecutiveFailures: 9},
+	}
+	p := Policy{MaxArticleAge: 24 * time.Hour, MaxFailures: 3, MaxStaleRatio: 0.5}
+	rep := p.Evaluate(sources, records, now)
+
+	want := map[string]string{
+		"fresh":   StatusHealthy,
+		"failing": StatusStale,
+		"quiet":   StatusStale,
+		"new":     StatusPending,
+		"off":     StatusStale,
+	}
+	for _, sr := range rep.Sources {
+		if sr.Status != want[sr.SourceID] {
+			t.Errorf("source %s: expected %s, got %s (%s)", sr.SourceID, want[sr.SourceID], sr.Status, sr.Reason)
+		}
+	}
+	if rep.Enabled != 4 || rep.Stale != 2 {
+		t.Fatalf("expected 2 of 4 enabled stale, got %d of %d", rep.Stale, rep.Enabled)
+	}
+	if !rep.Degraded {
+		t.Fatalf("expected degraded at ratio %.2f", rep.StaleRatio)
+	}
+
+	p.MaxStaleRatio = 0
+	if p.Evaluate(sources, records, now).Degraded {
+		t.Fatalf("expected ratio check disabled")
+	}
+}
@@ -0,0 +1,387 @@
+// Package htmldoc is a small, forgiving HTML parser that builds a DOM tree
+// good enough for content extraction and link discovery. It does not
+// implement the full HTML5 tree-construction algorithm: unknown end tags are
+// ignored and unclosed elements are closed when an ancestor closes.
+package htmldoc
+
+import (
+	"html"
+	"io"
+	"strings"
+)
+
+type NodeType int
+
+const (
+	DocumentNode NodeType = iota
+	ElementNode
+	TextNode
+	CommentNode
+)
+
+type Attr struct {
+	Key string
+	Val string
+}
+
+type Node struct {
+	Type     NodeType
+	Tag      string // lower-case element name
+	Attrs    []Attr
+	Text     string // decoded text for TextNode, raw text for CommentNode
+	Parent   *Node
+	Children []*Node
+}
+
+// Attr returns the value of the named attribute and whether it was present.
+func (n *Node) Attr(key string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// AttrOr returns the named attribute or "" when absent.
+func (n *Node) AttrOr(key string) string {
+	v, _ := n.Attr(key)
+	return v
+}
+
+func (n *Node) appendChild(c *Node) {
+	c.Parent = n
+	n.Children = append(n.Children, c)
+}
+
+// Remove detaches n from its parent.
+func (n *Node) Remove() {
+	p := n.Parent
+	if p == nil {
+		return
+	}
+	for i, c := range p.Children {
+		if c == n {
+			p.Children = append(p.Children[:i], p.Children[i+1:]...)
+			break
+		}
+	}
+	n.Parent = nil
+}
+
+// Walk visits n and its descendants depth-first. Returning false from fn
+// skips the node's children.
+func (n *Node) Walk(fn func(*Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, c := range append([]*Node(nil), n.Children...) {
+		c.Walk(fn)
+	}
+}
+
+// FindAll returns every descendant element with one of the given tags.
+func (n *Node) FindAll(tags ...string) []*Node {
+	var out []*Node
+	n.Walk(func(c *Node) bool {
+		if c != n && c.Type == ElementNode {
+			for _, t := range tags {
+				if c.Tag == t {
+					out = append(out, c)
+					break
+				}
+			}
+		}
+		return true
+	})
+	return out
+}
+
+// Find returns the first descendant element with the given tag, or nil.
+func (n *Node) Find(tag string) *Node {
+	if all := n.FindAll(tag); len(all) > 0 {
+		return all[0]
+	}
+	return nil
+}
+
+// InnerText concatenates descendant text, inserting line breaks around
+// block-level elements and collapsing runs of whitespace.
+func (n *Node) InnerText() string {
+	var b strings.Builder
+	var walk func(*Node)
+	walk = func(c *Node) {
+		switch c.Type {
+		case TextNode:
+			b.WriteString(c.Text)
+			return
+		case CommentNode:
+			return
+		}
+		if c.Type == ElementNode && (c.Tag == "script" || c.Tag == "style") {
+			return
+		}
+		block := c.Type == ElementNode && blockElements[c.Tag]
+		if block || c.Tag == "br" {
+			b.WriteByte('\n')
+		}
+		for _, ch := range c.Children {
+			walk(ch)
+		}
+		if block {
+			b.WriteByte('\n')
+		}
+	}
+	walk(n)
+	return CollapseWhitespace(b.String())
+}
+
+// CollapseWhitespace squeezes spaces and tabs within lines and drops empty
+// lines, keeping single line breaks between blocks.
+func CollapseWhitespace(s string) string {
+	lines := strings.Split(s, "\n")
+	out := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if l = strings.Join(strings.Fields(l), " "); l != "" {
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// rawTextElements hold unparsed text up to their closing tag.
+var rawTextElements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true, "noscript": true, "iframe": true,
+}
+
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "dd": true, "div": true,
+	"dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hr": true, "li": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"section": true, "table": true, "tr": true, "td": true, "th": true, "ul": true,
+}
+
+// autoClose lists open elements implicitly closed by a new start tag.
+var autoClose = map[string][]string{
+	"p":      {"p"},
+	"li":     {"li"},
+	"dt":     {"dt", "dd"},
+	"dd":     {"dt", "dd"},
+	"tr":     {"tr", "td", "th"},
+	"td":     {"td", "th"},
+	"th":     {"td", "th"},
+	"option": {"option"},
+}
+
+// Parse reads an HTML document. It never fails on malformed markup; the
+// error is only from reading r.
+func Parse(r io.Reader) (*Node, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseString(string(b)), nil
+}
+
+func ParseString(src string) *Node {
+	doc := &Node{Type: DocumentNode}
+	stack := []*Node{doc}
+	top := func() *Node { return stack[len(stack)-1] }
+	closeTo := func(tag string) bool {
+		for i := len(stack) - 1; i > 0; i-- {
+			if stack[i].Tag == tag {
+				stack = stack[:i]
+				return true
+			}
+		}
+		return false
+	}
+
+	i := 0
+	for i < len(src) {
+		lt := strings.IndexByte(src[i:], '<')
+		if lt < 0 {
+			top().appendChild(&Node{Type: TextNode, Text: html.UnescapeString(src[i:])})
+			break
+		}
+		if lt > 0 {
+			top().appendChild(&Node{Type: TextNode, Text: html.UnescapeString(src[i : i+lt])})
+		}
+		i += lt
+		rest := src[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				top().appendChild(&Node{Type: CommentNode, Text: rest[4:]})
+				i = len(src)
+				continue
+			}
+			top().appendChild(&Node{Type: CommentNode, Text: rest[4 : 4+end]})
+			i += 4 + end + 3
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				i = len(src)
+				continue
+			}
+			i += end + 1
+		case len(rest) > 2 && rest[1] == '/' && isLetter(rest[2]):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				i = len(src)
+				continue
+			}
+			tag := strings.ToLower(strings.TrimSpace(rest[2:end]))
+			if sp := strings.IndexAny(tag, " \t\r\n"); sp >= 0 {
+				tag = tag[:sp]
+			}
+			closeTo(tag)
+			i += end + 1
+		default:
+			tag, attrs, selfClose, n := parseStartTag(rest)
+			if n == 0 {
+				top().appendChild(&Node{Type: TextNode, Text: "<"})
+				i++
+				continue
+			}
+			i += n
+			for _, t := range autoClose[tag] {
+				if top().Tag == t {
+					stack = stack[:len(stack)-1]
+				}
+			}
+			if blockElements[tag] && top().Tag == "p" {
+				stack = stack[:len(stack)-1]
+			}
+			el := &Node{Type: ElementNode, Tag: tag, Attrs: attrs}
+			top().appendChild(el)
+			if voidElements[tag] || selfClose {
+				continue
+			}
+			if rawTextElements[tag] {
+				closing := "</" + tag
+				end := indexFold(src[i:], closing)
+				if end < 0 {
+					end = len(src) - i
+				}
+				text := src[i : i+end]
+				if tag == "title" || tag == "textarea" {
+					text = html.UnescapeString(text)
+				}
+				if text != "" {
+					el.appendChild(&Node{Type: TextNode, Text: text})
+				}
+				i += end
+				if gt := strings.IndexByte(src[i:], '>'); gt >= 0 {
+					i += gt + 1
+				}
+				continue
+			}
+			stack = append(stack, el)
+		}
+	}
+	return doc
+}
+
+// parseStartTag parses "<tag attr=val ...>" at the start of s and returns
+// the number of bytes consumed, or 0 if s does not start a tag.
+func parseStartTag(s string) (string, []Attr, bool, int) {
+	i := 1
+	start := i
+	if i >= len(s) || !isLetter(s[i]) {
+		return "", nil, false, 0
+	}
+	for i < len(s) && isNameByte(s[i]) {
+		i++
+	}
+	tag := strings.ToLower(s[start:i])
+	var attrs []Attr
+	selfClose := false
+	for i < len(s) {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		if s[i] == '>' {
+			return tag, attrs, selfClose, i + 1
+		}
+		if s[i] == '/' {
+			selfClose = true
+			i++
+			continue
+		}
+		ks := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		key := strings.ToLower(s[ks:i])
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		val := ""
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				q := s[i]
+				end := strings.IndexByte(s[i+1:], q)
+				if end < 0 {
+					val = s[i+1:]
+					i = len(s)
+				} else {
+					val = s[i+1 : i+1+end]
+					i += end + 2
+				}
+			} else {
+				vs := i
+				for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				val = s[vs:i]
+			}
+		}
+		if key != "" {
+			attrs = append(attrs, Attr{Key: key, Val: html.UnescapeString(val)})
+		}
+		selfClose = false
+	}
+	return tag, attrs, selfClose, len(s)
+}
+
+// isLetter reports whether c may start a tag name; after anything else a
+// '<' is just text.
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func isNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == ':' || c == '_'
+}
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' }
+
+// indexFold finds substr, which must start with '<', in s ignoring ASCII
+// case.
+func indexFold(s, substr string) int {
+	for j := 0; j+len(substr) <= len(s); {
+		k := strings.IndexByte(s[j:], '<')
+		if k < 0 || j+k+len(substr) > len(s) {
+			return -1
+		}
+		j += k
+		if strings.EqualFold(s[j:j+len(substr)], substr) {
+			return j
+		}
+		j++
+	}
+	return -1
+}
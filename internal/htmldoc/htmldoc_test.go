@@ -0,0 +1,45 @@
+package htmldoc
+
+import "testing"
+
+func TestParseString(t *testing.T) {
+	doc := ParseString(`<!doctype html><html><head><title>A &amp; B</title>
+<script>if (a < b) { document.write("</div>") }</SCRIPT></head>
+<body><div id=main class='x'><p>one<p>two &lt;3<br/>lines<div>block</div>
+<img src="a.png" alt="pic"><a href="/x?a=1&amp;b=2">link</a></div><!-- note --></body></html>`)
+
+	if got := doc.Find("title").InnerText(); got != "A & B" {
+		t.Errorf("title = %q", got)
+	}
+	if ps := doc.FindAll("p"); len(ps) != 2 || ps[1].InnerText() != "two <3\nlines" {
+		t.Fatalf("paragraphs = %d, second %q", len(ps), ps[len(ps)-1].InnerText())
+	}
+	div := doc.Find("div")
+	if div.AttrOr("id") != "main" || div.AttrOr("class") != "x" {
+		t.Errorf("attrs = %+v", div.Attrs)
+	}
+	if a := doc.Find("a"); a == nil || a.AttrOr("href") != "/x?a=1&b=2" || a.Parent != div {
+		t.Errorf("link parsed wrong: %+v", a)
+	}
+	if img := doc.Find("img"); img == nil || len(img.Children) != 0 {
+		t.Errorf("img should be a void element")
+	}
+	if s := doc.Find("script"); s == nil || len(s.Children) != 1 || doc.FindAll("div")[1].InnerText() != "block" {
+		t.Errorf("script raw text leaked into the tree")
+	}
+}
+
+func TestParseStringStrayLessThan(t *testing.T) {
+	for in, want := range map[string]string{
+		"<":                   "<",
+		"up <5% today":        "up <5% today",
+		"a<b":                 "a",
+		"a < b":               "a < b",
+		"x </5 y":             "x </5 y",
+		"1<2 and <b>bold</b>": "1<2 and bold",
+	} {
+		if got := ParseString(in).InnerText(); got != want {
+			t.Errorf("InnerText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package htmldoc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Selector is a compiled CSS selector. Supported: type, universal, #id,
+// .class, attribute selectors ([a], [a=v], ~=, |=, ^=, $=, *=), the
+// descendant and child (>) combinators, selector lists (a, b) and the
+// pseudo-classes :first-child, :last-child and :nth-child(N).
+type Selector struct {
+	src    string
+	groups [][]compound
+}
+
+type compound struct {
+	comb    byte // combinator linking to the previous compound: ' ' or '>'
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrMatch
+	nth     int // 1-based position among element siblings; -1 means last
+}
+
+type attrMatch struct {
+	key, op, val string
+}
+
+// Compile parses a selector.
+func Compile(sel string) (*Selector, error) {
+	p := &selParser{s: sel}
+	s := &Selector{src: sel}
+	for {
+		p.skipSpace()
+		parts, err := p.complex()
+		if err != nil {
+			return nil, fmt.Errorf("selector %q: %w", sel, err)
+		}
+		s.groups = append(s.groups, parts)
+		p.skipSpace()
+		if p.eof() {
+			return s, nil
+		}
+		if p.s[p.i] != ',' {
+			return nil, fmt.Errorf("selector %q: unexpected %q at %d", sel, p.s[p.i], p.i)
+		}
+		p.i++
+	}
+}
+
+func (s *Selector) String() string { return s.src }
+
+// Match reports whether element n matches s.
+func (s *Selector) Match(n *Node) bool {
+	if n == nil || n.Type != ElementNode {
+		return false
+	}
+	for _, g := range s.groups {
+		if matchComplex(g, len(g)-1, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the descendants of n that match s, in document order.
+func (n *Node) Select(s *Selector) []*Node {
+	var out []*Node
+	n.Walk(func(c *Node) bool {
+		if c != n && s.Match(c) {
+			out = append(out, c)
+		}
+		return true
+	})
+	return out
+}
+
+// SelectFirst returns the first descendant of n that matches s, or nil.
+func (n *Node) SelectFirst(s *Selector) *Node {
+	if all := n.Select(s); len(all) > 0 {
+		return all[0]
+	}
+	return nil
+}
+
+func matchComplex(parts []compound, i int, n *Node) bool {
+	if !parts[i].match(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	if parts[i].comb == '>' {
+		p := n.Parent
+		return p != nil && p.Type == ElementNode && matchComplex(parts, i-1, p)
+	}
+	for p := n.Parent; p != nil && p.Type == ElementNode; p = p.Parent {
+		if matchComplex(parts, i-1, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c compound) match(n *Node) bool {
+	if c.tag != "" && c.tag != "*" && c.tag != n.Tag {
+		return false
+	}
+	if c.id != "" && n.AttrOr("id") != c.id {
+		return false
+	}
+	for _, cl := range c.classes {
+		if !containsField(n.AttrOr("class"), cl) {
+			return false
+		}
+	}
+	for _, a := range c.attrs {
+		v, ok := n.Attr(a.key)
+		if !ok || !a.match(v) {
+			return false
+		}
+	}
+	if c.nth != 0 {
+		pos, last := siblingPosition(n)
+		if c.nth == -1 && pos != last || c.nth > 0 && pos != c.nth {
+			return false
+		}
+	}
+	return true
+}
+
+func (a attrMatch) match(v string) bool {
+	switch a.op {
+	case "":
+		return true
+	case "=":
+		return v == a.val
+	case "~=":
+		return containsField(v, a.val)
+	case "|=":
+		return v == a.val || strings.HasPrefix(v, a.val+"-")
+	case "^=":
+		return a.val != "" && strings.HasPrefix(v, a.val)
+	case "$=":
+		return a.val != "" && strings.HasSuffix(v, a.val)
+	case "*=":
+		return a.val != "" && strings.Contains(v, a.val)
+	}
+	return false
+}
+
+func containsField(list, v string) bool {
+	for _, f := range strings.Fields(list) {
+		if f == v {
+			return true
+		}
+	}
+	return false
+}
+
+// siblingPosition returns n's 1-based index among its parent's element
+// children and the number of such children.
+func siblingPosition(n *Node) (int, int) {
+	if n.Parent == nil {
+		return 1, 1
+	}
+	pos, count := 0, 0
+	for _, c := range n.Parent.Children {
+		if c.Type != ElementNode {
+			continue
+		}
+		count++
+		if c == n {
+			pos = count
+		}
+	}
+	return pos, count
+}
+
+type selParser struct {
+	s string
+	i int
+}
+
+func (p *selParser) eof() bool { return p.i >= len(p.s) }
+
+func (p *selParser) skipSpace() bool {
+	start := p.i
+	for !p.eof() && isSpace(p.s[p.i]) {
+		p.i++
+	}
+	return p.i > start
+}
+
+func (p *selParser) complex() ([]compound, error) {
+	var parts []compound
+	comb := byte(0)
+	for {
+		c, err := p.compound()
+		if err != nil {
+			return nil, err
+		}
+		c.comb = comb
+		parts = append(parts, c)
+		spaced := p.skipSpace()
+		if p.eof() || p.s[p.i] == ',' {
+			return parts, nil
+		}
+		switch p.s[p.i] {
+		case '>':
+			p.i++
+			p.skipSpace()
+			comb = '>'
+		case '+', '~':
+			return nil, fmt.Errorf("combinator %q is not supported", p.s[p.i])
+		default:
+			if !spaced {
+				return nil, fmt.Errorf("unexpected %q at %d", p.s[p.i], p.i)
+			}
+			comb = ' '
+		}
+	}
+}
+
+func (p *selParser) compound() (compound, error) {
+	var c compound
+	start := p.i
+	if !p.eof() && p.s[p.i] == '*' {
+		c.tag = "*"
+		p.i++
+	} else if name := p.ident(); name != "" {
+		c.tag = strings.ToLower(name)
+	}
+	for !p.eof() {
+		switch p.s[p.i] {
+		case '#':
+			p.i++
+			if c.id = p.ident(); c.id == "" {
+				return c, fmt.Errorf("empty id at %d", p.i)
+			}
+		case '.':
+			p.i++
+			cl := p.ident()
+			if cl == "" {
+				return c, fmt.Errorf("empty class at %d", p.i)
+			}
+			c.classes = append(c.classes, cl)
+		case '[':
+			a, err := p.attr()
+			if err != nil {
+				return c, err
+			}
+			c.attrs = append(c.attrs, a)
+		case ':':
+			if err := p.pseudo(&c); err != nil {
+				return c, err
+			}
+		default:
+			if p.i == start {
+				return c, fmt.Errorf("expected a selector at %d", p.i)
+			}
+			return c, nil
+		}
+	}
+	if p.i == start {
+		return c, fmt.Errorf("expected a selector at %d", p.i)
+	}
+	return c, nil
+}
+
+func (p *selParser) ident() string {
+	start := p.i
+	for !p.eof() {
+		ch := p.s[p.i]
+		if ch == '-' || ch == '_' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= 0x80 {
+			p.i++
+			continue
+		}
+		if ch == '\\' && p.i+1 < len(p.s) {
+			p.i += 2
+			continue
+		}
+		break
+	}
+	return strings.ReplaceAll(p.s[start:p.i], "\\", "")
+}
+
+func (p *selParser) attr() (attrMatch, error) {
+	p.i++ // '['
+	p.skipSpace()
+	var a attrMatch
+	if a.key = strings.ToLower(p.ident()); a.key == "" {
+		return a, fmt.Errorf("empty attribute name at %d", p.i)
+	}
+	p.skipSpace()
+	if p.eof() {
+		return a, fmt.Errorf("unterminated attribute selector")
+	}
+	if p.s[p.i] == ']' {
+		p.i++
+		return a, nil
+	}
+	for _, op := range []string{"=", "~=", "|=", "^=", "$=", "*="} {
+		if strings.HasPrefix(p.s[p.i:], op) {
+			a.op = op
+			p.i += len(op)
+			break
+		}
+	}
+	if a.op == "" {
+		return a, fmt.Errorf("unexpected %q in attribute selector", p.s[p.i])
+	}
+	p.skipSpace()
+	if !p.eof() && (p.s[p.i] == '"' || p.s[p.i] == '\'') {
+		q := p.s[p.i]
+		end := strings.IndexByte(p.s[p.i+1:], q)
+		if end < 0 {
+			return a, fmt.Errorf("unterminated string")
+		}
+		a.val = p.s[p.i+1 : p.i+1+end]
+		p.i += end + 2
+	} else {
+		a.val = p.ident()
+	}
+	p.skipSpace()
+	if p.eof() || p.s[p.i] != ']' {
+		return a, fmt.Errorf("unterminated attribute selector")
+	}
+	p.i++
+	return a, nil
+}
+
+func (p *selParser) pseudo(c *compound) error {
+	p.i++ // ':'
+	name := strings.ToLower(p.ident())
+	switch name {
+	case "first-child":
+		c.nth = 1
+	case "last-child":
+		c.nth = -1
+	case "nth-child":
+		if p.eof() || p.s[p.i] != '(' {
+			return fmt.Errorf(":nth-child needs an argument")
+		}
+		end := strings.IndexByte(p.s[p.i:], ')')
+		if end < 0 {
+			return fmt.Errorf("unterminated :nth-child")
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(p.s[p.i+1 : p.i+end]))
+		if err != nil || n < 1 {
+			return fmt.Errorf(":nth-child supports only a positive integer")
+		}
+		c.nth = n
+		p.i += end + 1
+	default:
+		return fmt.Errorf("pseudo-class :%s is not supported", name)
+	}
+	return nil
+}
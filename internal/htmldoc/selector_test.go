@@ -0,0 +1,59 @@
+package htmldoc
+
+import (
+	"strings"
+	"testing"
+)
+
+const selectorPage = `<html><body>
+<ul id="news" class="list main">
+  <li class="item"><a href="/1" data-kind="press-release">One</a><time datetime="2024-05-01">May 1</time></li>
+  <li class="item featured"><a href="/2" lang="en-GB">Two</a></li>
+  <li class="ad"><a href="https://ads.example/x">Ad</a></li>
+</ul>
+<div><p><a href="/deep">Deep</a></p></div>
+</body></html>`
+
+func TestSelect(t *testing.T) {
+	doc := ParseString(selectorPage)
+	cases := []struct {
+		sel  string
+		want string
+	}{
+		{"li.item a", "One|Two"},
+		{"#news > li > a", "One|Two|Ad"},
+		{"ul.main.list li.featured a", "Two"},
+		{"body > a", ""},
+		{"div a", "Deep"},
+		{`a[href^="https://"]`, "Ad"},
+		{"a[data-kind|=press]", "One"},
+		{"a[lang|=en]", "Two"},
+		{"a[href$='2'], a[href*=deep]", "Two|Deep"},
+		{"li:first-child a", "One"},
+		{"li:last-child > *", "Ad"},
+		{"li:nth-child(2) a", "Two"},
+		{"* > time[datetime]", "May 1"},
+	}
+	for _, c := range cases {
+		sel, err := Compile(c.sel)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", c.sel, err)
+			continue
+		}
+		var got []string
+		for _, n := range doc.Select(sel) {
+			got = append(got, n.InnerText())
+		}
+		if strings.Join(got, "|") != c.want {
+			t.Errorf("%q selected %q, want %q", c.sel, strings.Join(got, "|"), c.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, sel := range []string{"", "a >", "a + b", "li:hover", "[href", ".", "a,,b"} {
+		if _, err := Compile(sel); err == nil {
+			t.Errorf("Compile(%q) should fail", sel)
+		}
+	}
+}
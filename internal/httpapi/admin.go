@@ -0,0 +1,118 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"news-go/internal/retention"
+	"news-go/internal/storage"
+)
+
+// CrawlTrigger enqueues an immediate crawl. An empty sourceID means every
+// enabled source; an unknown one returns storage.ErrNotFound.
+type CrawlTrigger interface {
+	TriggerCrawl(ctx context.Context, sourceID string) error
+}
+
+func (h *Handler) triggerCrawl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var body struct {
+		Source string `json:"source"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	sourceID := strings.ToLower(strings.TrimSpace(body.Source))
+	if q := strings.TrimSpace(r.URL.Query().Get("source")); q != "" {
+		sourceID = strings.ToLower(q)
+	}
+	if err := h.crawler.TriggerCrawl(r.Context(), sourceID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "source not found"})
+			return
+		}
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	}
+	scope := sourceID
+	if scope == "" {
+		scope = "all"
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued", "source": scope})
+}
+
+func (h *Handler) listCrawlRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	limit := clamp(parseIntOrDefault(r.URL.Query().Get("limit"), 20), 1, 100)
+	opts := storage.CrawlRunListOptions{
+		Limit:    limit,
+		SourceID: strings.ToLower(strings.TrimSpace(r.URL.Query().Get("source"))),
+	}
+	runs, err := h.runs.ListCrawlRuns(r.Context(), opts)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list crawl runs"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": runs, "limit": limit})
+}
+
+func (h *Handler) listSourceFiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	limit := clamp(parseIntOrDefault(r.URL.Query().Get("limit"), 50), 1, 500)
+	source := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("source")))
+	files, err := h.files.ListSourceFiles(r.Context(), source, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list source files"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": files, "limit": limit})
+}
+
+// Pruner applies the article retention policy; a dry run only reports
+// what would be removed.
+type Pruner interface {
+	Prune(ctx context.Context, dryRun bool) (retention.Report, error)
+}
+
+// prune previews the retention policy on GET and applies it on POST,
+// unless ?dry_run=true.
+func (h *Handler) prune(w http.ResponseWriter, r *http.Request) {
+	var dryRun bool
+	switch r.Method {
+	case http.MethodGet:
+		dryRun = true
+	case http.MethodPost:
+		if v := r.URL.Query().Get("dry_run"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid dry_run, expected true or false"})
+				return
+			}
+			dryRun = b
+		}
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+		return
+	}
+	rep, err := h.pruner.Prune(r.Context(), dryRun)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "failed to prune articles", "report": rep})
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
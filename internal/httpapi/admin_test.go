@@ -0,0 +1,124 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"news-go/internal/news"
+	"news-go/internal/retention"
+	"news-go/internal/storage"
+)
+
+type stubTrigger struct {
+	requested []string
+}
+
+func (s *stubTrigger) TriggerCrawl(_ context.Context, sourceID string) error {
+	if sourceID == "missing" {
+		return storage.ErrNotFound
+	}
+	s.requested = append(s.requested, sourceID)
+	return nil
+}
+
+func TestTriggerCrawl(t *testing.T) {
+	trigger := &stubTrigger{}
+	h := NewHandler(stubRepo{}, WithCrawler(trigger, storage.NewMemoryCrawlRunRepository()))
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/admin/crawl", nil))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/admin/crawl", strings.NewReader(`{"source":"bbc"}`)))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rr.Code)
+	}
+	if len(trigger.requested) != 2 || trigger.requested[0] != "" || trigger.requested[1] != "bbc" {
+		t.Fatalf("unexpected trigger calls: %v", trigger.requested)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/admin/crawl?source=missing", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/admin/crawl", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rr.Code)
+	}
+}
+
+func TestListCrawlRuns(t *testing.T) {
+	runs := storage.NewMemoryCrawlRunRepository()
+	ctx := context.Background()
+	for _, id := range []string{"bbc", "reuters", "bbc"} {
+		if _, err := runs.CreateCrawlRun(ctx, news.CrawlRun{SourceID: id, Status: news.CrawlRunOK, StartedAt: time.Now().UTC()}); err != nil {
+			t.Fatalf("create run: %v", err)
+		}
+	}
+	h := NewHandler(stubRepo{}, WithCrawler(&stubTrigger{}, runs))
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/admin/crawl-runs?source=bbc", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var body struct {
+		Items []news.CrawlRun `json:"items"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || len(body.Items) != 2 {
+		t.Fatalf("unexpected response: %v len=%d", err, len(body.Items))
+	}
+	if body.Items[0].ID != 3 {
+		t.Fatalf("expected newest run first, got id=%d", body.Items[0].ID)
+	}
+}
+
+type stubPruner struct {
+	calls []bool
+}
+
+func (s *stubPruner) Prune(_ context.Context, dryRun bool) (retention.Report, error) {
+	s.calls = append(s.calls, dryRun)
+	return retention.Report{DryRun: dryRun, Articles: 3, BySource: map[string]int{"bbc": 3}}, nil
+}
+
+func TestPrune(t *testing.T) {
+	pruner := &stubPruner{}
+	h := NewHandler(stubRepo{}, WithPruner(pruner))
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	for _, tc := range []struct {
+		method, path string
+		code         int
+	}{
+		{http.MethodGet, "/v1/admin/prune", http.StatusOK},
+		{http.MethodPost, "/v1/admin/prune?dry_run=true", http.StatusOK},
+		{http.MethodPost, "/v1/admin/prune", http.StatusOK},
+		{http.MethodPost, "/v1/admin/prune?dry_run=maybe", http.StatusBadRequest},
+		{http.MethodDelete, "/v1/admin/prune", http.StatusMethodNotAllowed},
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, nil))
+		if rr.Code != tc.code {
+			t.Fatalf("%s %s: expected %d, got %d", tc.method, tc.path, tc.code, rr.Code)
+		}
+	}
+	if len(pruner.calls) != 3 || !pruner.calls[0] || !pruner.calls[1] || pruner.calls[2] {
+		t.Fatalf("dry run flags = %v", pruner.calls)
+	}
+}
@@ -8,14 +8,85 @@ import (
 	"strings"
 	"time"
 
+	"news-go/internal/crawler"
+	"news-go/internal/health"
+	"news-go/internal/news"
 	"news-go/internal/storage"
+	"news-go/internal/websub"
 )
 
 type Handler struct {
-	repo storage.ArticleRepository
+	repo    storage.ArticleRepository
+	sources storage.SourceRepository
+	crawler CrawlTrigger
+	runs    storage.CrawlRunRepository
+	health  storage.SourceHealthRepository
+	policy  health.Policy
+	finder  FeedDiscoverer
+	websub  WebSubCallback
+	files   storage.SourceFileRepository
+	revs    storage.ArticleRevisionRepository
+	pruner  Pruner
+	local   crawler.LocalAccess
 }
 
-func NewHandler(repo storage.ArticleRepository) *Handler { return &Handler{repo: repo} }
+// Option wires an optional dependency into the Handler. Routes backed by a
+// dependency are only registered when it is provided.
+type Option func(*Handler)
+
+func WithSources(repo storage.SourceRepository) Option {
+	return func(h *Handler) { h.sources = repo }
+}
+
+// WithLocalAccess lets created and updated sources use local files, CA
+// bundles and secrets within a; without it they may use none.
+func WithLocalAccess(a crawler.LocalAccess) Option {
+	return func(h *Handler) { h.local = a }
+}
+
+// WithDiscovery enables POST /v1/sources/discover.
+func WithDiscovery(d FeedDiscoverer) Option {
+	return func(h *Handler) { h.finder = d }
+}
+
+// WithWebSub enables the WebSub callback under /v1/websub/callback/.
+func WithWebSub(cb WebSubCallback) Option {
+	return func(h *Handler) { h.websub = cb }
+}
+
+// WithSourceFiles enables /v1/admin/source-files, the ledger of files
+// read by directory file sources.
+func WithSourceFiles(repo storage.SourceFileRepository) Option {
+	return func(h *Handler) { h.files = repo }
+}
+
+// WithRevisions enables GET /v1/articles/{id}/revisions.
+func WithRevisions(repo storage.ArticleRevisionRepository) Option {
+	return func(h *Handler) { h.revs = repo }
+}
+
+// WithPruner enables /v1/admin/prune.
+func WithPruner(p Pruner) Option {
+	return func(h *Handler) { h.pruner = p }
+}
+
+func WithCrawler(trigger CrawlTrigger, runs storage.CrawlRunRepository) Option {
+	return func(h *Handler) { h.crawler, h.runs = trigger, runs }
+}
+
+// WithSourceHealth enables /v1/sources/health and lets /readyz degrade when
+// policy.MaxStaleRatio is set. It needs WithSources as well.
+func WithSourceHealth(repo storage.SourceHealthRepository, policy health.Policy) Option {
+	return func(h *Handler) { h.health, h.policy = repo, policy }
+}
+
+func NewHandler(repo storage.ArticleRepository, opts ...Option) *Handler {
+	h := &Handler{repo: repo}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
 
 func (h *Handler) Register(mux *http.ServeMux) {
 	mux.HandleFunc("/healthz", h.healthz)
@@ -23,6 +94,31 @@ func (h *Handler) Register(mux *http.ServeMux) {
 	mux.HandleFunc("/v1/articles", h.listArticles)
 	mux.HandleFunc("/v1/articles/", h.getArticleByID)
 	mux.HandleFunc("/v1/digest", h.dailyDigest)
+	if h.sources != nil {
+		mux.HandleFunc("/v1/sources", h.sourcesCollection)
+		mux.HandleFunc("/v1/sources/", h.sourceItem)
+		mux.HandleFunc("/v1/sources/import", h.importSources)
+		mux.HandleFunc("/v1/sources/export.opml", h.exportSources)
+		if h.health != nil {
+			mux.HandleFunc("/v1/sources/health", h.sourcesHealth)
+		}
+	}
+	if h.finder != nil {
+		mux.HandleFunc("/v1/sources/discover", h.discoverSources)
+	}
+	if h.websub != nil {
+		mux.HandleFunc(websub.CallbackPath, h.websubCallback)
+	}
+	if h.crawler != nil {
+		mux.HandleFunc("/v1/admin/crawl", h.triggerCrawl)
+		mux.HandleFunc("/v1/admin/crawl-runs", h.listCrawlRuns)
+	}
+	if h.files != nil {
+		mux.HandleFunc("/v1/admin/source-files", h.listSourceFiles)
+	}
+	if h.pruner != nil {
+		mux.HandleFunc("/v1/admin/prune", h.prune)
+	}
 	mux.HandleFunc("/", h.home)
 }
 
@@ -48,6 +144,9 @@ const homeHTML = `<!doctype html>
     .hint { color: #666; margin-bottom: 16px; }
     .toolbar { display: flex; gap: 8px; margin-bottom: 14px; }
     input { flex: 1; padding: 10px; border: 1px solid #ddd; border-radius: 8px; }
+    select { padding: 10px; border: 1px solid #ddd; border-radius: 8px; background: #fff; }
+    audio, video { display: block; width: 100%; margin-top: 8px; }
+    video { max-height: 360px; background: #000; }
     button { padding: 10px 14px; border: 0; border-radius: 8px; background: #111827; color: #fff; cursor: pointer; }
     .card { background: white; border: 1px solid #e5e7eb; border-radius: 10px; padding: 12px 14px; margin-bottom: 10px; }
     .meta { color: #6b7280; font-size: 12px; margin-bottom: 6px; }
@@ -63,6 +162,11 @@ const homeHTML = `<!doctype html>
     <p class="hint">优先展示策略版每日摘要（/v1/digest）。若尚未生成，则回退展示普通新闻列表。</p>
     <div class="toolbar">
       <input id="q" placeholder="输入关键词，比如 AI" />
+      <select id="media" onchange="loadArticles()">
+        <option value="">全部</option>
+        <option value="audio">音频</option>
+        <option value="video">视频</option>
+      </select>
       <button onclick="loadArticles()">刷新</button>
     </div>
     <div id="status" class="hint">加载中...</div>
@@ -70,6 +174,42 @@ const homeHTML = `<!doctype html>
     <div id="list"></div>
   </div>
 <script>
+// Everything from the API is inserted as text or escaped; only the
+// sanitised article content could carry markup and it is not rendered here.
+function escapeHTML(v) {
+  return String(v === undefined || v === null ? '' : v)
+    .replace(/&/g, '&amp;')
+    .replace(/</g, '&lt;')
+    .replace(/>/g, '&gt;')
+    .replace(/"/g, '&quot;')
+    .replace(/'/g, '&#39;');
+}
+
+function safeHref(v) {
+  return /^https?:\/\//i.test(v || '') ? escapeHTML(v) : '#';
+}
+
+function formatDuration(sec) {
+  if (!sec) {
+    return '';
+  }
+  var h = Math.floor(sec / 3600), m = Math.floor(sec % 3600 / 60), s = sec % 60;
+  var mm = (h && m < 10 ? '0' : '') + m, ss = (s < 10 ? '0' : '') + s;
+  return (h ? h + ':' : '') + mm + ':' + ss;
+}
+
+// renderMedia shows a player for the first audio or video attachment.
+function renderMedia(media) {
+  var m = (media || []).filter(function (x) { return /^https?:\/\//i.test(x.url || ''); })[0];
+  if (!m) {
+    return '';
+  }
+  var tag = m.medium === 'video' ? 'video' : 'audio';
+  var label = (tag === 'video' ? '视频' : '音频') + (m.duration_sec ? ' · ' + formatDuration(m.duration_sec) : '');
+  return '<div class="meta">' + escapeHTML(label) + '</div>'
+    + '<' + tag + ' controls preload="none" src="' + safeHref(m.url) + '"></' + tag + '>';
+}
+
 function renderScoreboard(data, scoreboard) {
   if (!data || !data.scores) {
     return;
@@ -87,30 +227,14 @@ function renderScoreboard(data, scoreboard) {
     var ratio = (m.research_ratio !== undefined && m.research_ratio !== null) ? m.research_ratio : 0;
     var coverage = (m.topic_coverage !== undefined && m.topic_coverage !== null) ? m.topic_coverage : 0;
     return '<tr>'
-      + '<td>' + sid + '</td>'
-      + '<td>' + score + '</td>'
-      + '<td>' + slot + '</td>'
-      + '<td>' + volume + '</td>'
-      + '<td>' + ratio + '</td>'
-      + '<td>' + coverage + '</td>'
+      + '<td>' + escapeHTML(sid) + '</td>'
+      + '<td>' + escapeHTML(score) + '</td>'
+      + '<td>' + escapeHTML(slot) + '</td>'
+      + '<td>' + escapeHTML(volume) + '</td>'
+      + '<td>' + escapeHTML(ratio) + '</td>'
+      + '<td>' + escapeHTML(coverage) + '</td>'
       + '</tr>';
   }).join('');
-
-  const metrics = data.metrics || {};
-  const slots = data.slots || {};
-  const rows = Object.keys(data.scores)
-    .sort((a, b) => (data.scores[b] || 0) - (data.scores[a] || 0))
-    .map((sid) => {
-      const m = metrics[sid] || {};
-      return '<tr>'
-        + '<td>' + sid + '</td>'
-        + '<td>' + (data.scores[sid] ?? 0) + '</td>'
-        + '<td>' + (slots[sid] ?? 0) + '</td>'
-        + '<td>' + (m.weekly_volume ?? 0) + '</td>'
-        + '<td>' + (m.research_ratio ?? 0) + '</td>'
-        + '<td>' + (m.topic_coverage ?? 0) + '</td>'
-        + '</tr>';
-    }).join('');
   scoreboard.innerHTML = '<table>'
     + '<thead><tr><th>来源</th><th>评分</th><th>今日配额</th><th>周产量</th><th>研究占比</th><th>覆盖度</th></tr></thead>'
     + '<tbody>' + rows + '</tbody></table>';
@@ -118,8 +242,10 @@ function renderScoreboard(data, scoreboard) {
 
 function loadArticles() {
   var q = document.getElementById('q').value.trim();
+  var media = document.getElementById('media').value;
   var digestURL = '/v1/digest';
-  var url = '/v1/articles?limit=20&offset=0' + (q ? ('&q=' + encodeURIComponent(q)) : '');
+  var url = '/v1/articles?limit=20&offset=0' + (q ? ('&q=' + encodeURIComponent(q)) : '')
+    + (media ? ('&has_media=' + encodeURIComponent(media)) : '');
   var status = document.getElementById('status');
   var list = document.getElementById('list');
   var scoreboard = document.getElementById('scoreboard');
@@ -134,50 +260,19 @@ function loadArticles() {
   function renderList() {
     if (!items.length) {
       var notes = (data && data.notes) ? data.notes.join('；') : '';
-      list.innerHTML = '<div class="card">暂无可展示新闻。' + (notes ? ('<br/>' + notes) : '（可能是 RSS 源暂时不可访问）') + '</div>';
-    <div id="list"></div>
-  </div>
-<script>
-async function loadArticles() {
-  const q = document.getElementById('q').value.trim();
-  const digestURL = '/v1/digest';
-  const url = '/v1/articles?limit=20&offset=0' + (q ? ('&q=' + encodeURIComponent(q)) : '');
-  const status = document.getElementById('status');
-  const list = document.getElementById('list');
-  const scoreboard = document.getElementById('scoreboard');
-  status.textContent = '加载中...';
-  list.innerHTML = '';
-  scoreboard.innerHTML = '';
-  status.textContent = '加载中...';
-  list.innerHTML = '';
-  try {
-    let data = null;
-    let items = [];
-    if (!q) {
-      const digestRes = await fetch(digestURL);
-      if (digestRes.ok) {
-        data = await digestRes.json();
-        items = data.items || [];
-        status.textContent = '策略摘要，共 ' + items.length + ' 条';
-        renderScoreboard(data, scoreboard);
-      }
-    }
-    if (!items.length) {
-      const res = await fetch(url);
-      data = await res.json();
-      items = data.items || [];
-      status.textContent = '普通列表，共 ' + items.length + ' 条';
-    }
-    if (!items.length) {
-      const notes = (data && data.notes) ? data.notes.join('；') : '';
-      list.innerHTML = '<div class="card">暂无可展示新闻。' + (notes ? ('<br/>' + notes) : '（可能是 RSS 源暂时不可访问）') + '</div>';
-      list.innerHTML = '<div class="card">暂无数据（可能是 RSS 源暂时不可访问）</div>';
+      list.innerHTML = '<div class="card">暂无可展示新闻。' + (notes ? ('<br/>' + escapeHTML(notes)) : '（可能是 RSS 源暂时不可访问）') + '</div>';
       return;
     }
     list.innerHTML = items.map(function (x) {
+      var summary = x.content_text || '';
+      if (summary.length > 160) {
+        summary = summary.slice(0, 160) + '…';
+      }
       return '<div class="card">'
-        + '<div class="meta">#' + (x.id || '-') + ' · ' + (x.source || 'rss') + ' · ' + (x.published_at || '') + '</div>'
-        + '<div><a href="' + (x.url || '#') + '" target="_blank">' + (x.title || '(无标题)') + '</a></div>'
+        + '<div class="meta">#' + escapeHTML(x.id || '-') + ' · ' + escapeHTML(x.source || 'rss') + ' · ' + escapeHTML(x.published_at || '') + '</div>'
+        + '<div><a href="' + safeHref(x.url) + '" target="_blank" rel="noopener">' + escapeHTML(x.title || '(无标题)') + '</a></div>'
+        + (summary ? '<div class="hint">' + escapeHTML(summary) + '</div>' : '')
+        + renderMedia(x.media)
         + '</div>';
     }).join('');
   }
@@ -197,7 +292,7 @@ async function loadArticles() {
       });
   }
 
-  if (!q) {
+  if (!q && !media) {
     fetch(digestURL)
       .then(function (res) {
         if (!res.ok) {
@@ -224,12 +319,6 @@ async function loadArticles() {
 
   loadArticlesFallback();
 }
-
-  } catch (e) {
-    status.textContent = '加载失败';
-    list.innerHTML = '<div class="card">请求失败，请检查服务日志。</div>';
-  }
-}
 loadArticles();
 </script>
 </body>
@@ -260,6 +349,17 @@ func (h *Handler) readyz(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "not_ready", "error": err.Error()})
 		return
 	}
+	if h.sources != nil && h.health != nil && h.policy.MaxStaleRatio > 0 {
+		rep, err := h.sourceHealthReport(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "not_ready", "error": err.Error()})
+			return
+		}
+		if rep.Degraded {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "degraded", "stale": rep.Stale, "enabled": rep.Enabled, "stale_ratio": rep.StaleRatio})
+			return
+		}
+	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
 }
 
@@ -271,10 +371,19 @@ func (h *Handler) listArticles(w http.ResponseWriter, r *http.Request) {
 	}
 
 	opts := storage.ListOptions{
-		Limit:   limit,
-		Offset:  offset,
-		Keyword: strings.TrimSpace(r.URL.Query().Get("q")),
-		Source:  strings.TrimSpace(r.URL.Query().Get("source")),
+		Limit:    limit,
+		Offset:   offset,
+		Keyword:  strings.TrimSpace(r.URL.Query().Get("q")),
+		Source:   strings.TrimSpace(r.URL.Query().Get("source")),
+		Author:   strings.TrimSpace(r.URL.Query().Get("author")),
+		Language: strings.TrimSpace(r.URL.Query().Get("lang")),
+	}
+	switch m := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("has_media"))); m {
+	case "", news.MediumAudio, news.MediumVideo, "any":
+		opts.HasMedia = m
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid has_media, expected audio, video or any"})
+		return
 	}
 	if from := strings.TrimSpace(r.URL.Query().Get("from")); from != "" {
 		t, err := parseRFC3339Param(from)
@@ -306,7 +415,11 @@ func (h *Handler) listArticles(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) getArticleByID(w http.ResponseWriter, r *http.Request) {
-	idStr := strings.TrimPrefix(r.URL.Path, "/v1/articles/")
+	idStr, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/articles/"), "/")
+	if sub != "" && (sub != "revisions" || h.revs == nil) {
+		http.NotFound(w, r)
+		return
+	}
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid article id"})
@@ -321,6 +434,10 @@ func (h *Handler) getArticleByID(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to get article"})
 		return
 	}
+	if sub == "revisions" {
+		h.articleRevisions(w, r, article)
+		return
+	}
 	writeJSON(w, http.StatusOK, article)
 }
 
@@ -349,6 +466,11 @@ func parseRFC3339Param(v string) (time.Time, error) {
 	return time.Parse(time.RFC3339, v)
 }
 
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+}
+
 func writeJSON(w http.ResponseWriter, status int, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -47,8 +47,14 @@ func (s stubRepo) GetArticleByID(_ context.Context, id int64) (news.Article, err
 	return news.Article{}, storage.ErrNotFound
 }
 
-func (s stubRepo) UpsertArticles(_ context.Context, _ []news.Article) error { return nil }
-func (s stubRepo) Ready(_ context.Context) error                            { return s.readyErr }
+func (s stubRepo) UpsertArticles(_ context.Context, _ []news.Article) (storage.UpsertResult, error) {
+	return storage.UpsertResult{}, nil
+}
+
+func (s stubRepo) DeleteArticles(_ context.Context, _ []int64) (int, error) {
+	return 0, nil
+}
+func (s stubRepo) Ready(_ context.Context) error { return s.readyErr }
 
 func TestHomePage(t *testing.T) {
 	h := NewHandler(stubRepo{})
@@ -188,6 +194,18 @@ func TestListArticlesInvalidTimeRange(t *testing.T) {
 	}
 }
 
+func TestListArticlesInvalidHasMedia(t *testing.T) {
+	h := NewHandler(stubRepo{})
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/articles?has_media=image", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
 func TestGetArticleByID(t *testing.T) {
 	now := time.Now().UTC()
 	h := NewHandler(stubRepo{items: []news.Article{{ID: 7, Title: "detail", PublishedAt: now}}})
@@ -210,3 +228,52 @@ func TestGetArticleByID(t *testing.T) {
 		}
 	})
 }
+
+func TestArticleRevisions(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewMemoryArticleRepository()
+	article := news.Article{Title: "Minister resigns", URL: "https://example.com/a", ContentText: "He said nothing.", PublishedAt: time.Now().UTC()}
+	for _, title := range []string{"Minister resigns", "Minister resigns after scandal"} {
+		article.Title = title
+		if _, err := repo.UpsertArticles(ctx, []news.Article{article}); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+	h := NewHandler(repo, WithRevisions(repo))
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/articles/1/revisions", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var body struct {
+		Versions []struct {
+			Version   int  `json:"version"`
+			Current   bool `json:"current"`
+			TitleDiff []struct {
+				Op   string `json:"op"`
+				Text string `json:"text"`
+			} `json:"title_diff"`
+		} `json:"versions"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body.Versions) != 2 || !body.Versions[1].Current || len(body.Versions[0].TitleDiff) != 0 {
+		t.Fatalf("unexpected versions: %s", rr.Body.String())
+	}
+	diff := body.Versions[1].TitleDiff
+	if len(diff) != 2 || diff[1].Op != "insert" || diff[1].Text != "after scandal" {
+		t.Fatalf("unexpected title diff: %+v", diff)
+	}
+
+	for path, code := range map[string]int{"/v1/articles/9/revisions": http.StatusNotFound, "/v1/articles/1/history": http.StatusNotFound} {
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != code {
+			t.Fatalf("%s: expected %d, got %d", path, code, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package httpapi
+
+import (
+	"net/http"
+	"time"
+
+	"news-go/internal/news"
+	"news-go/internal/worddiff"
+)
+
+// articleVersion is one state of an article's title and content. The diffs
+// compare it word by word with the version before it, so the first
+// version has none.
+type articleVersion struct {
+	Version     int           `json:"version"`
+	Title       string        `json:"title"`
+	ContentText string        `json:"content_text"`
+	ContentHash string        `json:"content_hash"`
+	SeenAt      time.Time     `json:"seen_at"`
+	ReplacedAt  *time.Time    `json:"replaced_at,omitempty"`
+	Current     bool          `json:"current,omitempty"`
+	TitleDiff   []worddiff.Op `json:"title_diff,omitempty"`
+	ContentDiff []worddiff.Op `json:"content_diff,omitempty"`
+}
+
+// articleRevisions lists every version of an article, oldest first and
+// ending with the current one.
+func (h *Handler) articleRevisions(w http.ResponseWriter, r *http.Request, article news.Article) {
+	revs, err := h.revs.ArticleRevisions(r.Context(), article.ID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list revisions"})
+		return
+	}
+	versions := make([]articleVersion, 0, len(revs)+1)
+	for i, rev := range revs {
+		replaced := rev.ReplacedAt
+		versions = append(versions, articleVersion{
+			Version:     i + 1,
+			Title:       rev.Title,
+			ContentText: rev.ContentText,
+			ContentHash: rev.ContentHash,
+			SeenAt:      rev.SeenAt,
+			ReplacedAt:  &replaced,
+		})
+	}
+	current := articleVersion{
+		Version:     len(revs) + 1,
+		Title:       article.Title,
+		ContentText: article.ContentText,
+		ContentHash: article.ContentHash,
+		SeenAt:      article.UpdatedAt,
+		Current:     true,
+	}
+	if len(revs) > 0 {
+		current.SeenAt = revs[len(revs)-1].ReplacedAt
+	}
+	versions = append(versions, current)
+	for i := 1; i < len(versions); i++ {
+		prev, v := versions[i-1], &versions[i]
+		if prev.Title != v.Title {
+			v.TitleDiff = worddiff.Diff(prev.Title, v.Title)
+		}
+		if prev.ContentText != v.ContentText {
+			v.ContentDiff = worddiff.Diff(prev.ContentText, v.ContentText)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"article_id": article.ID, "url": article.URL, "versions": versions})
+}
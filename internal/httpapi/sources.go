@@ -0,0 +1,299 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"news-go/internal/crawler"
+	"news-go/internal/health"
+	"news-go/internal/news"
+	"news-go/internal/opml"
+	"news-go/internal/storage"
+)
+
+// sourcePatch carries the fields a PATCH may change; nil means unchanged.
+type sourcePatch struct {
+	Name            *string         `json:"name"`
+	Country         *string         `json:"country"`
+	FeedURL         *string         `json:"rss"`
+	BaseAuthority   *float64        `json:"base_authority"`
+	Topics          *[]string       `json:"topics"`
+	Enabled         *bool           `json:"enabled"`
+	ExtractFullText *bool           `json:"extract_full_text"`
+	Kind            *string         `json:"kind"`
+	Options         json.RawMessage `json:"options"`
+	HTTP            optionalHTTP    `json:"http"`
+	RetentionDays   *int            `json:"retention_days"`
+}
+
+func (p sourcePatch) apply(s *news.Source) {
+	if p.Name != nil {
+		s.Name = *p.Name
+	}
+	if p.Country != nil {
+		s.Country = *p.Country
+	}
+	if p.FeedURL != nil {
+		s.FeedURL = *p.FeedURL
+	}
+	if p.BaseAuthority != nil {
+		s.BaseAuthority = *p.BaseAuthority
+	}
+	if p.Topics != nil {
+		s.Topics = *p.Topics
+	}
+	if p.Enabled != nil {
+		s.Enabled = *p.Enabled
+	}
+	if p.ExtractFullText != nil {
+		s.ExtractFullText = *p.ExtractFullText
+	}
+	if p.Kind != nil && !strings.EqualFold(strings.TrimSpace(*p.Kind), s.Kind) {
+		// Options belong to the old kind.
+		s.Kind, s.Options = *p.Kind, nil
+	}
+	if p.Options != nil {
+		s.Options = p.Options
+	}
+	if p.HTTP.set {
+		s.HTTP = p.HTTP.value
+	}
+	if p.RetentionDays != nil {
+		s.RetentionDays = *p.RetentionDays
+	}
+}
+
+// optionalHTTP replaces the whole http object when the key is present;
+// null clears it.
+type optionalHTTP struct {
+	set   bool
+	value *news.SourceHTTP
+}
+
+func (o *optionalHTTP) UnmarshalJSON(b []byte) error {
+	o.set = true
+	return json.Unmarshal(b, &o.value)
+}
+
+func (h *Handler) sourcesCollection(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		items, err := h.sources.ListSources(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list sources"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"items": items})
+	case http.MethodPost:
+		var src news.Source
+		if err := json.NewDecoder(r.Body).Decode(&src); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		src.Normalize()
+		if err := crawler.ValidateSource(src, h.local); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		created, err := h.sources.CreateSource(r.Context(), src)
+		if err != nil {
+			if errors.Is(err, storage.ErrConflict) {
+				writeJSON(w, http.StatusConflict, map[string]string{"error": "source already exists"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create source"})
+			return
+		}
+		writeJSON(w, http.StatusCreated, created)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (h *Handler) sourceItem(w http.ResponseWriter, r *http.Request) {
+	id := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/v1/sources/"))
+	if id == "" || strings.Contains(id, "/") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid source id"})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		src, err := h.sources.GetSource(r.Context(), id)
+		if err != nil {
+			writeSourceError(w, err, "failed to get source")
+			return
+		}
+		writeJSON(w, http.StatusOK, src)
+	case http.MethodPatch:
+		var patch sourcePatch
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		src, err := h.sources.GetSource(r.Context(), id)
+		if err != nil {
+			writeSourceError(w, err, "failed to get source")
+			return
+		}
+		patch.apply(&src)
+		src.Normalize()
+		if err := crawler.ValidateSource(src, h.local); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		updated, err := h.sources.UpdateSource(r.Context(), src)
+		if err != nil {
+			writeSourceError(w, err, "failed to update source")
+			return
+		}
+		writeJSON(w, http.StatusOK, updated)
+	case http.MethodDelete:
+		if err := h.sources.DeleteSource(r.Context(), id); err != nil {
+			writeSourceError(w, err, "failed to delete source")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPatch, http.MethodDelete)
+	}
+}
+
+// maxOPMLBytes bounds an uploaded subscription list.
+const maxOPMLBytes = 4 << 20
+
+// importSources creates sources from an OPML body. Query parameters
+// base_authority and topics (comma-separated) apply to every feed unless
+// its outline says otherwise.
+func (h *Handler) importSources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var d opml.Defaults
+	if v := strings.TrimSpace(r.URL.Query().Get("base_authority")); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 1 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "base_authority must be a number within [0,1]"})
+			return
+		}
+		d.BaseAuthority = f
+	}
+	if v := r.URL.Query().Get("topics"); v != "" {
+		d.Topics = strings.Split(v, ",")
+	}
+	res, err := opml.Import(r.Context(), h.sources, http.MaxBytesReader(w, r.Body, maxOPMLBytes), d, h.local)
+	if err != nil {
+		if errors.Is(err, opml.ErrInvalidOPML) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to import sources"})
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) exportSources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	items, err := h.sources.ListSources(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list sources"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="sources.opml"`)
+	if err := opml.Write(w, items, time.Now()); err != nil {
+		log.Printf("event=opml_export status=error err=%q", err)
+	}
+}
+
+// FeedDiscoverer finds the feeds a web page links to.
+type FeedDiscoverer interface {
+	Discover(ctx context.Context, pageURL string) ([]crawler.FeedCandidate, error)
+}
+
+// discoverSources takes {"url": "..."} and returns the ranked feed
+// candidates of that page. A URL without scheme is tried as https.
+func (h *Handler) discoverSources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var body struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	raw := strings.TrimSpace(body.URL)
+	if raw != "" && !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if raw == "" || err != nil || u.Host == "" || u.Scheme != "http" && u.Scheme != "https" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "url must be an absolute http(s) URL"})
+		return
+	}
+	items, err := h.finder.Discover(r.Context(), u.String())
+	if err != nil {
+		if errors.Is(err, crawler.ErrDisallowed) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, crawler.ErrPrivateAddress) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "url must resolve to a public address"})
+			return
+		}
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "fetch page: " + err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"url": u.String(), "items": items})
+}
+
+func writeSourceError(w http.ResponseWriter, err error, msg string) {
+	if errors.Is(err, storage.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "source not found"})
+		return
+	}
+	if errors.Is(err, storage.ErrSourceInUse) {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "source has articles; disable it instead"})
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": msg})
+}
+
+func (h *Handler) sourcesHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	rep, err := h.sourceHealthReport(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to build health report"})
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
+func (h *Handler) sourceHealthReport(ctx context.Context) (health.Report, error) {
+	sources, err := h.sources.ListSources(ctx)
+	if err != nil {
+		return health.Report{}, err
+	}
+	records, err := h.health.ListSourceHealth(ctx)
+	if err != nil {
+		return health.Report{}, err
+	}
+	return h.policy.Evaluate(sources, records, time.Now().UTC()), nil
+}
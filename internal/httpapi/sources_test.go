@@ -0,0 +1,168 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"news-go/internal/crawler"
+	"news-go/internal/health"
+	"news-go/internal/news"
+	"news-go/internal/storage"
+)
+
+func newSourcesMux(opts ...Option) *http.ServeMux {
+	h := NewHandler(stubRepo{}, append([]Option{WithSources(storage.NewMemorySourceRepository())}, opts...)...)
+	mux := http.NewServeMux()
+	h.Register(mux)
+	return mux
+}
+
+func TestSourcesCRUD(t *testing.T) {
+	mux := newSourcesMux()
+
+	rr := httptest.NewRecorder()
+	body := `{"id":"bbc","name":"BBC News","rss":"https://feeds.bbci.co.uk/news/world/rss.xml","base_authority":0.9,"topics":["Politics","ai"]}`
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/sources", strings.NewReader(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created news.Source
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !created.Enabled || created.Topics[0] != "politics" {
+		t.Fatalf("unexpected created source: %+v", created)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/sources", strings.NewReader(body)))
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 on duplicate, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPatch, "/v1/sources/bbc", strings.NewReader(`{"enabled":false}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var patched news.Source
+	_ = json.Unmarshal(rr.Body.Bytes(), &patched)
+	if patched.Enabled || patched.Name != "BBC News" {
+		t.Fatalf("unexpected patched source: %+v", patched)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/v1/sources/bbc", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/sources/bbc", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestSourcesValidation(t *testing.T) {
+	mux := newSourcesMux()
+	cases := map[string]string{
+		"bad authority": `{"id":"x","name":"X","rss":"https://x.test/feed","base_authority":1.5}`,
+		"relative url":  `{"id":"x","name":"X","rss":"/feed","base_authority":0.5}`,
+		"bad scheme":    `{"id":"x","name":"X","rss":"ftp://x.test/feed","base_authority":0.5}`,
+		"bad id":        `{"id":"x y","name":"X","rss":"https://x.test/feed","base_authority":0.5}`,
+		"missing name":  `{"id":"x","rss":"https://x.test/feed","base_authority":0.5}`,
+		"retention":     `{"id":"x","name":"X","rss":"https://x.test/feed","base_authority":0.5,"retention_days":-1}`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/sources", strings.NewReader(body)))
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d", rr.Code)
+			}
+		})
+	}
+}
+
+func TestReadyzDegradesOnStaleSources(t *testing.T) {
+	ctx := context.Background()
+	sources := storage.NewMemorySourceRepository()
+	records := storage.NewMemorySourceHealthRepository()
+	for _, id := range []string{"a", "b"} {
+		if _, err := sources.CreateSource(ctx, news.Source{ID: id, Name: id, FeedURL: "https://x.test/" + id, Enabled: true}); err != nil {
+			t.Fatalf("create source: %v", err)
+		}
+	}
+	now := time.Now().UTC()
+	_ = records.SaveSourceHealth(ctx, news.SourceHealth{SourceID: "a", LastAttemptAt: &now, LastSuccessAt: &now, NewestArticleAt: &now})
+	_ = records.SaveSourceHealth(ctx, news.SourceHealth{SourceID: "b", LastAttemptAt: &now, ConsecutiveFailures: 5})
+
+	newMux := func(ratio float64) *http.ServeMux {
+		h := NewHandler(stubRepo{}, WithSources(sources), WithSourceHealth(records, health.Policy{MaxFailures: 3, MaxStaleRatio: ratio}))
+		mux := http.NewServeMux()
+		h.Register(mux)
+		return mux
+	}
+
+	rr := httptest.NewRecorder()
+	newMux(0.5).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	newMux(0.75).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	newMux(0).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/sources/health", nil))
+	var rep health.Report
+	if err := json.Unmarshal(rr.Body.Bytes(), &rep); err != nil || rep.Stale != 1 || len(rep.Sources) != 2 {
+		t.Fatalf("unexpected report: %v %+v", err, rep)
+	}
+}
+
+func TestSourcesPatchHTTP(t *testing.T) {
+	mux := newSourcesMux(WithLocalAccess(crawler.LocalAccess{SecretEnvPrefix: "NEWS_SECRET_"}))
+	body := `{"id":"paywall","name":"Paywall","rss":"https://paywall.test/feed","base_authority":0.5,"http":{"headers":{"Accept-Language":"de"},"auth":{"type":"bearer","token_env":"NEWS_SECRET_PAYWALL"}}}`
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/sources", strings.NewReader(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPatch, "/v1/sources/paywall", strings.NewReader(`{"http":{"headers":{"Authorization":"Bearer x"}}}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("static Authorization header: expected 400, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPatch, "/v1/sources/paywall", strings.NewReader(`{"http":{"auth":{"type":"bearer","token_env":"DB_PASSWORD"}}}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("token_env outside SECRET_ENV_PREFIX: expected 400, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPatch, "/v1/sources/paywall", strings.NewReader(`{"enabled":false}`)))
+	var patched news.Source
+	_ = json.Unmarshal(rr.Body.Bytes(), &patched)
+	if patched.HTTP == nil || patched.HTTP.Auth == nil || patched.HTTP.Auth.TokenEnv != "NEWS_SECRET_PAYWALL" {
+		t.Fatalf("http settings lost on unrelated patch: %+v", patched.HTTP)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPatch, "/v1/sources/paywall", strings.NewReader(`{"http":null}`)))
+	patched = news.Source{}
+	_ = json.Unmarshal(rr.Body.Bytes(), &patched)
+	if rr.Code != http.StatusOK || patched.HTTP != nil {
+		t.Fatalf("expected http cleared, got %d %+v", rr.Code, patched.HTTP)
+	}
+}
@@ -0,0 +1,69 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"news-go/internal/websub"
+)
+
+// WebSubCallback handles what hubs send to the subscription callback.
+type WebSubCallback interface {
+	Verify(ctx context.Context, sourceID string, q url.Values) (string, error)
+	Receive(ctx context.Context, sourceID string, body []byte, signature string) error
+}
+
+// maxPushBytes bounds a pushed feed body.
+const maxPushBytes = 4 << 20
+
+// websubCallback answers intent verification on GET and accepts pushed
+// content on POST. A bad signature is acknowledged but dropped, as the
+// spec requires; 410 tells a hub to stop pushing for unknown sources.
+func (h *Handler) websubCallback(w http.ResponseWriter, r *http.Request) {
+	sourceID := strings.ToLower(strings.TrimPrefix(r.URL.Path, websub.CallbackPath))
+	if sourceID == "" || strings.Contains(sourceID, "/") {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown subscription"})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		challenge, err := h.websub.Verify(r.Context(), sourceID, r.URL.Query())
+		if err != nil {
+			if errors.Is(err, websub.ErrUnknownSubscription) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to verify subscription"})
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, challenge)
+	case http.MethodPost:
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPushBytes))
+		if err != nil {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "push body too large"})
+			return
+		}
+		err = h.websub.Receive(r.Context(), sourceID, body, r.Header.Get("X-Hub-Signature"))
+		switch {
+		case err == nil:
+			w.WriteHeader(http.StatusNoContent)
+		case errors.Is(err, websub.ErrBadSignature):
+			log.Printf("event=websub_push status=rejected source=%s err=%q", sourceID, err)
+			w.WriteHeader(http.StatusAccepted)
+		case errors.Is(err, websub.ErrUnknownSubscription):
+			writeJSON(w, http.StatusGone, map[string]string{"error": err.Error()})
+		default:
+			log.Printf("event=websub_push status=error source=%s err=%q", sourceID, err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to store pushed content"})
+		}
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
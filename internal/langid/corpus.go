@@ -0,0 +1,83 @@
+package langid
+
+// trainingText holds a few paragraphs of ordinary news prose per
+// Latin-script language. Trigram statistics are built from it at start-up;
+// function words and spelling patterns dominate, so topic matters little.
+var trainingText = map[string]string{
+	"en": `The government announced on Tuesday that it would increase spending on public transport and schools over the next three years.
+The minister said the plan was necessary because many families were struggling with higher prices and the cost of housing.
+Opposition leaders argued that the proposal did not go far enough and that the money should have been made available much earlier.
+Scientists have found evidence that the climate in the region is changing faster than expected, according to a report published this week.
+The company reported strong results for the third quarter, with revenue rising by more than ten percent compared with the same period last year.
+Police are asking witnesses who were in the area at the time of the accident to come forward with any information they may have.
+Shares in technology firms fell sharply after investors worried about new rules and the threat of higher interest rates.
+Thousands of people gathered in the city centre to celebrate the victory of the national team, which had not won the title since the nineties.
+Experts warn that without further investment the health service will not be able to cope with the growing number of older patients.
+He told reporters that the talks had been difficult but that both sides were willing to keep working towards an agreement.`,
+
+	"de": `Die Bundesregierung hat am Dienstag angekündigt, dass sie in den nächsten drei Jahren mehr Geld für den öffentlichen Verkehr und die Schulen ausgeben will.
+Der Minister sagte, der Plan sei notwendig, weil viele Familien unter den hohen Preisen und den Kosten für Wohnungen leiden.
+Die Opposition kritisierte, dass der Vorschlag nicht weit genug gehe und das Geld schon viel früher hätte bereitgestellt werden müssen.
+Wissenschaftler haben Hinweise darauf gefunden, dass sich das Klima in der Region schneller verändert als erwartet, heißt es in einem Bericht.
+Das Unternehmen meldete für das dritte Quartal starke Ergebnisse, der Umsatz stieg im Vergleich zum Vorjahr um mehr als zehn Prozent.
+Die Polizei bittet Zeugen, die sich zum Zeitpunkt des Unfalls in der Nähe aufgehalten haben, sich mit ihren Hinweisen zu melden.
+Die Aktien von Technologiekonzernen fielen deutlich, nachdem sich Anleger über neue Regeln und steigende Zinsen Sorgen gemacht hatten.
+Tausende Menschen versammelten sich in der Innenstadt, um den Sieg der Nationalmannschaft zu feiern, die den Titel seit Jahren nicht gewonnen hatte.
+Experten warnen, dass das Gesundheitssystem ohne weitere Investitionen nicht mit der wachsenden Zahl älterer Patienten zurechtkommen wird.
+Er sagte den Journalisten, die Gespräche seien schwierig gewesen, aber beide Seiten wollten weiter an einer Einigung arbeiten.`,
+
+	"fr": `Le gouvernement a annoncé mardi qu'il allait augmenter les dépenses consacrées aux transports publics et aux écoles au cours des trois prochaines années.
+Le ministre a déclaré que ce plan était nécessaire parce que de nombreuses familles souffrent de la hausse des prix et du coût du logement.
+Les dirigeants de l'opposition ont estimé que la proposition n'allait pas assez loin et que l'argent aurait dû être débloqué beaucoup plus tôt.
+Des scientifiques ont trouvé des preuves que le climat de la région change plus vite que prévu, selon un rapport publié cette semaine.
+L'entreprise a publié de solides résultats pour le troisième trimestre, avec un chiffre d'affaires en hausse de plus de dix pour cent sur un an.
+La police demande aux témoins qui se trouvaient dans le secteur au moment de l'accident de se manifester et de donner leurs informations.
+Les actions des entreprises technologiques ont fortement baissé après que les investisseurs se sont inquiétés des nouvelles règles et des taux d'intérêt.
+Des milliers de personnes se sont rassemblées dans le centre-ville pour fêter la victoire de l'équipe nationale, qui n'avait pas gagné le titre depuis longtemps.
+Les experts préviennent que sans nouveaux investissements le système de santé ne pourra pas faire face au nombre croissant de patients âgés.
+Il a dit aux journalistes que les discussions avaient été difficiles mais que les deux parties voulaient continuer à chercher un accord.`,
+
+	"es": `El gobierno anunció el martes que aumentará el gasto en transporte público y en escuelas durante los próximos tres años.
+El ministro dijo que el plan era necesario porque muchas familias tienen dificultades con la subida de los precios y el coste de la vivienda.
+Los líderes de la oposición afirmaron que la propuesta no iba lo bastante lejos y que el dinero debería haberse puesto a disposición mucho antes.
+Los científicos han encontrado pruebas de que el clima de la región está cambiando más rápido de lo previsto, según un informe publicado esta semana.
+La empresa presentó unos resultados sólidos en el tercer trimestre, con unos ingresos que crecieron más de un diez por ciento respecto al año anterior.
+La policía pide a los testigos que se encontraban en la zona en el momento del accidente que se pongan en contacto con cualquier información.
+Las acciones de las empresas tecnológicas cayeron con fuerza después de que los inversores se preocuparan por las nuevas normas y los tipos de interés.
+Miles de personas se reunieron en el centro de la ciudad para celebrar la victoria de la selección nacional, que no ganaba el título desde hacía años.
+Los expertos advierten de que sin más inversión el sistema de salud no podrá hacer frente al número creciente de pacientes mayores.
+Dijo a los periodistas que las conversaciones habían sido difíciles pero que ambas partes estaban dispuestas a seguir trabajando para llegar a un acuerdo.`,
+
+	"it": `Il governo ha annunciato martedì che aumenterà la spesa per il trasporto pubblico e per le scuole nei prossimi tre anni.
+Il ministro ha detto che il piano era necessario perché molte famiglie hanno difficoltà con l'aumento dei prezzi e il costo delle case.
+I leader dell'opposizione hanno sostenuto che la proposta non va abbastanza lontano e che i soldi avrebbero dovuto essere stanziati molto prima.
+Gli scienziati hanno trovato prove che il clima della regione sta cambiando più rapidamente del previsto, secondo un rapporto pubblicato questa settimana.
+L'azienda ha comunicato risultati solidi per il terzo trimestre, con ricavi in crescita di oltre il dieci per cento rispetto allo stesso periodo dell'anno scorso.
+La polizia chiede ai testimoni che si trovavano nella zona al momento dell'incidente di farsi avanti con qualsiasi informazione.
+Le azioni delle società tecnologiche sono scese bruscamente dopo che gli investitori si sono preoccupati per le nuove regole e per i tassi di interesse.
+Migliaia di persone si sono radunate nel centro della città per festeggiare la vittoria della nazionale, che non vinceva il titolo da molti anni.
+Gli esperti avvertono che senza ulteriori investimenti il sistema sanitario non sarà in grado di gestire il numero crescente di pazienti anziani.
+Ha detto ai giornalisti che i colloqui erano stati difficili ma che entrambe le parti erano disposte a continuare a lavorare per un accordo.`,
+
+	"pt": `O governo anunciou na terça-feira que vai aumentar os gastos com transporte público e escolas nos próximos três anos.
+O ministro disse que o plano era necessário porque muitas famílias estão com dificuldades por causa da subida dos preços e do custo da habitação.
+Os líderes da oposição afirmaram que a proposta não vai suficientemente longe e que o dinheiro deveria ter sido disponibilizado muito mais cedo.
+Os cientistas encontraram provas de que o clima da região está a mudar mais depressa do que o esperado, segundo um relatório publicado esta semana.
+A empresa apresentou resultados fortes no terceiro trimestre, com as receitas a crescerem mais de dez por cento em relação ao mesmo período do ano passado.
+A polícia pede às testemunhas que estavam na zona no momento do acidente que entrem em contacto com qualquer informação que tenham.
+As ações das empresas de tecnologia caíram fortemente depois de os investidores se preocuparem com as novas regras e com as taxas de juro.
+Milhares de pessoas juntaram-se no centro da cidade para celebrar a vitória da seleção nacional, que não conquistava o título há muitos anos.
+Os especialistas avisam que sem mais investimento o serviço de saúde não vai conseguir responder ao número crescente de doentes idosos.
+Ele disse aos jornalistas que as conversações tinham sido difíceis, mas que as duas partes estavam dispostas a continuar a trabalhar num acordo.`,
+
+	"nl": `De regering heeft dinsdag aangekondigd dat zij de komende drie jaar meer geld gaat uitgeven aan het openbaar vervoer en aan scholen.
+De minister zei dat het plan nodig was omdat veel gezinnen het moeilijk hebben met de hogere prijzen en de kosten van een woning.
+Leiders van de oppositie vonden dat het voorstel niet ver genoeg gaat en dat het geld al veel eerder beschikbaar had moeten komen.
+Wetenschappers hebben aanwijzingen gevonden dat het klimaat in de regio sneller verandert dan verwacht, volgens een rapport dat deze week verscheen.
+Het bedrijf meldde sterke resultaten over het derde kwartaal, met een omzet die meer dan tien procent hoger was dan in dezelfde periode vorig jaar.
+De politie vraagt getuigen die op het moment van het ongeluk in de buurt waren om zich te melden met alle informatie die zij hebben.
+De aandelen van technologiebedrijven daalden flink nadat beleggers zich zorgen maakten over nieuwe regels en een hogere rente.
+Duizenden mensen kwamen samen in het centrum van de stad om de overwinning van het nationale team te vieren, dat de titel in jaren niet had gewonnen.
+Deskundigen waarschuwen dat de gezondheidszorg zonder extra investeringen het groeiende aantal oudere patiënten niet aankan.
+Hij vertelde verslaggevers dat de gesprekken moeilijk waren geweest, maar dat beide partijen bereid waren om verder te werken aan een akkoord.`,
+}
@@ -0,0 +1,248 @@
+// Package langid identifies the language of a text offline. Writing system
+// decides for Chinese, Japanese, Korean, Russian and Arabic; Latin-script
+// languages are told apart by a character-trigram naive Bayes model.
+package langid
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Result carries an ISO 639-1 code, or "" when the text is too short or
+// has no letters, and a confidence in [0,1].
+type Result struct {
+	Code       string  `json:"code"`
+	Confidence float64 `json:"confidence"`
+}
+
+// MinConfidence is the level below which callers should treat a result as
+// undetermined. Headlines of four or five words land around 0.6-0.8, so
+// short texts mostly fall back to the feed's declared language.
+const MinConfidence = 0.7
+
+// minLetters is the least weighted letter count worth classifying.
+const minLetters = 12
+
+// cjkWeight makes one ideograph or kana count like a few Latin letters, so a
+// Chinese headline with an English brand name is still Chinese.
+const cjkWeight = 3
+
+// maxRunes bounds the work spent on long bodies.
+const maxRunes = 4000
+
+type script int
+
+const (
+	scriptOther script = iota
+	scriptLatin
+	scriptCJK
+	scriptHangul
+	scriptCyrillic
+	scriptArabic
+)
+
+func scriptOf(r rune) script {
+	switch {
+	case unicode.Is(unicode.Han, r), unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+		return scriptCJK
+	case unicode.Is(unicode.Hangul, r):
+		return scriptHangul
+	case unicode.Is(unicode.Cyrillic, r):
+		return scriptCyrillic
+	case unicode.Is(unicode.Arabic, r):
+		return scriptArabic
+	case unicode.Is(unicode.Latin, r):
+		return scriptLatin
+	}
+	return scriptOther
+}
+
+func isKana(r rune) bool {
+	return unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) && r != 'ー'
+}
+
+// Detect identifies the language of text from its first maxRunes runes.
+// The dominant writing system settles non-Latin scripts; Latin text goes to
+// the trigram model, whose probability is scaled by the Latin share of the
+// letters. Texts with too few letters give an empty Result.
+func Detect(text string) Result {
+	if r := []rune(text); len(r) > maxRunes {
+		text = string(r[:maxRunes])
+	}
+	weights := map[script]float64{}
+	total, kana, cjk := 0.0, 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		s := scriptOf(r)
+		if s == scriptOther {
+			continue
+		}
+		w := 1.0
+		if s == scriptCJK || s == scriptHangul {
+			w = cjkWeight
+		}
+		if s == scriptCJK {
+			cjk++
+			if isKana(r) {
+				kana++
+			}
+		}
+		weights[s] += w
+		total += w
+	}
+	if total < minLetters {
+		return Result{}
+	}
+	best, bestW := scriptOther, 0.0
+	for s, w := range weights {
+		if w > bestW || w == bestW && s < best {
+			best, bestW = s, w
+		}
+	}
+	share := round(bestW / total)
+	switch best {
+	case scriptCJK:
+		// Japanese prose always mixes in kana; Chinese has none.
+		if float64(kana) >= 0.05*float64(cjk) {
+			return Result{Code: "ja", Confidence: share}
+		}
+		return Result{Code: "zh", Confidence: share}
+	case scriptHangul:
+		return Result{Code: "ko", Confidence: share}
+	case scriptCyrillic:
+		return Result{Code: "ru", Confidence: share}
+	case scriptArabic:
+		return Result{Code: "ar", Confidence: share}
+	}
+	code, p := defaultModel.classify(text)
+	return Result{Code: code, Confidence: round(p * share)}
+}
+
+func round(v float64) float64 { return math.Round(v*1000) / 1000 }
+
+type model struct {
+	langs  []string
+	counts map[string]map[string]float64
+	totals map[string]float64
+	vocab  float64
+}
+
+var defaultModel = newModel(trainingText)
+
+func newModel(corpus map[string]string) *model {
+	m := &model{counts: map[string]map[string]float64{}, totals: map[string]float64{}}
+	vocab := map[string]bool{}
+	for lang, text := range corpus {
+		m.langs = append(m.langs, lang)
+		c := map[string]float64{}
+		for _, g := range trigrams(text) {
+			c[g]++
+			m.totals[lang]++
+			vocab[g] = true
+		}
+		m.counts[lang] = c
+	}
+	sort.Strings(m.langs)
+	m.vocab = float64(len(vocab))
+	return m
+}
+
+// classify returns the most likely language and its posterior probability
+// under a uniform prior.
+func (m *model) classify(text string) (string, float64) {
+	grams := trigrams(text)
+	if len(grams) == 0 {
+		return "", 0
+	}
+	scores := make([]float64, len(m.langs))
+	for i, lang := range m.langs {
+		c, denom := m.counts[lang], m.totals[lang]+m.vocab
+		for _, g := range grams {
+			scores[i] += math.Log((c[g] + 1) / denom)
+		}
+	}
+	best := 0
+	for i := range scores {
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	sum := 0.0
+	for _, s := range scores {
+		sum += math.Exp(s - scores[best])
+	}
+	return m.langs[best], 1 / sum
+}
+
+// trigrams lower-cases Latin words, pads each with spaces and returns their
+// character trigrams; other characters act as word breaks.
+func trigrams(text string) []string {
+	var out []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) || scriptOf(r) != scriptLatin
+	}) {
+		r := []rune(" " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			out = append(out, string(r[i:i+3]))
+		}
+	}
+	return out
+}
+
+// SearchTerms splits a search query into lower-case terms that must all
+// match. Whitespace and punctuation separate words in alphabetic scripts;
+// Chinese, Japanese and Korean are not segmented into words, so a CJK run
+// stays one substring term and only a change of script splits it, e.g.
+// "AI芯片" gives "ai" and "芯片".
+func SearchTerms(query string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	var cur []rune
+	flush := func() {
+		t := strings.Trim(string(cur), "-.'_&")
+		cur = cur[:0]
+		if t = strings.ToLower(t); t != "" && !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	for _, r := range query {
+		if unicode.IsSpace(r) || (unicode.IsPunct(r) || unicode.IsSymbol(r)) && !strings.ContainsRune("-.'_&+#", r) {
+			flush()
+			continue
+		}
+		if len(cur) > 0 && isCJKChar(cur[len(cur)-1]) != isCJKChar(r) {
+			flush()
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return terms
+}
+
+func isCJKChar(r rune) bool {
+	switch scriptOf(r) {
+	case scriptCJK, scriptHangul:
+		return true
+	}
+	return r == 'ー' || r == '々' || r == '〆'
+}
+
+// Resolve detects the language of text and falls back to the primary
+// subtag of declared, typically the feed's <language>, when detection is
+// below MinConfidence. A fallback result has zero confidence.
+func Resolve(text, declared string) Result {
+	if r := Detect(text); r.Code != "" && r.Confidence >= MinConfidence {
+		return r
+	}
+	code, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(declared)), "-")
+	code, _, _ = strings.Cut(code, "_")
+	if len(code) != 2 {
+		return Result{}
+	}
+	return Result{Code: code}
+}
@@ -0,0 +1,74 @@
+package langid
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"Central bank raises interest rates again as inflation stays stubbornly high", "en"},
+		{"Die Zentralbank erhöht erneut die Zinsen, weil die Inflation hartnäckig hoch bleibt", "de"},
+		{"La banque centrale relève encore ses taux car l'inflation reste obstinément élevée", "fr"},
+		{"El banco central vuelve a subir los tipos porque la inflación sigue siendo alta", "es"},
+		{"La banca centrale alza di nuovo i tassi perché l'inflazione resta ostinatamente alta", "it"},
+		{"O banco central volta a subir os juros porque a inflação continua elevada", "pt"},
+		{"De centrale bank verhoogt opnieuw de rente omdat de inflatie hardnekkig hoog blijft", "nl"},
+		{"央行再次加息，因为通胀依然居高不下", "zh"},
+		{"OpenAI发布新一代GPT模型，性能大幅提升", "zh"},
+		{"日本銀行は物価上昇が続いているため、再び金利を引き上げた", "ja"},
+		{"중앙은행이 물가 상승으로 다시 금리를 인상했다", "ko"},
+		{"Центральный банк снова повысил процентные ставки", "ru"},
+	}
+	for _, c := range cases {
+		got := Detect(c.text)
+		if got.Code != c.want {
+			t.Errorf("Detect(%q) = %+v, want %s", c.text, got, c.want)
+			continue
+		}
+		if got.Confidence < MinConfidence {
+			t.Errorf("Detect(%q) confidence %.3f below threshold", c.text, got.Confidence)
+		}
+	}
+}
+
+func TestDetectUndetermined(t *testing.T) {
+	for _, text := range []string{"", "2024-10-19 12:00", "OK"} {
+		if got := Detect(text); got.Code != "" {
+			t.Errorf("Detect(%q) = %+v, want undetermined", text, got)
+		}
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"  Harbour   STORM ", []string{"harbour", "storm"}},
+		{"AI芯片 出口", []string{"ai", "芯片", "出口"}},
+		{"東京タワー", []string{"東京タワー"}},
+		{"e-mail, U.S.", []string{"e-mail", "u.s"}},
+		{"c++ vs c#", []string{"c++", "vs", "c#"}},
+	}
+	for _, c := range cases {
+		if got := SearchTerms(c.query); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("SearchTerms(%q) = %q, want %q", c.query, got, c.want)
+		}
+	}
+}
+
+func TestResolveFallsBackToDeclared(t *testing.T) {
+	if got := Resolve("Apple unveils new iPhone", "en-US"); got.Code != "en" {
+		t.Errorf("short headline: %+v", got)
+	}
+	if got := Resolve("央行再次加息，因为通胀依然居高不下", "en"); got.Code != "zh" || got.Confidence < MinConfidence {
+		t.Errorf("detected language should win over the feed: %+v", got)
+	}
+	if got := Resolve("OK", "english"); got.Code != "" {
+		t.Errorf("non-ISO declared language: %+v", got)
+	}
+}
@@ -1,12 +1,142 @@
 package news
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
 
 type Article struct {
+	ID     int64  `json:"id"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Source string `json:"source"`
+	// GUID is the feed's own item identifier; it is informational, articles
+	// are still deduplicated by URL.
+	GUID       string   `json:"guid,omitempty"`
+	Authors    []string `json:"authors"`
+	Categories []string `json:"categories"`
+	ImageURL   string   `json:"image_url,omitempty"`
+	// Media are the audio and video attachments of podcast and video feeds.
+	Media []Media `json:"media,omitempty"`
+	// Language is an ISO 639-1 code detected from the text at ingest, or the
+	// feed's declared language when detection was unsure, in which case
+	// LanguageConfidence is 0.
+	Language           string  `json:"language,omitempty"`
+	LanguageConfidence float64 `json:"language_confidence"`
+	// Content is the feed description reduced to allow-listed HTML at
+	// ingest; ContentText is its plain-text form, used for search.
+	Content     string `json:"content,omitempty"`
+	ContentText string `json:"content_text,omitempty"`
+	// ContentHash identifies the title and plain-text content; a feed
+	// changing either keeps the earlier version as an ArticleRevision.
+	ContentHash string    `json:"content_hash,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+	// FetchedAt is the last time a feed delivered the article; UpdatedAt is
+	// when its stored fields last changed.
+	FetchedAt time.Time `json:"fetched_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Body is the full text extracted from the article page for sources with
+	// extract_full_text set; ExtractionStatus is empty until it was tried.
+	Body             string `json:"body,omitempty"`
+	ExtractionStatus string `json:"extraction_status,omitempty"`
+}
+
+// ArticleRevision is an earlier title and content of an article. SeenAt
+// is when that version was stored, ReplacedAt when a feed changed it.
+type ArticleRevision struct {
 	ID          int64     `json:"id"`
+	ArticleID   int64     `json:"article_id"`
 	Title       string    `json:"title"`
-	URL         string    `json:"url"`
-	Source      string    `json:"source"`
 	Content     string    `json:"content,omitempty"`
-	PublishedAt time.Time `json:"published_at"`
+	ContentText string    `json:"content_text,omitempty"`
+	ContentHash string    `json:"content_hash"`
+	SeenAt      time.Time `json:"seen_at"`
+	ReplacedAt  time.Time `json:"replaced_at"`
+}
+
+// ContentHash returns the hex SHA-256 of an article's title and plain-text
+// content, so markup-only changes such as a rotating tracking pixel or
+// reordered attributes are not revisions.
+func ContentHash(title, contentText string) string {
+	sum := sha256.Sum256([]byte(title + "\x00" + contentText))
+	return hex.EncodeToString(sum[:])
+}
+
+// Media is an enclosure, media:content or attachment. Medium is MediumAudio
+// or MediumVideo; Length (bytes) and DurationSec are 0 when the feed does
+// not give them.
+type Media struct {
+	URL         string `json:"url"`
+	Type        string `json:"type,omitempty"`
+	Medium      string `json:"medium"`
+	Length      int64  `json:"length,omitempty"`
+	DurationSec int    `json:"duration_sec,omitempty"`
+}
+
+const (
+	MediumAudio = "audio"
+	MediumVideo = "video"
+)
+
+// MediaMedium classifies an attachment by its declared medium or else its
+// MIME type, returning "" for anything other than audio or video.
+func MediaMedium(medium, mimeType string) string {
+	switch m := strings.ToLower(strings.TrimSpace(medium)); m {
+	case MediumAudio, MediumVideo:
+		return m
+	case "":
+	default:
+		return ""
+	}
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	switch {
+	case strings.HasPrefix(mimeType, "audio/"):
+		return MediumAudio
+	case strings.HasPrefix(mimeType, "video/"):
+		return MediumVideo
+	}
+	return ""
+}
+
+// HasMedia reports whether the article has an attachment of the given
+// medium, or of any medium when it is "any".
+func (a Article) HasMedia(medium string) bool {
+	for _, m := range a.Media {
+		if medium == "any" || m.Medium == medium {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	ExtractionOK         = "ok"
+	ExtractionEmpty      = "empty"
+	ExtractionFailed     = "failed"
+	ExtractionDisallowed = "disallowed"
+)
+
+// NormalizeLanguage lower-cases a language tag and uses "-" as separator,
+// so "en_US" and "EN-us" both become "en-us".
+func NormalizeLanguage(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
+
+// MatchesLanguage reports whether tag is lang or a subtag of it, e.g.
+// "zh-cn" matches "zh".
+func MatchesLanguage(tag, lang string) bool {
+	tag, lang = NormalizeLanguage(tag), NormalizeLanguage(lang)
+	return tag == lang || strings.HasPrefix(tag, lang+"-")
+}
+
+// HasAuthor matches author names case-insensitively.
+func (a Article) HasAuthor(name string) bool {
+	for _, au := range a.Authors {
+		if strings.EqualFold(au, strings.TrimSpace(name)) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,28 @@
+package news
+
+import "time"
+
+const (
+	CrawlRunRunning = "running"
+	CrawlRunOK      = "ok"
+	CrawlRunFailed  = "failed"
+)
+
+// CrawlRun records one syncWithRetry pass over a single source.
+type CrawlRun struct {
+	ID         int64      `json:"id"`
+	SourceID   string     `json:"source_id"`
+	Trigger    string     `json:"trigger"`
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Attempts   int        `json:"attempts"`
+	Fetched    int        `json:"fetched"`
+	Inserted   int        `json:"inserted"`
+	Updated    int        `json:"updated"`
+	Errors     []string   `json:"errors"`
+	// Truncated counts items skipped for exceeding FEED_MAX_ITEMS;
+	// Rejected is why the crawler refused to parse the response.
+	Truncated int    `json:"truncated"`
+	Rejected  string `json:"rejected,omitempty"`
+}
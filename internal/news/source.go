@@ -0,0 +1,199 @@
+package news
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Source is a whitelisted feed. Field names follow data/sources.json so the
+// seed file, the REST API and the Python pipeline share one shape.
+type Source struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Country       string   `json:"country,omitempty"`
+	FeedURL       string   `json:"rss"`
+	BaseAuthority float64  `json:"base_authority"`
+	Topics        []string `json:"topics"`
+	Enabled       bool     `json:"enabled"`
+	// ExtractFullText fetches each new article page and stores its main
+	// text as the article body.
+	ExtractFullText bool `json:"extract_full_text"`
+	// Kind selects how the source is fetched, see crawler.Register; rss is
+	// the URL the kind reads, e.g. a listing page for html sources. Options
+	// holds the kind's own settings as a JSON object.
+	Kind    string          `json:"kind"`
+	Options json.RawMessage `json:"options,omitempty"`
+	// HTTP changes how the crawler reaches the source, for any kind that
+	// fetches over http(s).
+	HTTP *SourceHTTP `json:"http,omitempty"`
+	// RetentionDays overrides the global maximum article age for this
+	// source; 0 keeps the global setting.
+	RetentionDays int       `json:"retention_days,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// SourceHTTP holds per-source request settings. Secrets are never stored:
+// fields ending in _env name the environment variable holding the value,
+// read when a request is made.
+type SourceHTTP struct {
+	// Proxy is an http, https or socks5 URL, optionally with a user name
+	// whose password is in ProxyPasswordEnv.
+	Proxy            string `json:"proxy,omitempty"`
+	ProxyPasswordEnv string `json:"proxy_password_env,omitempty"`
+	// Headers are sent as given; HeaderEnv maps a header name to the
+	// variable holding its value, e.g. a Cookie for a partner feed.
+	Headers   map[string]string `json:"headers,omitempty"`
+	HeaderEnv map[string]string `json:"header_env,omitempty"`
+	Auth      *SourceAuth       `json:"auth,omitempty"`
+	// CAFile is a PEM bundle trusted in addition to the system roots.
+	CAFile     string `json:"ca_file,omitempty"`
+	TimeoutSec int    `json:"timeout_sec,omitempty"`
+}
+
+// SourceAuth is basic auth (Username and PasswordEnv) or a bearer token
+// (TokenEnv).
+type SourceAuth struct {
+	Type        string `json:"type"`
+	Username    string `json:"username,omitempty"`
+	PasswordEnv string `json:"password_env,omitempty"`
+	TokenEnv    string `json:"token_env,omitempty"`
+}
+
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+)
+
+// IsZero reports whether h changes nothing.
+func (h *SourceHTTP) IsZero() bool {
+	return h == nil || h.Proxy == "" && len(h.Headers) == 0 && len(h.HeaderEnv) == 0 && h.Auth == nil && h.CAFile == "" && h.TimeoutSec == 0
+}
+
+// maxRetentionDays bounds Source.RetentionDays to a hundred years.
+const maxRetentionDays = 36500
+
+// KindRSS is the kind of sources that do not name one.
+const KindRSS = "rss"
+
+var ErrInvalidSource = errors.New("invalid source")
+
+// UnmarshalJSON treats a missing "enabled" key as true, since the seed file
+// predates the flag and new sources are expected to be crawled.
+func (s *Source) UnmarshalJSON(data []byte) error {
+	type plain Source
+	v := plain{Enabled: true}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = Source(v)
+	return nil
+}
+
+// DecodeOptions unmarshals Options into v; empty options leave v as is.
+func (s Source) DecodeOptions(v any) error {
+	if isEmptyJSON(s.Options) {
+		return nil
+	}
+	if err := json.Unmarshal(s.Options, v); err != nil {
+		return fmt.Errorf("%w: options: %v", ErrInvalidSource, err)
+	}
+	return nil
+}
+
+func isEmptyJSON(raw json.RawMessage) bool {
+	t := bytes.TrimSpace(raw)
+	return len(t) == 0 || bytes.Equal(t, []byte("null"))
+}
+
+// Normalize trims free-text fields and drops empty or duplicate topics.
+func (s *Source) Normalize() {
+	s.ID = strings.ToLower(strings.TrimSpace(s.ID))
+	s.Name = strings.TrimSpace(s.Name)
+	s.Country = strings.TrimSpace(s.Country)
+	s.FeedURL = strings.TrimSpace(s.FeedURL)
+	s.Kind = strings.ToLower(strings.TrimSpace(s.Kind))
+	if s.Kind == "" {
+		s.Kind = KindRSS
+	}
+	if isEmptyJSON(s.Options) {
+		s.Options = nil
+	} else {
+		var b bytes.Buffer
+		if json.Compact(&b, s.Options) == nil {
+			s.Options = b.Bytes()
+		}
+	}
+	if s.HTTP != nil {
+		s.HTTP.Proxy = strings.TrimSpace(s.HTTP.Proxy)
+		s.HTTP.CAFile = strings.TrimSpace(s.HTTP.CAFile)
+		if s.HTTP.Auth != nil {
+			s.HTTP.Auth.Type = strings.ToLower(strings.TrimSpace(s.HTTP.Auth.Type))
+		}
+		if s.HTTP.IsZero() {
+			s.HTTP = nil
+		}
+	}
+	topics := make([]string, 0, len(s.Topics))
+	seen := map[string]bool{}
+	for _, t := range s.Topics {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		topics = append(topics, t)
+	}
+	s.Topics = topics
+}
+
+// Validate reports the first problem with s wrapped in ErrInvalidSource.
+// It covers what every kind shares: rss must be an http(s) URL unless its
+// scheme is one of schemes, which kinds reading files or mailboxes pass.
+// crawler.ValidateSource also checks the URL and options against the kind.
+func (s Source) Validate(schemes ...string) error {
+	if s.ID == "" {
+		return fmt.Errorf("%w: id is required", ErrInvalidSource)
+	}
+	for _, ch := range s.ID {
+		if !(ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' || ch == '-' || ch == '_') {
+			return fmt.Errorf("%w: id must contain only a-z, 0-9, '-' or '_'", ErrInvalidSource)
+		}
+	}
+	if s.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidSource)
+	}
+	if err := validateFeedURL(s.FeedURL, schemes); err != nil {
+		return err
+	}
+	if s.BaseAuthority < 0 || s.BaseAuthority > 1 {
+		return fmt.Errorf("%w: base_authority must be within [0,1]", ErrInvalidSource)
+	}
+	if s.Options != nil && !bytes.HasPrefix(bytes.TrimSpace(s.Options), []byte("{")) {
+		return fmt.Errorf("%w: options must be a JSON object", ErrInvalidSource)
+	}
+	if s.RetentionDays < 0 || s.RetentionDays > maxRetentionDays {
+		return fmt.Errorf("%w: retention_days must be within [0,%d]", ErrInvalidSource, maxRetentionDays)
+	}
+	return nil
+}
+
+func validateFeedURL(raw string, schemes []string) error {
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" || u.Scheme == "" {
+		return fmt.Errorf("%w: rss must be an absolute URL", ErrInvalidSource)
+	}
+	if u.Scheme == "http" || u.Scheme == "https" || slices.Contains(schemes, u.Scheme) {
+		return nil
+	}
+	if len(schemes) == 0 {
+		return fmt.Errorf("%w: rss must use http or https", ErrInvalidSource)
+	}
+	return fmt.Errorf("%w: rss must use http, https or %s", ErrInvalidSource, strings.Join(schemes, ", "))
+}
@@ -0,0 +1,41 @@
+package news
+
+import "time"
+
+// SourceHealth is the crawl state of one source, updated after every run.
+type SourceHealth struct {
+	SourceID            string     `json:"source_id"`
+	LastAttemptAt       *time.Time `json:"last_attempt_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	NewestArticleAt     *time.Time `json:"newest_article_at,omitempty"`
+	// PollIntervalSec is the interval learned from the source's publishing
+	// cadence; NextPollAt is when the scheduler polls it next.
+	PollIntervalSec int        `json:"poll_interval_sec,omitempty"`
+	NextPollAt      *time.Time `json:"next_poll_at,omitempty"`
+}
+
+// Observe folds a finished run into h. newest is the latest publish time in
+// the fetched items and is ignored when zero or older than what was seen.
+func (h *SourceHealth) Observe(run CrawlRun, newest time.Time) {
+	at := run.StartedAt
+	if run.FinishedAt != nil {
+		at = *run.FinishedAt
+	}
+	h.LastAttemptAt = &at
+	if run.Status != CrawlRunOK {
+		h.ConsecutiveFailures++
+		if len(run.Errors) > 0 {
+			h.LastError = run.Errors[len(run.Errors)-1]
+		}
+		return
+	}
+	h.LastSuccessAt = &at
+	h.ConsecutiveFailures = 0
+	h.LastError = ""
+	if !newest.IsZero() && (h.NewestArticleAt == nil || newest.After(*h.NewestArticleAt)) {
+		n := newest.UTC()
+		h.NewestArticleAt = &n
+	}
+}
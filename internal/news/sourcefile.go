@@ -0,0 +1,22 @@
+package news
+
+import "time"
+
+const (
+	SourceFileProcessed = "processed"
+	SourceFileFailed    = "failed"
+)
+
+// SourceFile is a file a file source read from its directory. Files are
+// keyed by the SHA-256 of their content, so a copy uploaded again under
+// another name is not ingested twice, and a file that failed to parse is
+// only retried once its content changes.
+type SourceFile struct {
+	SourceID    string    `json:"source_id"`
+	Checksum    string    `json:"checksum"`
+	Name        string    `json:"name"`
+	Status      string    `json:"status"`
+	Items       int       `json:"items"`
+	Error       string    `json:"error,omitempty"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
@@ -0,0 +1,24 @@
+package news
+
+import "time"
+
+const (
+	WebSubPending = "pending"
+	WebSubActive  = "active"
+	WebSubDenied  = "denied"
+)
+
+// WebSubSubscription is a source's push subscription at the hub its feed
+// advertises. The secret signs pushed content and never leaves the server.
+type WebSubSubscription struct {
+	SourceID     string     `json:"source_id"`
+	Hub          string     `json:"hub"`
+	Topic        string     `json:"topic"`
+	Secret       string     `json:"-"`
+	State        string     `json:"state"`
+	LeaseSeconds int        `json:"lease_seconds"`
+	RequestedAt  time.Time  `json:"requested_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	LastPushAt   *time.Time `json:"last_push_at,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+}
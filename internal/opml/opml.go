@@ -0,0 +1,266 @@
+// Package opml converts between OPML subscription lists and sources.
+//
+// Feed outlines may carry the data/sources.json fields as extra attributes
+// (id, country, base_authority, topics as a comma-separated list, enabled,
+// extract_full_text), so an export can be imported again without losing
+// them. Folder outlines become topics of the feeds inside them.
+package opml
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"news-go/internal/crawler"
+	"news-go/internal/news"
+	"news-go/internal/storage"
+)
+
+type document struct {
+	XMLName xml.Name  `xml:"opml"`
+	Version string    `xml:"version,attr"`
+	Title   string    `xml:"head>title"`
+	Created string    `xml:"head>dateCreated,omitempty"`
+	Body    []outline `xml:"body>outline"`
+}
+
+type outline struct {
+	Text          string    `xml:"text,attr"`
+	Title         string    `xml:"title,attr,omitempty"`
+	Type          string    `xml:"type,attr,omitempty"`
+	XMLURL        string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL       string    `xml:"htmlUrl,attr,omitempty"`
+	ID            string    `xml:"id,attr,omitempty"`
+	Country       string    `xml:"country,attr,omitempty"`
+	BaseAuthority string    `xml:"base_authority,attr,omitempty"`
+	Topics        string    `xml:"topics,attr,omitempty"`
+	Enabled       string    `xml:"enabled,attr,omitempty"`
+	ExtractFull   string    `xml:"extract_full_text,attr,omitempty"`
+	Outlines      []outline `xml:"outline"`
+}
+
+var ErrInvalidOPML = errors.New("invalid opml")
+
+// Defaults fill in what an outline does not say. Topics are added to every
+// imported source.
+type Defaults struct {
+	BaseAuthority float64
+	Topics        []string
+}
+
+// Parse reads the feed outlines of an OPML document as sources. IDs are
+// taken from the id attribute or derived from the title or feed host; they
+// are not yet checked against existing sources.
+func Parse(r io.Reader, d Defaults) ([]news.Source, error) {
+	var doc document
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOPML, err)
+	}
+	var out []news.Source
+	var walk func(items []outline, folders []string)
+	walk = func(items []outline, folders []string) {
+		for _, o := range items {
+			if strings.TrimSpace(o.XMLURL) == "" {
+				name := firstNonEmpty(o.Title, o.Text)
+				walk(o.Outlines, appendNonEmpty(folders, name))
+				continue
+			}
+			out = append(out, o.source(folders, d))
+		}
+	}
+	walk(doc.Body, nil)
+	return out, nil
+}
+
+func (o outline) source(folders []string, d Defaults) news.Source {
+	feed := strings.TrimSpace(o.XMLURL)
+	src := news.Source{
+		ID:            strings.TrimSpace(o.ID),
+		Name:          firstNonEmpty(o.Title, o.Text, hostOf(feed)),
+		Country:       o.Country,
+		FeedURL:       feed,
+		BaseAuthority: d.BaseAuthority,
+		Enabled:       true,
+	}
+	if v, err := strconv.ParseFloat(strings.TrimSpace(o.BaseAuthority), 64); err == nil {
+		src.BaseAuthority = v
+	}
+	if v, err := strconv.ParseBool(strings.TrimSpace(o.Enabled)); err == nil {
+		src.Enabled = v
+	}
+	if v, err := strconv.ParseBool(strings.TrimSpace(o.ExtractFull)); err == nil {
+		src.ExtractFullText = v
+	}
+	src.Topics = append(src.Topics, folders...)
+	src.Topics = append(src.Topics, strings.Split(o.Topics, ",")...)
+	src.Topics = append(src.Topics, d.Topics...)
+	if src.ID == "" {
+		src.ID = Slug(src.Name)
+	}
+	if src.ID == "" {
+		src.ID = Slug(hostOf(feed))
+	}
+	src.Normalize()
+	return src
+}
+
+// Slug lower-cases s and replaces runs of anything but a-z and 0-9 with a
+// single "-". Names without Latin letters or digits yield "".
+func Slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+func hostOf(feed string) string {
+	u, err := url.Parse(feed)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func appendNonEmpty(list []string, v string) []string {
+	if v == "" {
+		return list
+	}
+	return append(append([]string(nil), list...), v)
+}
+
+// ImportResult reports what Import did with each feed outline.
+type ImportResult struct {
+	Created []news.Source  `json:"created"`
+	Skipped []SkippedEntry `json:"skipped"`
+}
+
+type SkippedEntry struct {
+	ID      string `json:"id"`
+	FeedURL string `json:"rss"`
+	Reason  string `json:"reason"`
+}
+
+// Import creates a source for every feed in r whose URL is not already
+// subscribed and that validates within access. An ID that is taken by
+// another feed gets a numeric suffix.
+func Import(ctx context.Context, repo storage.SourceRepository, r io.Reader, d Defaults, access crawler.LocalAccess) (ImportResult, error) {
+	res := ImportResult{Created: []news.Source{}, Skipped: []SkippedEntry{}}
+	items, err := Parse(r, d)
+	if err != nil {
+		return res, err
+	}
+	existing, err := repo.ListSources(ctx)
+	if err != nil {
+		return res, err
+	}
+	ids, feeds := map[string]bool{}, map[string]bool{}
+	for _, s := range existing {
+		ids[s.ID] = true
+		feeds[s.FeedURL] = true
+	}
+	for _, src := range items {
+		if feeds[src.FeedURL] {
+			res.Skipped = append(res.Skipped, SkippedEntry{ID: src.ID, FeedURL: src.FeedURL, Reason: "feed already subscribed"})
+			continue
+		}
+		for base, n := src.ID, 2; src.ID != "" && ids[src.ID]; n++ {
+			src.ID = fmt.Sprintf("%s-%d", base, n)
+		}
+		if err := crawler.ValidateSource(src, access); err != nil {
+			res.Skipped = append(res.Skipped, SkippedEntry{ID: src.ID, FeedURL: src.FeedURL, Reason: err.Error()})
+			continue
+		}
+		created, err := repo.CreateSource(ctx, src)
+		if err != nil {
+			if errors.Is(err, storage.ErrConflict) {
+				res.Skipped = append(res.Skipped, SkippedEntry{ID: src.ID, FeedURL: src.FeedURL, Reason: "source already exists"})
+				continue
+			}
+			return res, err
+		}
+		ids[created.ID], feeds[created.FeedURL] = true, true
+		res.Created = append(res.Created, created)
+	}
+	return res, nil
+}
+
+// Write renders feed sources as OPML 2.0, grouped into folders by their
+// first topic. Only rss and atom sources are feeds a reader can subscribe
+// to; other kinds are left out.
+func Write(w io.Writer, sources []news.Source, now time.Time) error {
+	doc := document{Version: "2.0", Title: "news-go sources", Created: now.UTC().Format(time.RFC1123Z)}
+	folders := map[string]*outline{}
+	var names []string
+	var loose []outline
+	for _, s := range sources {
+		if s.Kind != "" && s.Kind != news.KindRSS && s.Kind != crawler.KindAtom {
+			continue
+		}
+		o := outline{
+			Text:          s.Name,
+			Title:         s.Name,
+			Type:          "rss",
+			XMLURL:        s.FeedURL,
+			ID:            s.ID,
+			Country:       s.Country,
+			BaseAuthority: strconv.FormatFloat(s.BaseAuthority, 'f', -1, 64),
+			Topics:        strings.Join(s.Topics, ","),
+			Enabled:       strconv.FormatBool(s.Enabled),
+		}
+		if s.ExtractFullText {
+			o.ExtractFull = "true"
+		}
+		if len(s.Topics) == 0 {
+			loose = append(loose, o)
+			continue
+		}
+		f, ok := folders[s.Topics[0]]
+		if !ok {
+			f = &outline{Text: s.Topics[0], Title: s.Topics[0]}
+			folders[s.Topics[0]] = f
+			names = append(names, s.Topics[0])
+		}
+		f.Outlines = append(f.Outlines, o)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		doc.Body = append(doc.Body, *folders[n])
+	}
+	doc.Body = append(doc.Body, loose...)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,117 @@
+package opml
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"news-go/internal/crawler"
+	"news-go/internal/news"
+	"news-go/internal/storage"
+)
+
+const sample = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="1.0">
+  <head><title>My feeds</title></head>
+  <body>
+    <outline text="Tech">
+      <outline text="AI">
+        <outline type="rss" text="The Verge" xmlUrl="https://www.theverge.com/rss/index.xml"/>
+      </outline>
+      <outline type="rss" title="Example" text="ignored" xmlUrl="https://example.com/feed" base_authority="0.9" topics="Cloud, tech"/>
+    </outline>
+    <outline type="rss" text="新闻" xmlUrl="https://www.news.cn/rss.xml" enabled="false"/>
+    <outline type="rss" text="Bad" xmlUrl="ftp://example.org/feed"/>
+  </body>
+</opml>`
+
+func TestParseFoldersBecomeTopics(t *testing.T) {
+	items, err := Parse(strings.NewReader(sample), Defaults{BaseAuthority: 0.5, Topics: []string{"imported"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("got %d feeds, want 4", len(items))
+	}
+	verge := items[0]
+	if verge.ID != "the-verge" || verge.BaseAuthority != 0.5 || !verge.Enabled {
+		t.Errorf("verge: %+v", verge)
+	}
+	if want := []string{"tech", "ai", "imported"}; !reflect.DeepEqual(verge.Topics, want) {
+		t.Errorf("verge topics = %v, want %v", verge.Topics, want)
+	}
+	ex := items[1]
+	if ex.ID != "example" || ex.Name != "Example" || ex.BaseAuthority != 0.9 {
+		t.Errorf("example: %+v", ex)
+	}
+	if want := []string{"tech", "cloud", "imported"}; !reflect.DeepEqual(ex.Topics, want) {
+		t.Errorf("example topics = %v, want %v", ex.Topics, want)
+	}
+	if cn := items[2]; cn.ID != "news-cn" || cn.Enabled {
+		t.Errorf("non-Latin title should fall back to the host: %+v", cn)
+	}
+}
+
+func TestParseRejectsNonXML(t *testing.T) {
+	if _, err := Parse(strings.NewReader("not opml"), Defaults{}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestImportSkipsKnownFeedsAndSuffixesIDs(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewMemorySourceRepository()
+	for _, src := range []news.Source{
+		{ID: "example", Name: "Other", FeedURL: "https://other.example/rss", Enabled: true},
+		{ID: "verge", Name: "Verge", FeedURL: "https://www.theverge.com/rss/index.xml", Enabled: true},
+	} {
+		if _, err := repo.CreateSource(ctx, src); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res, err := Import(ctx, repo, strings.NewReader(sample), Defaults{BaseAuthority: 0.5}, crawler.LocalAccess{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created []string
+	for _, s := range res.Created {
+		created = append(created, s.ID)
+	}
+	if want := []string{"example-2", "news-cn"}; !reflect.DeepEqual(created, want) {
+		t.Errorf("created %v, want %v", created, want)
+	}
+	if len(res.Skipped) != 2 {
+		t.Fatalf("skipped %+v, want the subscribed feed and the ftp one", res.Skipped)
+	}
+	if res.Skipped[0].Reason != "feed already subscribed" {
+		t.Errorf("skip reason: %+v", res.Skipped[0])
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	in := []news.Source{
+		{ID: "b", Name: "B & Co", Country: "US", FeedURL: "https://b.example/rss?x=1&y=2", BaseAuthority: 0.75, Topics: []string{"world", "politics"}, Enabled: true, ExtractFullText: true},
+		{ID: "a", Name: "A", FeedURL: "https://a.example/rss", BaseAuthority: 0.4, Topics: []string{}, Enabled: false},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, in, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Parse(&buf, Defaults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("round trip gave %d sources", len(out))
+	}
+	for i, want := range []news.Source{in[0], in[1]} {
+		got := out[i]
+		if got.ID != want.ID || got.Name != want.Name || got.Country != want.Country || got.FeedURL != want.FeedURL ||
+			got.BaseAuthority != want.BaseAuthority || got.Enabled != want.Enabled || got.ExtractFullText != want.ExtractFullText || !reflect.DeepEqual(got.Topics, want.Topics) {
+			t.Errorf("round trip %d:\n got %+v\nwant %+v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Package retention prunes articles outside the configured retention
+// policy. Pruned articles are written, with their revisions, to
+// gzip-compressed NDJSON archives before they are deleted.
+package retention
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"news-go/internal/news"
+	"news-go/internal/storage"
+)
+
+const (
+	// batchSize is how many articles are archived and deleted at a time.
+	batchSize = 500
+	// sampleSize is how many articles a report lists.
+	sampleSize = 20
+)
+
+// Job prunes the article store. Policy is combined with the sources'
+// own retention_days at each run.
+type Job struct {
+	Policy    storage.RetentionPolicy
+	Articles  storage.ArticleRepository
+	Expired   storage.ArticleRetentionRepository
+	Revisions storage.ArticleRevisionRepository
+	Sources   storage.SourceRepository
+	// Dir receives one archive per run that deletes anything.
+	Dir string
+	// MaxPerRun caps the articles removed by one run; 0 means no cap.
+	MaxPerRun int
+
+	mu sync.Mutex
+}
+
+// Report describes what a run removed, or would remove when DryRun.
+type Report struct {
+	DryRun   bool           `json:"dry_run"`
+	Articles int            `json:"articles"`
+	BySource map[string]int `json:"by_source"`
+	// Oldest and Newest are the publication dates of the articles.
+	Oldest  *time.Time `json:"oldest,omitempty"`
+	Newest  *time.Time `json:"newest,omitempty"`
+	Archive string     `json:"archive,omitempty"`
+	// Sample lists the first articles, least recently seen first.
+	Sample []SampleItem `json:"sample"`
+}
+
+type SampleItem struct {
+	ID          int64     `json:"id"`
+	Source      string    `json:"source"`
+	Title       string    `json:"title"`
+	PublishedAt time.Time `json:"published_at"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// archived is one archive line: the article as the API shows it, body
+// included, plus its earlier versions.
+type archived struct {
+	news.Article
+	Revisions []news.ArticleRevision `json:"revisions,omitempty"`
+}
+
+// Run applies the policy once. A dry run only reports. Runs do not
+// overlap; a second caller waits for the first.
+func (j *Job) Run(ctx context.Context, now time.Time, dryRun bool) (Report, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	rep := Report{DryRun: dryRun, BySource: map[string]int{}, Sample: []SampleItem{}}
+	policy, err := j.policy(ctx)
+	if err != nil || policy.IsZero() {
+		return rep, err
+	}
+	if dryRun {
+		items, err := j.Expired.ExpiredArticles(ctx, policy, now, j.MaxPerRun)
+		if err != nil {
+			return rep, err
+		}
+		rep.add(items)
+		return rep, nil
+	}
+	path := filepath.Join(j.Dir, "articles-"+now.UTC().Format("20060102T150405Z")+".ndjson.gz")
+	for j.MaxPerRun <= 0 || rep.Articles < j.MaxPerRun {
+		limit := batchSize
+		if j.MaxPerRun > 0 && j.MaxPerRun-rep.Articles < limit {
+			limit = j.MaxPerRun - rep.Articles
+		}
+		items, err := j.Expired.ExpiredArticles(ctx, policy, now, limit)
+		if err != nil || len(items) == 0 {
+			return rep, err
+		}
+		if err := j.archive(ctx, path, items); err != nil {
+			return rep, err
+		}
+		rep.Archive = path
+		ids := make([]int64, len(items))
+		for i, a := range items {
+			ids[i] = a.ID
+		}
+		if _, err := j.Articles.DeleteArticles(ctx, ids); err != nil {
+			return rep, err
+		}
+		rep.add(items)
+		if len(items) < limit {
+			break
+		}
+	}
+	return rep, nil
+}
+
+// policy adds the sources' retention_days to the configured policy.
+func (j *Job) policy(ctx context.Context) (storage.RetentionPolicy, error) {
+	p := j.Policy
+	if j.Sources == nil {
+		return p, nil
+	}
+	sources, err := j.Sources.ListSources(ctx)
+	if err != nil {
+		return p, err
+	}
+	p.SourceMaxAge = map[string]time.Duration{}
+	for _, src := range sources {
+		if src.RetentionDays > 0 {
+			p.SourceMaxAge[src.ID] = time.Duration(src.RetentionDays) * 24 * time.Hour
+		}
+	}
+	if len(p.SourceMaxAge) == 0 {
+		p.SourceMaxAge = nil
+	}
+	return p, nil
+}
+
+// archive appends items to path as one gzip member and syncs it, so the
+// articles are on disk before they are deleted. A file of several members
+// reads as one stream with gzip -d or Go's gzip.Reader.
+func (j *Job) archive(ctx context.Context, path string, items []news.Article) error {
+	if err := os.MkdirAll(j.Dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+	for _, a := range items {
+		line := archived{Article: a}
+		if j.Revisions != nil {
+			if line.Revisions, err = j.Revisions.ArticleRevisions(ctx, a.ID); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(line); err != nil {
+			return fmt.Errorf("archive %s: %w", path, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("archive %s: %w", path, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("archive %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+func (r *Report) add(items []news.Article) {
+	for _, a := range items {
+		r.Articles++
+		r.BySource[a.Source]++
+		if published := a.PublishedAt; r.Oldest == nil || published.Before(*r.Oldest) {
+			r.Oldest = &published
+		}
+		if published := a.PublishedAt; r.Newest == nil || published.After(*r.Newest) {
+			r.Newest = &published
+		}
+		if len(r.Sample) < sampleSize {
+			r.Sample = append(r.Sample, SampleItem{ID: a.ID, Source: a.Source, Title: a.Title, PublishedAt: a.PublishedAt, FetchedAt: a.FetchedAt})
+		}
+	}
+}
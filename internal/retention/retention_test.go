@@ -0,0 +1,170 @@
+package retention
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"news-go/internal/news"
+	"news-go/internal/storage"
+)
+
+func newJob(t *testing.T, policy storage.RetentionPolicy) (*Job, *storage.MemoryArticleRepository, time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	articles := storage.NewMemoryArticleRepository()
+	sources := storage.NewMemorySourceRepository()
+	for _, id := range []string{"bbc", "hn"} {
+		src := news.Source{ID: id, Name: id, FeedURL: "https://" + id + ".test/feed", Enabled: true}
+		if id == "hn" {
+			src.RetentionDays = 2
+		}
+		if _, err := sources.CreateSource(ctx, src); err != nil {
+			t.Fatalf("create source: %v", err)
+		}
+	}
+	// Each source has one article per day over the last five days.
+	var items []news.Article
+	for day := 0; day < 5; day++ {
+		for _, src := range []string{"bbc", "hn"} {
+			at := now.Add(-time.Duration(day)*24*time.Hour - time.Hour)
+			items = append(items, news.Article{Title: fmt.Sprintf("%s day %d", src, day), URL: fmt.Sprintf("https://%s.test/%d", src, day), Source: src, PublishedAt: at, FetchedAt: at})
+		}
+	}
+	if _, err := articles.UpsertArticles(ctx, items); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	return &Job{Policy: policy, Articles: articles, Expired: articles, Revisions: articles, Sources: sources, Dir: t.TempDir()}, articles, now
+}
+
+func count(t *testing.T, repo storage.ArticleRepository) int {
+	items, err := repo.ListArticles(context.Background(), storage.ListOptions{Limit: 100})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	return len(items)
+}
+
+func TestRunDryRun(t *testing.T) {
+	job, articles, now := newJob(t, storage.RetentionPolicy{MaxAge: 72 * time.Hour})
+	rep, err := job.Run(context.Background(), now, true)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	// bbc keeps three days, hn two by its own retention_days.
+	if !rep.DryRun || rep.Articles != 5 || rep.BySource["bbc"] != 2 || rep.BySource["hn"] != 3 || rep.Archive != "" {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	if rep.Sample[0].Title != "bbc day 4" && rep.Sample[0].Title != "hn day 4" {
+		t.Fatalf("sample not least recent first: %+v", rep.Sample)
+	}
+	if n := count(t, articles); n != 10 {
+		t.Fatalf("dry run deleted articles: %d left", n)
+	}
+}
+
+func TestRunArchivesBeforeDeleting(t *testing.T) {
+	job, articles, now := newJob(t, storage.RetentionPolicy{MaxPerSource: 4, MaxRows: 7})
+	job.MaxPerRun = 2
+	rep, err := job.Run(context.Background(), now, false)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if rep.Articles != 2 || count(t, articles) != 8 {
+		t.Fatalf("MaxPerRun ignored: %+v", rep)
+	}
+	job.MaxPerRun = 0
+	rep, err = job.Run(context.Background(), now.Add(time.Second), false)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	// Both rules drop bbc day 3 and hn day 3; hn's retention_days drops
+	// hn day 2 as well.
+	if rep.Articles != 3 || count(t, articles) != 5 || rep.BySource["hn"] != 2 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+
+	f, err := os.Open(rep.Archive)
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	var lines []news.Article
+	sc := bufio.NewScanner(zr)
+	for sc.Scan() {
+		var a news.Article
+		if err := json.Unmarshal(sc.Bytes(), &a); err != nil {
+			t.Fatalf("decode %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, a)
+	}
+	if len(lines) != 3 || lines[2].Title != "hn day 2" {
+		t.Fatalf("archived %+v", lines)
+	}
+}
+
+func TestRunKeepsEverythingWithoutPolicy(t *testing.T) {
+	job, articles, now := newJob(t, storage.RetentionPolicy{})
+	job.Sources = nil
+	rep, err := job.Run(context.Background(), now, false)
+	if err != nil || rep.Articles != 0 || count(t, articles) != 10 {
+		t.Fatalf("report %+v, err %v", rep, err)
+	}
+}
+
+// A per-source limit below the feed's size must not delete articles the
+// feed still carries: the next poll would insert them again as new.
+func TestRunKeepsLatestFetch(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	articles := storage.NewMemoryArticleRepository()
+	sources := storage.NewMemorySourceRepository()
+	if _, err := sources.CreateSource(ctx, news.Source{ID: "bbc", Name: "bbc", FeedURL: "https://bbc.test/feed", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	feed := func(fetched time.Time, days ...int) []news.Article {
+		var items []news.Article
+		for _, day := range days {
+			items = append(items, news.Article{Title: fmt.Sprintf("day %d", day), URL: fmt.Sprintf("https://bbc.test/%d", day), Source: "bbc",
+				PublishedAt: now.Add(-time.Duration(day) * 24 * time.Hour), FetchedAt: fetched})
+		}
+		return items
+	}
+	job := &Job{Policy: storage.RetentionPolicy{MaxPerSource: 2}, Articles: articles, Expired: articles, Revisions: articles, Sources: sources, Dir: t.TempDir()}
+
+	for poll := 0; poll < 3; poll++ {
+		res, err := articles.UpsertArticles(ctx, feed(now.Add(time.Duration(poll)*time.Hour), 1, 2, 3, 4, 5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if poll > 0 && res.Inserted != 0 {
+			t.Fatalf("poll %d re-inserted %d pruned articles", poll, res.Inserted)
+		}
+		rep, err := job.Run(ctx, now.Add(time.Duration(poll)*time.Hour), false)
+		if err != nil || rep.Articles != 0 {
+			t.Fatalf("poll %d pruned %d articles, %v", poll, rep.Articles, err)
+		}
+	}
+
+	// Once the feed moves on, the limit applies to what it dropped.
+	if _, err := articles.UpsertArticles(ctx, feed(now.Add(4*time.Hour), 0)); err != nil {
+		t.Fatal(err)
+	}
+	rep, err := job.Run(ctx, now.Add(4*time.Hour), false)
+	if err != nil || rep.Articles != 4 {
+		t.Fatalf("pruned %d articles, %v", rep.Articles, err)
+	}
+	if n := count(t, articles); n != 2 {
+		t.Fatalf("%d articles left", n)
+	}
+}
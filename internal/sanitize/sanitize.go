@@ -0,0 +1,143 @@
+// Package sanitize turns untrusted feed HTML into an allow-listed HTML
+// fragment that is safe to render, and into plain text for search.
+package sanitize
+
+import (
+	"html"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"news-go/internal/htmldoc"
+)
+
+// droppedTags are removed together with everything inside them.
+var droppedTags = []string{
+	"script", "style", "iframe", "frame", "frameset", "object", "embed", "applet", "noscript",
+	"template", "form", "button", "input", "select", "textarea", "svg", "math", "head", "title",
+	"link", "meta", "base",
+}
+
+// allowedTags maps each kept element to the attributes it may carry. Other
+// elements are unwrapped: their children are kept, the tag is not.
+var allowedTags = map[string][]string{
+	"a": {"href", "title"}, "abbr": {"title"}, "b": nil, "blockquote": {"cite"}, "br": nil,
+	"code": nil, "div": nil, "em": nil, "figcaption": nil, "figure": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil, "hr": nil, "i": nil,
+	"img": {"src", "alt", "title", "width", "height"}, "li": nil, "ol": nil, "p": nil, "pre": nil,
+	"q": {"cite"}, "s": nil, "small": nil, "strong": nil, "sub": nil, "sup": nil,
+	"table": nil, "tbody": nil, "td": {"colspan", "rowspan"}, "th": {"colspan", "rowspan"},
+	"thead": nil, "tr": nil, "u": nil, "ul": nil,
+}
+
+var urlAttrs = map[string]bool{"href": true, "src": true, "cite": true}
+
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true}
+
+// HTML returns the allow-listed form of src. Relative links are resolved
+// against baseURL; links that are not http(s) (or mailto for anchors) are
+// dropped, and anchors get rel="nofollow noopener".
+func HTML(src, baseURL string) string {
+	base, _ := url.Parse(baseURL)
+	doc := parse(src)
+	var b strings.Builder
+	for _, c := range doc.Children {
+		writeNode(&b, c, base)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Text returns the plain text of src: entities decoded, markup and
+// script-like content removed, whitespace collapsed and blocks separated by
+// single line breaks.
+func Text(src string) string {
+	return parse(src).InnerText()
+}
+
+// Inline cleans a plain-text field such as a title, author or category:
+// control characters are dropped and whitespace is collapsed onto one
+// line. It does not parse markup, so "a<b" and "<5" are kept as written.
+func Inline(src string) string {
+	src = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, src)
+	return strings.Join(strings.Fields(src), " ")
+}
+
+func parse(src string) *htmldoc.Node {
+	doc := htmldoc.ParseString(src)
+	for _, n := range doc.FindAll(droppedTags...) {
+		n.Remove()
+	}
+	return doc
+}
+
+func writeNode(b *strings.Builder, n *htmldoc.Node, base *url.URL) {
+	switch n.Type {
+	case htmldoc.TextNode:
+		b.WriteString(html.EscapeString(n.Text))
+		return
+	case htmldoc.CommentNode:
+		return
+	}
+	allowed, ok := allowedTags[n.Tag]
+	if !ok {
+		for _, c := range n.Children {
+			writeNode(b, c, base)
+		}
+		return
+	}
+	var attrs strings.Builder
+	for _, key := range allowed {
+		v, present := n.Attr(key)
+		if !present {
+			continue
+		}
+		if urlAttrs[key] {
+			if v = safeURL(v, base, n.Tag == "a"); v == "" {
+				continue
+			}
+		}
+		attrs.WriteString(" " + key + `="` + html.EscapeString(v) + `"`)
+	}
+	if n.Tag == "img" && !strings.Contains(attrs.String(), ` src="`) {
+		return
+	}
+	if n.Tag == "a" {
+		attrs.WriteString(` rel="nofollow noopener"`)
+	}
+	b.WriteString("<" + n.Tag + attrs.String() + ">")
+	if voidTags[n.Tag] {
+		return
+	}
+	for _, c := range n.Children {
+		writeNode(b, c, base)
+	}
+	b.WriteString("</" + n.Tag + ">")
+}
+
+func safeURL(raw string, base *url.URL, allowMailto bool) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return ""
+		}
+	case "mailto":
+		if !allowMailto {
+			return ""
+		}
+	default:
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,45 @@
+package sanitize
+
+import "testing"
+
+func TestHTML(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		{"keeps allowed markup", `<p>Hello <b>world</b></p>`, `<p>Hello <b>world</b></p>`},
+		{"drops script and style", `<p>a</p><script>alert(1)</script><style>p{}</style>b`, `<p>a</p>b`},
+		{"drops iframes with content", `x<iframe src="https://evil.test">fallback</iframe>y`, `xy`},
+		{"strips event handlers", `<img src="https://x.test/a.png" onerror="alert(1)" alt="a">`, `<img src="https://x.test/a.png" alt="a">`},
+		{"drops javascript links", `<a href=" JavaScript:alert(1)" onclick="x()">click</a>`, `<a rel="nofollow noopener">click</a>`},
+		{"resolves relative links", `<a href="/story?id=1&amp;p=2">more</a>`, `<a href="https://news.test/story?id=1&amp;p=2" rel="nofollow noopener">more</a>`},
+		{"unwraps unknown tags", `<font color="red"><span style="x">hi</span></font>`, `hi`},
+		{"drops images without safe src", `<img src="data:image/png;base64,xx">ok`, `ok`},
+		{"escapes text", `1 &lt; 2 &amp;&amp; "q"`, `1 &lt; 2 &amp;&amp; &#34;q&#34;`},
+	}
+	for _, c := range cases {
+		if got := HTML(c.in, "https://news.test/feed.xml"); got != c.want {
+			t.Errorf("%s: HTML(%q) = %q, want %q", c.name, c.in, got, c.want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	in := "<p>First&nbsp;line &amp; more</p>\n<script>var x;</script><p>Second\t\tline<br>third</p><iframe>x</iframe>"
+	if got, want := Text(in), "First line & more\nSecond line\nthird"; got != want {
+		t.Errorf("Text = %q, want %q", got, want)
+	}
+}
+
+func TestInline(t *testing.T) {
+	for in, want := range map[string]string{
+		"Breaking:\n AT&T  deal\x00": "Breaking: AT&T deal",
+		"<":                          "<",
+		"Profits up <5% this year":   "Profits up <5% this year",
+		"Why a<b matters":            "Why a<b matters",
+		"Using <b> for bold":         "Using <b> for bold",
+	} {
+		if got := Inline(in); got != want {
+			t.Errorf("Inline(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
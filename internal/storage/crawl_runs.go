@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"news-go/internal/news"
+)
+
+type CrawlRunListOptions struct {
+	Limit    int
+	SourceID string
+}
+
+type CrawlRunRepository interface {
+	CreateCrawlRun(ctx context.Context, run news.CrawlRun) (news.CrawlRun, error)
+	UpdateCrawlRun(ctx context.Context, run news.CrawlRun) error
+	ListCrawlRuns(ctx context.Context, opts CrawlRunListOptions) ([]news.CrawlRun, error)
+}
+
+// memoryCrawlRunLimit bounds the in-memory history; SQLite keeps everything.
+const memoryCrawlRunLimit = 500
+
+type MemoryCrawlRunRepository struct {
+	mu     sync.RWMutex
+	runs   []news.CrawlRun
+	nextID int64
+}
+
+func NewMemoryCrawlRunRepository() *MemoryCrawlRunRepository {
+	return &MemoryCrawlRunRepository{}
+}
+
+func (r *MemoryCrawlRunRepository) CreateCrawlRun(_ context.Context, run news.CrawlRun) (news.CrawlRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	run.ID = r.nextID
+	r.runs = append(r.runs, run)
+	if len(r.runs) > memoryCrawlRunLimit {
+		r.runs = r.runs[len(r.runs)-memoryCrawlRunLimit:]
+	}
+	return run, nil
+}
+
+func (r *MemoryCrawlRunRepository) UpdateCrawlRun(_ context.Context, run news.CrawlRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.runs {
+		if r.runs[i].ID == run.ID {
+			r.runs[i] = run
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryCrawlRunRepository) ListCrawlRuns(_ context.Context, opts CrawlRunListOptions) ([]news.CrawlRun, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := []news.CrawlRun{}
+	for i := len(r.runs) - 1; i >= 0 && len(items) < opts.Limit; i-- {
+		if opts.SourceID != "" && r.runs[i].SourceID != opts.SourceID {
+			continue
+		}
+		items = append(items, r.runs[i])
+	}
+	return items, nil
+}
+
+type SQLiteCrawlRunRepository struct{ dbPath string }
+
+func NewSQLiteCrawlRunRepository(dbPath string) *SQLiteCrawlRunRepository {
+	return &SQLiteCrawlRunRepository{dbPath: dbPath}
+}
+
+type crawlRunRow struct {
+	ID         int64  `json:"id"`
+	SourceID   string `json:"source_id"`
+	Trigger    string `json:"trigger"`
+	Status     string `json:"status"`
+	StartedAt  string `json:"started_at"`
+	FinishedAt string `json:"finished_at"`
+	Attempts   int    `json:"attempts"`
+	Fetched    int    `json:"fetched"`
+	Inserted   int    `json:"inserted"`
+	Updated    int    `json:"updated"`
+	Errors     string `json:"errors"`
+	Truncated  int    `json:"truncated"`
+	Rejected   string `json:"rejected"`
+}
+
+func (row crawlRunRow) run() news.CrawlRun {
+	run := news.CrawlRun{
+		ID:        row.ID,
+		SourceID:  row.SourceID,
+		Trigger:   row.Trigger,
+		Status:    row.Status,
+		Attempts:  row.Attempts,
+		Fetched:   row.Fetched,
+		Inserted:  row.Inserted,
+		Updated:   row.Updated,
+		Errors:    []string{},
+		Truncated: row.Truncated,
+		Rejected:  row.Rejected,
+	}
+	run.StartedAt, _ = time.Parse(time.RFC3339, row.StartedAt)
+	run.FinishedAt = parseOptionalTime(row.FinishedAt)
+	_ = json.Unmarshal([]byte(row.Errors), &run.Errors)
+	return run
+}
+
+func (r *SQLiteCrawlRunRepository) CreateCrawlRun(_ context.Context, run news.CrawlRun) (news.CrawlRun, error) {
+	q := fmt.Sprintf("INSERT INTO crawl_runs (source_id, trigger, status, started_at, attempts, fetched, inserted, updated, errors, truncated, rejected) VALUES ('%s','%s','%s','%s',%d,%d,%d,%d,'%s',%d,'%s'); SELECT last_insert_rowid() AS id;",
+		esc(run.SourceID), esc(run.Trigger), esc(run.Status), run.StartedAt.UTC().Format(time.RFC3339), run.Attempts, run.Fetched, run.Inserted, run.Updated, esc(stringsJSON(run.Errors)), run.Truncated, esc(run.Rejected))
+	var rows []struct {
+		ID int64 `json:"id"`
+	}
+	if err := querySQLite(r.dbPath, q, &rows); err != nil {
+		return news.CrawlRun{}, err
+	}
+	if len(rows) == 1 {
+		run.ID = rows[0].ID
+	}
+	return run, nil
+}
+
+func (r *SQLiteCrawlRunRepository) UpdateCrawlRun(_ context.Context, run news.CrawlRun) error {
+	q := fmt.Sprintf("UPDATE crawl_runs SET status='%s', finished_at=%s, attempts=%d, fetched=%d, inserted=%d, updated=%d, errors='%s', truncated=%d, rejected='%s' WHERE id=%d;",
+		esc(run.Status), sqlTime(run.FinishedAt), run.Attempts, run.Fetched, run.Inserted, run.Updated, esc(stringsJSON(run.Errors)), run.Truncated, esc(run.Rejected), run.ID)
+	_, err := runSQLite(r.dbPath, q)
+	return err
+}
+
+func (r *SQLiteCrawlRunRepository) ListCrawlRuns(_ context.Context, opts CrawlRunListOptions) ([]news.CrawlRun, error) {
+	conds := []string{"1=1"}
+	if opts.SourceID != "" {
+		conds = append(conds, fmt.Sprintf("source_id = '%s'", esc(opts.SourceID)))
+	}
+	q := fmt.Sprintf("SELECT id, source_id, trigger, status, started_at, COALESCE(finished_at,'') AS finished_at, attempts, fetched, inserted, updated, errors, truncated, rejected FROM crawl_runs WHERE %s ORDER BY id DESC LIMIT %d;", strings.Join(conds, " AND "), opts.Limit)
+	var rows []crawlRunRow
+	if err := querySQLite(r.dbPath, q, &rows); err != nil {
+		return nil, err
+	}
+	items := make([]news.CrawlRun, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row.run())
+	}
+	return items, nil
+}
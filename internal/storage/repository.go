@@ -1,65 +1,113 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"news-go/internal/langid"
 	"news-go/internal/news"
+	"news-go/internal/sanitize"
 )
 
 var ErrNotFound = errors.New("not found")
+var ErrConflict = errors.New("already exists")
+
+// ErrSourceInUse refuses to delete a source that stored articles still
+// name; disabling it keeps them attributed.
+var ErrSourceInUse = errors.New("source has articles")
 var ErrSQLiteBinaryNotFound = errors.New("sqlite3 binary not found")
 
 type ListOptions struct {
-	Limit         int
-	Offset        int
-	Keyword       string
-	Source        string
+	Limit    int
+	Offset   int
+	Keyword  string
+	Source   string
+	Author   string
+	Language string
+	// HasMedia keeps articles with an attachment of that medium
+	// (news.MediumAudio or news.MediumVideo), or with any when it is "any".
+	HasMedia      string
 	PublishedFrom time.Time
 	PublishedTo   time.Time
 }
 
+// UpsertResult counts rows created and existing rows whose fields changed.
+// Revised counts the updates that changed title or content and so stored
+// a revision.
+type UpsertResult struct {
+	Inserted int
+	Updated  int
+	Revised  int
+}
+
 type ArticleRepository interface {
 	ListArticles(ctx context.Context, opts ListOptions) ([]news.Article, error)
 	GetArticleByID(ctx context.Context, id int64) (news.Article, error)
-	UpsertArticles(ctx context.Context, articles []news.Article) error
+	// UpsertArticles dedupes by URL. An article without PublishedAt keeps
+	// its stored date, or is dated by its FetchedAt when new. Changing the
+	// title or content of a stored article keeps the old version as a
+	// revision.
+	UpsertArticles(ctx context.Context, articles []news.Article) (UpsertResult, error)
+	// DeleteArticles removes the articles and their revisions, returning
+	// how many articles existed. A feed still carrying a deleted article
+	// brings it back as new.
+	DeleteArticles(ctx context.Context, ids []int64) (int, error)
 	Ready(ctx context.Context) error
 }
 
+// ArticleBodyRepository stores full text extracted from article pages.
+// Upserts from the feed never touch it, so a body survives feed edits.
+type ArticleBodyRepository interface {
+	// PendingExtractions lists the newest articles of a source that have
+	// not been through extraction yet.
+	PendingExtractions(ctx context.Context, sourceID string, limit int) ([]news.Article, error)
+	SaveExtraction(ctx context.Context, id int64, body, status string) error
+}
+
 type MemoryArticleRepository struct {
-	mu       sync.RWMutex
-	articles []news.Article
+	mu        sync.RWMutex
+	articles  []news.Article
+	revisions map[int64][]news.ArticleRevision
+	revID     int64
 }
 
 func NewMemoryArticleRepository() *MemoryArticleRepository {
-	return &MemoryArticleRepository{articles: []news.Article{}}
+	return &MemoryArticleRepository{articles: []news.Article{}, revisions: map[int64][]news.ArticleRevision{}}
 }
 
 func (r *MemoryArticleRepository) ListArticles(_ context.Context, opts ListOptions) ([]news.Article, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	items := make([]news.Article, 0, len(r.articles))
-	keyword := strings.ToLower(strings.TrimSpace(opts.Keyword))
+	terms := langid.SearchTerms(opts.Keyword)
 	source := strings.ToLower(strings.TrimSpace(opts.Source))
 	for _, a := range r.articles {
-		if keyword != "" {
-			if !strings.Contains(strings.ToLower(a.Title), keyword) && !strings.Contains(strings.ToLower(a.Content), keyword) {
-				continue
-			}
+		if !matchesTerms(a, terms) {
+			continue
 		}
 		if source != "" && strings.ToLower(a.Source) != source {
 			continue
 		}
+		if opts.Author != "" && !a.HasAuthor(opts.Author) {
+			continue
+		}
+		if opts.Language != "" && !news.MatchesLanguage(a.Language, opts.Language) {
+			continue
+		}
+		if opts.HasMedia != "" && !a.HasMedia(opts.HasMedia) {
+			continue
+		}
 		if !opts.PublishedFrom.IsZero() && a.PublishedAt.Before(opts.PublishedFrom) {
 			continue
 		}
@@ -90,9 +138,10 @@ func (r *MemoryArticleRepository) GetArticleByID(_ context.Context, id int64) (n
 	return news.Article{}, ErrNotFound
 }
 
-func (r *MemoryArticleRepository) UpsertArticles(_ context.Context, articles []news.Article) error {
+func (r *MemoryArticleRepository) UpsertArticles(_ context.Context, articles []news.Article) (UpsertResult, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	var res UpsertResult
 	byURL := map[string]news.Article{}
 	var maxID int64
 	for _, a := range r.articles {
@@ -101,12 +150,35 @@ func (r *MemoryArticleRepository) UpsertArticles(_ context.Context, articles []n
 			maxID = a.ID
 		}
 	}
+	now := time.Now().UTC()
 	for _, a := range articles {
+		if a.FetchedAt.IsZero() {
+			a.FetchedAt = now
+		}
+		a.ContentHash = news.ContentHash(a.Title, a.ContentText)
 		if old, ok := byURL[a.URL]; ok {
 			a.ID = old.ID
+			if a.PublishedAt.IsZero() {
+				a.PublishedAt = old.PublishedAt
+			}
+			a.Body, a.ExtractionStatus = old.Body, old.ExtractionStatus
+			a.UpdatedAt = old.UpdatedAt
+			if articleChanged(old, a) {
+				a.UpdatedAt = now
+				res.Updated++
+			}
+			if old.ContentHash != a.ContentHash {
+				r.keepRevision(old, now)
+				res.Revised++
+			}
 		} else {
 			maxID++
 			a.ID = maxID
+			if a.PublishedAt.IsZero() {
+				a.PublishedAt = a.FetchedAt
+			}
+			a.UpdatedAt = now
+			res.Inserted++
 		}
 		byURL[a.URL] = a
 	}
@@ -114,7 +186,69 @@ func (r *MemoryArticleRepository) UpsertArticles(_ context.Context, articles []n
 	for _, a := range byURL {
 		r.articles = append(r.articles, a)
 	}
-	return nil
+	return res, nil
+}
+
+// matchesTerms requires every search term in the title, plain-text content
+// or extracted body.
+func matchesTerms(a news.Article, terms []string) bool {
+	text := searchText(a.Title, a.ContentText, a.Body)
+	for _, t := range terms {
+		if !strings.Contains(text, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// searchText is what keyword search matches against, lower-cased in Go
+// because SQLite's LOWER and LIKE only fold ASCII letters.
+func searchText(title, contentText, body string) string {
+	return strings.ToLower(title + "\n" + contentText + "\n" + body)
+}
+
+// articleChanged compares the feed-supplied fields that count as an update.
+func articleChanged(old, a news.Article) bool {
+	return old.Title != a.Title || old.Content != a.Content || !old.PublishedAt.Equal(a.PublishedAt) ||
+		old.GUID != a.GUID || old.ImageURL != a.ImageURL || old.Language != a.Language ||
+		stringsJSON(old.Authors) != stringsJSON(a.Authors) || stringsJSON(old.Categories) != stringsJSON(a.Categories) ||
+		mediaJSON(old.Media) != mediaJSON(a.Media)
+}
+
+func mediaJSON(media []news.Media) string {
+	if media == nil {
+		media = []news.Media{}
+	}
+	b, _ := json.Marshal(media)
+	return string(b)
+}
+
+func (r *MemoryArticleRepository) PendingExtractions(_ context.Context, sourceID string, limit int) ([]news.Article, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := []news.Article{}
+	for _, a := range r.articles {
+		if a.Source == sourceID && a.ExtractionStatus == "" {
+			items = append(items, a)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].PublishedAt.After(items[j].PublishedAt) })
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+func (r *MemoryArticleRepository) SaveExtraction(_ context.Context, id int64, body, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.articles {
+		if r.articles[i].ID == id {
+			r.articles[i].Body, r.articles[i].ExtractionStatus = body, status
+			return nil
+		}
+	}
+	return ErrNotFound
 }
 
 func (r *MemoryArticleRepository) Ready(_ context.Context) error { return nil }
@@ -135,17 +269,54 @@ func NewSQLiteArticleRepository(dbPath, schemaPath string) (*SQLiteArticleReposi
 	if _, err := runSQLite(dbPath, string(schema)); err != nil {
 		return nil, err
 	}
+	added, err := migrateSQLite(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := sanitizeStoredContent(dbPath); err != nil {
+		return nil, err
+	}
+	if added["articles.language_confidence"] {
+		if err := detectStoredLanguages(dbPath); err != nil {
+			return nil, err
+		}
+	}
+	if added["articles.search_text"] {
+		if err := indexStoredSearchText(dbPath); err != nil {
+			return nil, err
+		}
+	}
+	if added["articles.content_hash"] {
+		if err := hashStoredContent(dbPath); err != nil {
+			return nil, err
+		}
+	}
 	return &SQLiteArticleRepository{dbPath: dbPath}, nil
 }
 
 func (r *SQLiteArticleRepository) ListArticles(_ context.Context, opts ListOptions) ([]news.Article, error) {
 	conds := []string{"1=1"}
-	if opts.Keyword != "" {
-		k := escLike(strings.ToLower(opts.Keyword))
-		conds = append(conds, fmt.Sprintf("(LOWER(title) LIKE '%%%s%%' ESCAPE '\\\\' OR LOWER(content) LIKE '%%%s%%' ESCAPE '\\\\')", k, k))
+	for _, term := range langid.SearchTerms(opts.Keyword) {
+		k := escLike(term)
+		conds = append(conds, fmt.Sprintf("search_text LIKE '%%%s%%' ESCAPE '\\'", k))
 	}
 	if opts.Source != "" {
-		conds = append(conds, fmt.Sprintf("LOWER(COALESCE((SELECT name FROM sources s WHERE s.id = a.source_id), 'rss')) = '%s'", esc(strings.ToLower(opts.Source))))
+		src := esc(strings.ToLower(opts.Source))
+		conds = append(conds, fmt.Sprintf("(LOWER(COALESCE((SELECT slug FROM sources s WHERE s.id = a.source_id), 'rss')) = '%s' OR LOWER(COALESCE((SELECT name FROM sources s WHERE s.id = a.source_id), '')) = '%s')", src, src))
+	}
+	if opts.Author != "" {
+		conds = append(conds, fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(a.authors) WHERE LOWER(value) = '%s')", esc(strings.ToLower(strings.TrimSpace(opts.Author)))))
+	}
+	if opts.Language != "" {
+		lang := news.NormalizeLanguage(opts.Language)
+		conds = append(conds, fmt.Sprintf("(language = '%s' OR language LIKE '%s-%%' ESCAPE '\\')", esc(lang), escLike(lang)))
+	}
+	switch opts.HasMedia {
+	case "":
+	case "any":
+		conds = append(conds, "media <> '[]'")
+	default:
+		conds = append(conds, fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(a.media) WHERE json_extract(value, '$.medium') = '%s')", esc(opts.HasMedia)))
 	}
 	if !opts.PublishedFrom.IsZero() {
 		conds = append(conds, fmt.Sprintf("published_at >= '%s'", opts.PublishedFrom.UTC().Format(time.RFC3339)))
@@ -153,35 +324,174 @@ func (r *SQLiteArticleRepository) ListArticles(_ context.Context, opts ListOptio
 	if !opts.PublishedTo.IsZero() {
 		conds = append(conds, fmt.Sprintf("published_at <= '%s'", opts.PublishedTo.UTC().Format(time.RFC3339)))
 	}
-	q := fmt.Sprintf("SELECT id, title, url, COALESCE(content,''), COALESCE(published_at,''), COALESCE((SELECT name FROM sources s WHERE s.id = a.source_id), 'rss') FROM articles a WHERE %s ORDER BY published_at DESC LIMIT %d OFFSET %d;", strings.Join(conds, " AND "), opts.Limit, opts.Offset)
-	out, err := runSQLite(r.dbPath, q)
-	if err != nil {
-		return nil, err
-	}
-	return parseRows(out), nil
+	return r.queryArticles(fmt.Sprintf("SELECT %s FROM articles a WHERE %s ORDER BY published_at DESC LIMIT %d OFFSET %d;", articleColumns, strings.Join(conds, " AND "), opts.Limit, opts.Offset))
 }
 
 func (r *SQLiteArticleRepository) GetArticleByID(_ context.Context, id int64) (news.Article, error) {
-	q := fmt.Sprintf("SELECT id, title, url, COALESCE(content,''), COALESCE(published_at,''), COALESCE((SELECT name FROM sources s WHERE s.id = a.source_id), 'rss') FROM articles a WHERE id = %d;", id)
-	out, err := runSQLite(r.dbPath, q)
+	items, err := r.queryArticles(fmt.Sprintf("SELECT %s FROM articles a WHERE id = %d;", articleColumns, id))
 	if err != nil {
 		return news.Article{}, err
 	}
-	items := parseRows(out)
 	if len(items) == 0 {
 		return news.Article{}, ErrNotFound
 	}
 	return items[0], nil
 }
 
-func (r *SQLiteArticleRepository) UpsertArticles(_ context.Context, articles []news.Article) error {
+const articleColumns = "id, title, url, COALESCE(content,'') AS content, content_text, content_hash, COALESCE(published_at,'') AS published_at, COALESCE((SELECT slug FROM sources s WHERE s.id = a.source_id), 'rss') AS source, guid, authors, categories, image_url, media, language, language_confidence, COALESCE(fetched_at,'') AS fetched_at, COALESCE(updated_at,'') AS updated_at, body, extraction_status"
+
+type articleRow struct {
+	ID               int64   `json:"id"`
+	Title            string  `json:"title"`
+	URL              string  `json:"url"`
+	Content          string  `json:"content"`
+	ContentText      string  `json:"content_text"`
+	ContentHash      string  `json:"content_hash"`
+	PublishedAt      string  `json:"published_at"`
+	Source           string  `json:"source"`
+	GUID             string  `json:"guid"`
+	Authors          string  `json:"authors"`
+	Categories       string  `json:"categories"`
+	ImageURL         string  `json:"image_url"`
+	Media            string  `json:"media"`
+	Language         string  `json:"language"`
+	LanguageConf     float64 `json:"language_confidence"`
+	FetchedAt        string  `json:"fetched_at"`
+	UpdatedAt        string  `json:"updated_at"`
+	Body             string  `json:"body"`
+	ExtractionStatus string  `json:"extraction_status"`
+}
+
+func (r *SQLiteArticleRepository) queryArticles(q string) ([]news.Article, error) {
+	var rows []articleRow
+	if err := querySQLite(r.dbPath, q, &rows); err != nil {
+		return nil, err
+	}
+	items := make([]news.Article, 0, len(rows))
+	for _, row := range rows {
+		a := news.Article{
+			ID:                 row.ID,
+			Title:              row.Title,
+			URL:                row.URL,
+			Source:             row.Source,
+			GUID:               row.GUID,
+			Authors:            []string{},
+			Categories:         []string{},
+			ImageURL:           row.ImageURL,
+			Language:           row.Language,
+			LanguageConfidence: row.LanguageConf,
+			Content:            row.Content,
+			ContentText:        row.ContentText,
+			ContentHash:        row.ContentHash,
+			Body:               row.Body,
+			ExtractionStatus:   row.ExtractionStatus,
+		}
+		_ = json.Unmarshal([]byte(row.Authors), &a.Authors)
+		_ = json.Unmarshal([]byte(row.Categories), &a.Categories)
+		_ = json.Unmarshal([]byte(row.Media), &a.Media)
+		a.PublishedAt, _ = time.Parse(time.RFC3339, row.PublishedAt)
+		a.FetchedAt, _ = time.Parse(time.RFC3339, row.FetchedAt)
+		a.UpdatedAt, _ = time.Parse(time.RFC3339, row.UpdatedAt)
+		items = append(items, a)
+	}
+	return items, nil
+}
+
+func (r *SQLiteArticleRepository) PendingExtractions(_ context.Context, sourceID string, limit int) ([]news.Article, error) {
+	return r.queryArticles(fmt.Sprintf("SELECT %s FROM articles a WHERE extraction_status = '' AND source_id = (SELECT id FROM sources WHERE slug = '%s') ORDER BY published_at DESC LIMIT %d;", articleColumns, esc(sourceID), limit))
+}
+
+func (r *SQLiteArticleRepository) SaveExtraction(_ context.Context, id int64, body, status string) error {
+	var rows []struct {
+		Title       string `json:"title"`
+		ContentText string `json:"content_text"`
+	}
+	if err := querySQLite(r.dbPath, fmt.Sprintf("SELECT title, content_text FROM articles WHERE id = %d;", id), &rows); err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return ErrNotFound
+	}
+	q := fmt.Sprintf("UPDATE articles SET body = '%s', extraction_status = '%s', search_text = '%s' WHERE id = %d;", esc(body), esc(status), esc(searchText(rows[0].Title, rows[0].ContentText, body)), id)
+	_, err := runSQLite(r.dbPath, q)
+	return err
+}
+
+// UpsertArticles skips the UPDATE when nothing changed, so total_changes()
+// minus the rows that did not exist beforehand and the revisions stored is
+// the number of updates. Revisions are decided from the stored content
+// hashes and written just before the row they preserve is overwritten.
+func (r *SQLiteArticleRepository) UpsertArticles(_ context.Context, articles []news.Article) (UpsertResult, error) {
+	if len(articles) == 0 {
+		return UpsertResult{}, nil
+	}
+	hashes := map[string]bool{}
+	quoted := make([]string, 0, len(articles))
+	for _, a := range articles {
+		h := hashURL(a.URL)
+		if !hashes[h] {
+			hashes[h] = true
+			quoted = append(quoted, "'"+h+"'")
+		}
+	}
+	var existing []struct {
+		URLHash     string `json:"url_hash"`
+		ContentHash string `json:"content_hash"`
+		Body        string `json:"body"`
+	}
+	if err := querySQLite(r.dbPath, fmt.Sprintf("SELECT url_hash, content_hash, body FROM articles WHERE url_hash IN (%s);", strings.Join(quoted, ",")), &existing); err != nil {
+		return UpsertResult{}, err
+	}
+	stored := make(map[string]string, len(existing))
+	bodies := make(map[string]string, len(existing))
+	for _, e := range existing {
+		stored[e.URLHash] = e.ContentHash
+		bodies[e.URLHash] = e.Body
+	}
+	now := time.Now().UTC()
+	var res UpsertResult
 	var b strings.Builder
 	for _, a := range articles {
-		published := a.PublishedAt.UTC().Format(time.RFC3339)
-		b.WriteString(fmt.Sprintf("INSERT INTO articles (title, url, url_hash, content, published_at) VALUES ('%s','%s','%s','%s','%s') ON CONFLICT(url_hash) DO UPDATE SET title=excluded.title, content=excluded.content, published_at=excluded.published_at;", esc(a.Title), esc(a.URL), hashURL(a.URL), esc(a.Content), published))
+		h := hashURL(a.URL)
+		a.ContentHash = news.ContentHash(a.Title, a.ContentText)
+		if old, ok := stored[h]; ok && old != a.ContentHash {
+			b.WriteString(fmt.Sprintf("INSERT INTO article_revisions (article_id, title, content, content_text, content_hash, seen_at, replaced_at) "+
+				"SELECT id, title, COALESCE(content,''), content_text, content_hash, COALESCE((SELECT MAX(replaced_at) FROM article_revisions v WHERE v.article_id = articles.id), updated_at, fetched_at), %s FROM articles WHERE url_hash = '%s';",
+				sqlTime(&now), h))
+			res.Revised++
+		}
+		stored[h] = a.ContentHash
+		fetched := a.FetchedAt
+		if fetched.IsZero() {
+			fetched = now
+		}
+		published := "'" + a.PublishedAt.UTC().Format(time.RFC3339) + "'"
+		if a.PublishedAt.IsZero() {
+			published = fmt.Sprintf("COALESCE((SELECT published_at FROM articles WHERE url_hash = '%s'), %s)", h, sqlTime(&fetched))
+		}
+		sourceID := fmt.Sprintf("(SELECT id FROM sources WHERE slug = '%s')", esc(a.Source))
+		b.WriteString(fmt.Sprintf("INSERT INTO articles (source_id, title, url, url_hash, guid, authors, categories, image_url, media, language, language_confidence, content, content_text, content_hash, search_text, published_at, fetched_at, updated_at) VALUES (%s,'%s','%s','%s','%s','%s','%s','%s','%s','%s',%g,'%s','%s','%s','%s',%s,%s,%s) "+
+			"ON CONFLICT(url_hash) DO UPDATE SET source_id=COALESCE(excluded.source_id, articles.source_id), title=excluded.title, guid=excluded.guid, authors=excluded.authors, categories=excluded.categories, image_url=excluded.image_url, media=excluded.media, language=excluded.language, language_confidence=excluded.language_confidence, content=excluded.content, content_text=excluded.content_text, content_hash=excluded.content_hash, search_text=excluded.search_text, published_at=excluded.published_at, updated_at=excluded.updated_at "+
+			"WHERE articles.title IS NOT excluded.title OR articles.content IS NOT excluded.content OR articles.published_at IS NOT excluded.published_at OR articles.guid IS NOT excluded.guid OR articles.authors IS NOT excluded.authors OR articles.categories IS NOT excluded.categories OR articles.image_url IS NOT excluded.image_url OR articles.media IS NOT excluded.media OR articles.language IS NOT excluded.language;",
+			sourceID, esc(a.Title), esc(a.URL), h, esc(a.GUID), esc(stringsJSON(a.Authors)), esc(stringsJSON(a.Categories)), esc(a.ImageURL), esc(mediaJSON(a.Media)), esc(a.Language), a.LanguageConfidence, esc(a.Content), esc(a.ContentText), a.ContentHash, esc(searchText(a.Title, a.ContentText, bodies[h])), published, sqlTime(&fetched), sqlTime(&now)))
 	}
-	_, err := runSQLite(r.dbPath, b.String())
-	return err
+	// fetched_at moves on every delivery, so it is set after counting
+	// changes to keep unchanged articles out of Updated.
+	b.WriteString("SELECT total_changes() AS n;")
+	var changes []struct {
+		N int `json:"n"`
+	}
+	if err := querySQLite(r.dbPath, b.String(), &changes); err != nil {
+		return UpsertResult{}, err
+	}
+	if _, err := runSQLite(r.dbPath, fmt.Sprintf("UPDATE articles SET fetched_at = %s WHERE url_hash IN (%s);", sqlTime(&now), strings.Join(quoted, ","))); err != nil {
+		return UpsertResult{}, err
+	}
+	res.Inserted = len(quoted) - len(existing)
+	if len(changes) == 1 {
+		res.Updated = changes[0].N - res.Inserted - res.Revised
+	}
+	return res, nil
 }
 
 func (r *SQLiteArticleRepository) Ready(_ context.Context) error {
@@ -189,8 +499,12 @@ func (r *SQLiteArticleRepository) Ready(_ context.Context) error {
 	return err
 }
 
+// busyTimeout lets concurrent crawl workers wait for the write lock instead
+// of failing with "database is locked".
+const busyTimeout = ".timeout 5000"
+
 func runSQLite(path, query string) (string, error) {
-	cmd := exec.Command("sqlite3", "-separator", "|", path, query)
+	cmd := exec.Command("sqlite3", "-cmd", busyTimeout, "-separator", "|", path, query)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("sqlite error: %v, output: %s", err, string(out))
@@ -198,22 +512,194 @@ func runSQLite(path, query string) (string, error) {
 	return string(out), nil
 }
 
-func parseRows(raw string) []news.Article {
-	lines := strings.Split(strings.TrimSpace(raw), "\n")
-	if len(lines) == 1 && lines[0] == "" {
-		return []news.Article{}
+// querySQLite runs a single SELECT in JSON output mode and decodes the rows
+// into dest, a pointer to a slice. Values containing newlines or the
+// separator come through intact.
+func querySQLite(path, query string, dest any) error {
+	cmd := exec.Command("sqlite3", "-cmd", busyTimeout, "-json", path, query)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("sqlite error: %v, output: %s", err, string(out))
 	}
-	items := make([]news.Article, 0, len(lines))
-	for _, line := range lines {
-		cols := strings.Split(line, "|")
-		if len(cols) < 6 {
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(out, dest)
+}
+
+type columnMigration struct {
+	table  string
+	column string
+	decl   string
+}
+
+// columnMigrations lists columns added after a table first shipped. The
+// schema file only uses CREATE TABLE IF NOT EXISTS, so databases created by
+// older builds get them through ALTER TABLE instead.
+var columnMigrations = []columnMigration{
+	{"sources", "slug", "TEXT"},
+	{"sources", "country", "TEXT NOT NULL DEFAULT ''"},
+	{"sources", "base_authority", "REAL NOT NULL DEFAULT 0"},
+	{"sources", "topics", "TEXT NOT NULL DEFAULT '[]'"},
+	{"sources", "enabled", "INTEGER NOT NULL DEFAULT 1"},
+	{"sources", "updated_at", "DATETIME"},
+	{"sources", "extract_full_text", "INTEGER NOT NULL DEFAULT 0"},
+	{"sources", "kind", "TEXT NOT NULL DEFAULT 'rss'"},
+	{"sources", "options", "TEXT NOT NULL DEFAULT ''"},
+	{"sources", "http_options", "TEXT NOT NULL DEFAULT ''"},
+	{"sources", "retention_days", "INTEGER NOT NULL DEFAULT 0"},
+	{"articles", "body", "TEXT NOT NULL DEFAULT ''"},
+	{"articles", "extraction_status", "TEXT NOT NULL DEFAULT ''"},
+	{"articles", "content_text", "TEXT NOT NULL DEFAULT ''"},
+	{"articles", "guid", "TEXT NOT NULL DEFAULT ''"},
+	{"articles", "authors", "TEXT NOT NULL DEFAULT '[]'"},
+	{"articles", "categories", "TEXT NOT NULL DEFAULT '[]'"},
+	{"articles", "image_url", "TEXT NOT NULL DEFAULT ''"},
+	{"articles", "language", "TEXT NOT NULL DEFAULT ''"},
+	{"articles", "fetched_at", "DATETIME"},
+	{"articles", "updated_at", "DATETIME"},
+	{"articles", "language_confidence", "REAL NOT NULL DEFAULT 0"},
+	{"articles", "media", "TEXT NOT NULL DEFAULT '[]'"},
+	{"articles", "content_hash", "TEXT NOT NULL DEFAULT ''"},
+	{"articles", "search_text", "TEXT NOT NULL DEFAULT ''"},
+	{"crawl_runs", "truncated", "INTEGER NOT NULL DEFAULT 0"},
+	{"crawl_runs", "rejected", "TEXT NOT NULL DEFAULT ''"},
+	{"source_health", "poll_interval_sec", "INTEGER NOT NULL DEFAULT 0"},
+	{"source_health", "next_poll_at", "DATETIME"},
+}
+
+// migrationIndexes reference migrated columns, so they run after
+// columnMigrations rather than from the schema file.
+var migrationIndexes = []string{
+	"CREATE UNIQUE INDEX IF NOT EXISTS idx_sources_slug ON sources(slug);",
+	"CREATE INDEX IF NOT EXISTS idx_articles_language ON articles(language);",
+}
+
+// migrateSQLite returns the columns it added as "table.column", so data
+// backfills can run exactly once.
+func migrateSQLite(dbPath string) (map[string]bool, error) {
+	existing := map[string]map[string]bool{}
+	added := map[string]bool{}
+	var b strings.Builder
+	for _, m := range columnMigrations {
+		cols, ok := existing[m.table]
+		if !ok {
+			var info []struct {
+				Name string `json:"name"`
+			}
+			if err := querySQLite(dbPath, fmt.Sprintf("PRAGMA table_info(%s);", m.table), &info); err != nil {
+				return nil, err
+			}
+			cols = map[string]bool{}
+			for _, c := range info {
+				cols[c.Name] = true
+			}
+			existing[m.table] = cols
+		}
+		if cols[m.column] {
 			continue
 		}
-		id, _ := strconv.ParseInt(cols[0], 10, 64)
-		t, _ := time.Parse(time.RFC3339, cols[4])
-		items = append(items, news.Article{ID: id, Title: cols[1], URL: cols[2], Content: cols[3], PublishedAt: t, Source: cols[5]})
+		b.WriteString(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", m.table, m.column, m.decl))
+		added[m.table+"."+m.column] = true
+	}
+	b.WriteString(strings.Join(migrationIndexes, ""))
+	if _, err := runSQLite(dbPath, b.String()); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// sanitizeStoredContent brings rows written before ingest-time
+// sanitisation in line with new ones: raw description HTML is replaced by
+// its allow-listed form and the plain-text column is filled in.
+func sanitizeStoredContent(dbPath string) error {
+	var lastID int64
+	for {
+		var rows []struct {
+			ID      int64  `json:"id"`
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+			Body    string `json:"body"`
+		}
+		q := fmt.Sprintf("SELECT id, title, url, content, body FROM articles WHERE id > %d AND content_text = '' AND COALESCE(content,'') != '' ORDER BY id LIMIT 200;", lastID)
+		if err := querySQLite(dbPath, q, &rows); err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		var b strings.Builder
+		for _, row := range rows {
+			text := sanitize.Text(row.Content)
+			b.WriteString(fmt.Sprintf("UPDATE articles SET content = '%s', content_text = '%s', search_text = '%s' WHERE id = %d;",
+				esc(sanitize.HTML(row.Content, row.URL)), esc(text), esc(searchText(row.Title, text, row.Body)), row.ID))
+			lastID = row.ID
+		}
+		if _, err := runSQLite(dbPath, b.String()); err != nil {
+			return err
+		}
+	}
+}
+
+// detectStoredLanguages runs language detection over articles stored
+// before it existed, keeping their feed-declared language as the fallback.
+func detectStoredLanguages(dbPath string) error {
+	var lastID int64
+	for {
+		var rows []struct {
+			ID          int64  `json:"id"`
+			Title       string `json:"title"`
+			ContentText string `json:"content_text"`
+			Body        string `json:"body"`
+			Language    string `json:"language"`
+		}
+		q := fmt.Sprintf("SELECT id, title, content_text, body, language FROM articles WHERE id > %d ORDER BY id LIMIT 200;", lastID)
+		if err := querySQLite(dbPath, q, &rows); err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		var b strings.Builder
+		for _, row := range rows {
+			res := langid.Resolve(row.Title+"\n"+row.ContentText+"\n"+row.Body, row.Language)
+			b.WriteString(fmt.Sprintf("UPDATE articles SET language = '%s', language_confidence = %g WHERE id = %d;", esc(res.Code), res.Confidence, row.ID))
+			lastID = row.ID
+		}
+		if _, err := runSQLite(dbPath, b.String()); err != nil {
+			return err
+		}
+	}
+}
+
+// indexStoredSearchText fills search_text for articles stored before it
+// existed.
+func indexStoredSearchText(dbPath string) error {
+	var lastID int64
+	for {
+		var rows []struct {
+			ID          int64  `json:"id"`
+			Title       string `json:"title"`
+			ContentText string `json:"content_text"`
+			Body        string `json:"body"`
+		}
+		q := fmt.Sprintf("SELECT id, title, content_text, body FROM articles WHERE id > %d ORDER BY id LIMIT 200;", lastID)
+		if err := querySQLite(dbPath, q, &rows); err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		var b strings.Builder
+		for _, row := range rows {
+			b.WriteString(fmt.Sprintf("UPDATE articles SET search_text = '%s' WHERE id = %d;", esc(searchText(row.Title, row.ContentText, row.Body)), row.ID))
+			lastID = row.ID
+		}
+		if _, err := runSQLite(dbPath, b.String()); err != nil {
+			return err
+		}
 	}
-	return items
 }
 
 func hashURL(url string) string {
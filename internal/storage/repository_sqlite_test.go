@@ -2,6 +2,9 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -16,7 +19,7 @@ func TestMemoryUpsertDeduplicate(t *testing.T) {
 		{Title: "A", URL: "https://example.com/1", Content: "v1", PublishedAt: now},
 		{Title: "A updated", URL: "https://example.com/1", Content: "v2", PublishedAt: now.Add(time.Minute)},
 	}
-	if err := repo.UpsertArticles(ctx, input); err != nil {
+	if _, err := repo.UpsertArticles(ctx, input); err != nil {
 		t.Fatalf("upsert: %v", err)
 	}
 	items, err := repo.ListArticles(ctx, ListOptions{Limit: 100, Offset: 0})
@@ -41,7 +44,7 @@ func TestMemoryFilterByKeyword(t *testing.T) {
 	repo := NewMemoryArticleRepository()
 	ctx := context.Background()
 	now := time.Now().UTC()
-	if err := repo.UpsertArticles(ctx, []news.Article{{Title: "initialized headline", URL: "https://example.com/init", Content: "ok", PublishedAt: now}}); err != nil {
+	if _, err := repo.UpsertArticles(ctx, []news.Article{{Title: "initialized headline", URL: "https://example.com/init", Content: "ok", PublishedAt: now}}); err != nil {
 		t.Fatalf("upsert: %v", err)
 	}
 	items, err := repo.ListArticles(ctx, ListOptions{Limit: 100, Offset: 0, Keyword: "initialized"})
@@ -52,3 +55,466 @@ func TestMemoryFilterByKeyword(t *testing.T) {
 		t.Fatalf("expected 1 filtered article, got %d", len(items))
 	}
 }
+
+func TestMemoryUpsertCounts(t *testing.T) {
+	repo := NewMemoryArticleRepository()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	first := []news.Article{
+		{Title: "A", URL: "https://example.com/a", PublishedAt: now},
+		{Title: "B", URL: "https://example.com/b", PublishedAt: now},
+	}
+	res, err := repo.UpsertArticles(ctx, first)
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if res.Inserted != 2 || res.Updated != 0 {
+		t.Fatalf("unexpected first result: %+v", res)
+	}
+	second := []news.Article{
+		{Title: "A", URL: "https://example.com/a", PublishedAt: now},
+		{Title: "B corrected", URL: "https://example.com/b", PublishedAt: now},
+	}
+	res, err = repo.UpsertArticles(ctx, second)
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if res.Inserted != 0 || res.Updated != 1 {
+		t.Fatalf("unexpected second result: %+v", res)
+	}
+}
+
+func TestMemoryExtractionSurvivesUpsert(t *testing.T) {
+	repo := NewMemoryArticleRepository()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	article := news.Article{Title: "A", URL: "https://example.com/a", Source: "bbc", Content: "teaser", PublishedAt: now}
+	if _, err := repo.UpsertArticles(ctx, []news.Article{article}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	pending, err := repo.PendingExtractions(ctx, "bbc", 10)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("pending = %v, %v", pending, err)
+	}
+	if err := repo.SaveExtraction(ctx, pending[0].ID, "full body text", news.ExtractionOK); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	article.Content = "edited teaser"
+	if _, err := repo.UpsertArticles(ctx, []news.Article{article}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if pending, _ := repo.PendingExtractions(ctx, "bbc", 10); len(pending) != 0 {
+		t.Fatalf("article extracted twice: %+v", pending)
+	}
+	items, _ := repo.ListArticles(ctx, ListOptions{Limit: 10, Keyword: "full body"})
+	if len(items) != 1 || items[0].Body != "full body text" || items[0].ExtractionStatus != news.ExtractionOK {
+		t.Fatalf("body lost or not searchable: %+v", items)
+	}
+}
+
+func TestMemoryFilterByAuthorAndLanguage(t *testing.T) {
+	repo := NewMemoryArticleRepository()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	input := []news.Article{
+		{Title: "A", URL: "https://example.com/a", Authors: []string{"Ada Lovelace"}, Language: "en-us", PublishedAt: now},
+		{Title: "B", URL: "https://example.com/b", Authors: []string{"Lu Xun"}, Language: "zh", PublishedAt: now},
+		{Title: "C", URL: "https://example.com/c", Language: "english", PublishedAt: now},
+	}
+	if _, err := repo.UpsertArticles(ctx, input); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	items, _ := repo.ListArticles(ctx, ListOptions{Limit: 10, Author: "ada lovelace"})
+	if len(items) != 1 || items[0].Title != "A" {
+		t.Fatalf("author filter: %+v", items)
+	}
+	items, _ = repo.ListArticles(ctx, ListOptions{Limit: 10, Language: "EN"})
+	if len(items) != 1 || items[0].Title != "A" {
+		t.Fatalf("language filter: %+v", items)
+	}
+	if items[0].UpdatedAt.IsZero() || items[0].FetchedAt.IsZero() {
+		t.Fatalf("timestamps not set: %+v", items[0])
+	}
+}
+
+func TestMemoryFilterByMedia(t *testing.T) {
+	repo := NewMemoryArticleRepository()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	input := []news.Article{
+		{Title: "Pod", URL: "https://example.com/pod", Media: []news.Media{{URL: "https://cdn.example.com/1.mp3", Medium: news.MediumAudio}}, PublishedAt: now},
+		{Title: "Clip", URL: "https://example.com/clip", Media: []news.Media{{URL: "https://cdn.example.com/1.mp4", Medium: news.MediumVideo}}, PublishedAt: now},
+		{Title: "Text", URL: "https://example.com/text", PublishedAt: now},
+	}
+	if _, err := repo.UpsertArticles(ctx, input); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	for medium, want := range map[string]int{"": 3, "audio": 1, "video": 1, "any": 2} {
+		items, _ := repo.ListArticles(ctx, ListOptions{Limit: 10, HasMedia: medium})
+		if len(items) != want {
+			t.Errorf("has_media=%q: got %d items, want %d", medium, len(items), want)
+		}
+	}
+	input[2].Media = []news.Media{{URL: "https://cdn.example.com/2.mp3", Medium: news.MediumAudio}}
+	res, _ := repo.UpsertArticles(ctx, input[2:])
+	if res.Updated != 1 {
+		t.Fatalf("added enclosure not counted as update: %+v", res)
+	}
+}
+
+func TestMemoryKeywordTermsAllMatch(t *testing.T) {
+	repo := NewMemoryArticleRepository()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	input := []news.Article{
+		{Title: "AI芯片出口管制", URL: "https://example.com/zh", ContentText: "美国收紧出口", PublishedAt: now},
+		{Title: "Harbour reopens", URL: "https://example.com/en", ContentText: "after the storm", PublishedAt: now},
+	}
+	if _, err := repo.UpsertArticles(ctx, input); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	for q, want := range map[string]int{"AI芯片": 1, "storm harbour": 1, "storm 芯片": 0, "出口 美国": 1} {
+		items, _ := repo.ListArticles(ctx, ListOptions{Limit: 10, Keyword: q})
+		if len(items) != want {
+			t.Errorf("q=%q: got %d items, want %d", q, len(items), want)
+		}
+	}
+}
+
+func TestMemoryUndatedArticleKeepsFirstSeenDate(t *testing.T) {
+	repo := NewMemoryArticleRepository()
+	ctx := context.Background()
+	first := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := repo.UpsertArticles(ctx, []news.Article{{Title: "A", URL: "https://example.com/a", FetchedAt: first}}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	res, err := repo.UpsertArticles(ctx, []news.Article{{Title: "A", URL: "https://example.com/a", FetchedAt: first.Add(time.Hour)}})
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if res.Updated != 0 {
+		t.Errorf("undated re-delivery counted as update: %+v", res)
+	}
+	got, _ := repo.ListArticles(ctx, ListOptions{Limit: 10})
+	if len(got) != 1 || !got[0].PublishedAt.Equal(first) {
+		t.Fatalf("got %+v, want published at first fetch", got)
+	}
+}
+
+func TestMemoryUpsertKeepsRevisions(t *testing.T) {
+	repo := NewMemoryArticleRepository()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	article := news.Article{Title: "Minister resigns", URL: "https://example.com/a", Content: "<p>v1</p>", ContentText: "v1", PublishedAt: now}
+	if _, err := repo.UpsertArticles(ctx, []news.Article{article}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	article.Categories = []string{"politics"}
+	res, err := repo.UpsertArticles(ctx, []news.Article{article})
+	if err != nil || res.Updated != 1 || res.Revised != 0 {
+		t.Fatalf("category change: %+v, %v", res, err)
+	}
+	article.Title = "Minister resigns after scandal"
+	if res, err = repo.UpsertArticles(ctx, []news.Article{article}); err != nil || res.Revised != 1 {
+		t.Fatalf("title change: %+v, %v", res, err)
+	}
+	article.Content = `<p class="lead">v1</p><img src="https://px.example/1.gif">`
+	if res, err = repo.UpsertArticles(ctx, []news.Article{article}); err != nil || res.Revised != 0 {
+		t.Fatalf("markup change: %+v, %v", res, err)
+	}
+	article.Content, article.ContentText = "<p>v2</p>", "v2"
+	if _, err := repo.UpsertArticles(ctx, []news.Article{article}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	stored, _ := repo.GetArticleByID(ctx, 1)
+	revs, err := repo.ArticleRevisions(ctx, stored.ID)
+	if err != nil || len(revs) != 2 {
+		t.Fatalf("revisions = %+v, %v", revs, err)
+	}
+	if revs[0].Title != "Minister resigns" || revs[1].Title != "Minister resigns after scandal" || revs[1].ContentText != "v1" {
+		t.Fatalf("unexpected revisions: %+v", revs)
+	}
+	if !revs[1].SeenAt.Equal(revs[0].ReplacedAt) || revs[0].ContentHash != news.ContentHash("Minister resigns", "v1") {
+		t.Fatalf("revision times or hash: %+v", revs)
+	}
+	if stored.ContentHash != news.ContentHash(article.Title, "v2") {
+		t.Fatalf("content hash = %q", stored.ContentHash)
+	}
+}
+
+func TestMemoryDeleteArticles(t *testing.T) {
+	repo := NewMemoryArticleRepository()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	article := news.Article{Title: "A", URL: "https://example.com/a", PublishedAt: now}
+	_, _ = repo.UpsertArticles(ctx, []news.Article{article, {Title: "B", URL: "https://example.com/b", PublishedAt: now}})
+	article.Title = "A corrected"
+	_, _ = repo.UpsertArticles(ctx, []news.Article{article})
+
+	n, err := repo.DeleteArticles(ctx, []int64{1, 99})
+	if err != nil || n != 1 {
+		t.Fatalf("deleted %d, %v", n, err)
+	}
+	if _, err := repo.GetArticleByID(ctx, 1); err != ErrNotFound {
+		t.Fatalf("article still there: %v", err)
+	}
+	if revs, _ := repo.ArticleRevisions(ctx, 1); len(revs) != 0 {
+		t.Fatalf("revisions kept: %+v", revs)
+	}
+	if items, _ := repo.ListArticles(ctx, ListOptions{Limit: 10}); len(items) != 1 || items[0].Title != "B" {
+		t.Fatalf("unexpected remaining articles: %+v", items)
+	}
+}
+
+// newSQLiteRepo opens a fresh database from db/schema.sql, skipping the
+// test when the sqlite3 binary is not installed.
+func newSQLiteRepo(t *testing.T) *SQLiteArticleRepository {
+	t.Helper()
+	repo, err := NewSQLiteArticleRepository(filepath.Join(t.TempDir(), "news.db"), filepath.Join("..", "..", "db", "schema.sql"))
+	if errors.Is(err, ErrSQLiteBinaryNotFound) {
+		t.Skip("sqlite3 not installed")
+	}
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	return repo
+}
+
+func TestSQLiteFilterByAuthorAndLanguage(t *testing.T) {
+	repo := newSQLiteRepo(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	input := []news.Article{
+		{Title: "A", URL: "https://example.com/a", Authors: []string{"Ada Lovelace", "O'Brien"}, Language: "en-us", PublishedAt: now},
+		{Title: "B", URL: "https://example.com/b", Authors: []string{"Lu Xun"}, Language: "zh", PublishedAt: now},
+		{Title: "C", URL: "https://example.com/c", Authors: []string{"Ada"}, Language: "english", PublishedAt: now},
+	}
+	if _, err := repo.UpsertArticles(ctx, input); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	for author, want := range map[string]string{"ada lovelace": "A", "o'brien": "A", "LU XUN": "B", "lovelace": ""} {
+		items, err := repo.ListArticles(ctx, ListOptions{Limit: 10, Author: author})
+		if err != nil {
+			t.Fatalf("author %q: %v", author, err)
+		}
+		if want == "" && len(items) != 0 || want != "" && (len(items) != 1 || items[0].Title != want) {
+			t.Errorf("author %q: %+v", author, items)
+		}
+	}
+	items, err := repo.ListArticles(ctx, ListOptions{Limit: 10, Language: "EN"})
+	if err != nil || len(items) != 1 || items[0].Title != "A" {
+		t.Fatalf("language filter: %+v, %v", items, err)
+	}
+	if items[0].UpdatedAt.IsZero() || items[0].FetchedAt.IsZero() || len(items[0].Authors) != 2 {
+		t.Fatalf("columns not round-tripped: %+v", items[0])
+	}
+}
+
+func TestSQLiteFilterByMedia(t *testing.T) {
+	repo := newSQLiteRepo(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	input := []news.Article{
+		{Title: "Pod", URL: "https://example.com/pod", Media: []news.Media{{URL: "https://cdn.example.com/1.mp3", Medium: news.MediumAudio}}, PublishedAt: now},
+		{Title: "Clip", URL: "https://example.com/clip", Media: []news.Media{{URL: "https://cdn.example.com/1.mp4", Medium: news.MediumVideo}}, PublishedAt: now},
+		{Title: "Text", URL: "https://example.com/text", PublishedAt: now},
+	}
+	if _, err := repo.UpsertArticles(ctx, input); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	for medium, want := range map[string]int{"": 3, "audio": 1, "video": 1, "any": 2, "image": 0} {
+		items, err := repo.ListArticles(ctx, ListOptions{Limit: 10, HasMedia: medium})
+		if err != nil || len(items) != want {
+			t.Errorf("has_media=%q: got %d items (%v), want %d", medium, len(items), err, want)
+		}
+	}
+	input[2].Media = []news.Media{{URL: "https://cdn.example.com/2.mp3", Medium: news.MediumAudio}}
+	res, err := repo.UpsertArticles(ctx, input[2:])
+	if err != nil || res.Updated != 1 {
+		t.Fatalf("added enclosure not counted as update: %+v, %v", res, err)
+	}
+}
+
+func TestSQLiteKeywordFoldsNonASCII(t *testing.T) {
+	repo := newSQLiteRepo(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	input := []news.Article{
+		{Title: "Центральный банк снизил ставку", URL: "https://example.com/ru", PublishedAt: now},
+		{Title: "Über die Grenze", URL: "https://example.com/de", ContentText: "Straßenbahn fährt wieder", PublishedAt: now},
+		{Title: "AI芯片出口管制", URL: "https://example.com/zh", ContentText: "美国收紧出口", PublishedAt: now},
+	}
+	if _, err := repo.UpsertArticles(ctx, input); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	for q, want := range map[string]int{"центральный": 1, "ЦЕНТРАЛЬНЫЙ банк": 1, "über": 1, "FÄHRT": 1, "ai芯片": 1, "über 芯片": 0, "100%": 0} {
+		items, err := repo.ListArticles(ctx, ListOptions{Limit: 10, Keyword: q})
+		if err != nil || len(items) != want {
+			t.Errorf("q=%q: got %d items (%v), want %d", q, len(items), err, want)
+		}
+	}
+
+	stored, err := repo.ListArticles(ctx, ListOptions{Limit: 10, Keyword: "über"})
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("list: %+v, %v", stored, err)
+	}
+	if err := repo.SaveExtraction(ctx, stored[0].ID, "Die Ölpreise steigen", "ok"); err != nil {
+		t.Fatalf("save extraction: %v", err)
+	}
+	if items, _ := repo.ListArticles(ctx, ListOptions{Limit: 10, Keyword: "ölpreise"}); len(items) != 1 {
+		t.Fatalf("extracted body not searchable: %+v", items)
+	}
+	// A feed update keeps the extracted body searchable.
+	input[1].Content, input[1].ContentText = "<p>Straßenbahn fährt nicht</p>", "Straßenbahn fährt nicht"
+	if _, err := repo.UpsertArticles(ctx, input[1:2]); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if items, _ := repo.ListArticles(ctx, ListOptions{Limit: 10, Keyword: "ölpreise nicht"}); len(items) != 1 {
+		t.Fatalf("body lost from search after update: %+v", items)
+	}
+	if err := repo.SaveExtraction(ctx, 999, "", "failed"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSQLiteCrawlRunLimits(t *testing.T) {
+	runs := NewSQLiteCrawlRunRepository(newSQLiteRepo(t).dbPath)
+	ctx := context.Background()
+	run, err := runs.CreateCrawlRun(ctx, news.CrawlRun{SourceID: "big", Status: news.CrawlRunRunning, StartedAt: time.Now().UTC()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run.Status, run.Truncated, run.Rejected = news.CrawlRunFailed, 250, "feed rejected: body exceeds 16777216 bytes"
+	if err := runs.UpdateCrawlRun(ctx, run); err != nil {
+		t.Fatal(err)
+	}
+	got, err := runs.ListCrawlRuns(ctx, CrawlRunListOptions{Limit: 1})
+	if err != nil || len(got) != 1 {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	if got[0].Truncated != 250 || got[0].Rejected != run.Rejected {
+		t.Errorf("run: %+v", got[0])
+	}
+}
+
+func TestSQLiteContentHashIgnoresMarkup(t *testing.T) {
+	repo := newSQLiteRepo(t)
+	ctx := context.Background()
+	a := news.Article{Title: "Rates held", URL: "https://example.com/rates", Content: "<p>Rates held.</p>", ContentText: "Rates held.", PublishedAt: time.Now().UTC()}
+	if _, err := repo.UpsertArticles(ctx, []news.Article{a}); err != nil {
+		t.Fatal(err)
+	}
+	a.Content = `<p class="x">Rates held.</p><img src="https://px.example/2.gif">`
+	res, err := repo.UpsertArticles(ctx, []news.Article{a})
+	if err != nil || res.Revised != 0 {
+		t.Fatalf("markup change: %+v, %v", res, err)
+	}
+	a.Content, a.ContentText = "<p>Rates cut.</p>", "Rates cut."
+	if res, err = repo.UpsertArticles(ctx, []news.Article{a}); err != nil || res.Revised != 1 {
+		t.Fatalf("text change: %+v, %v", res, err)
+	}
+
+	// The backfill runs only when the column is added, not on every open.
+	if _, err := runSQLite(repo.dbPath, "UPDATE articles SET content_hash = '';"); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewSQLiteArticleRepository(repo.dbPath, filepath.Join("..", "..", "db", "schema.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := reopened.GetArticleByID(ctx, 1); got.ContentHash != "" {
+		t.Errorf("content hash recomputed on open: %q", got.ContentHash)
+	}
+}
+
+func TestSQLiteRetentionKeepsLatestFetch(t *testing.T) {
+	repo := newSQLiteRepo(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	feed := func(fetched time.Time, days ...int) []news.Article {
+		var items []news.Article
+		for _, day := range days {
+			items = append(items, news.Article{Title: fmt.Sprintf("day %d", day), URL: fmt.Sprintf("https://example.com/%d", day),
+				PublishedAt: now.Add(-time.Duration(day) * 24 * time.Hour), FetchedAt: fetched})
+		}
+		return items
+	}
+	policy := RetentionPolicy{MaxPerSource: 2, MaxRows: 2}
+	if _, err := repo.UpsertArticles(ctx, feed(now, 1, 2, 3, 4, 5)); err != nil {
+		t.Fatal(err)
+	}
+	if expired, err := repo.ExpiredArticles(ctx, policy, now, 0); err != nil || len(expired) != 0 {
+		t.Fatalf("expired from the latest fetch: %d, %v", len(expired), err)
+	}
+	// Upserts stamp fetched_at with the wall clock; move the first fetch an
+	// hour back so the next one is told apart.
+	earlier := now.Add(-time.Hour)
+	if _, err := runSQLite(repo.dbPath, "UPDATE articles SET fetched_at = "+sqlTime(&earlier)+";"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.UpsertArticles(ctx, feed(now, 0)); err != nil {
+		t.Fatal(err)
+	}
+	expired, err := repo.ExpiredArticles(ctx, policy, now, 0)
+	if err != nil || len(expired) != 4 {
+		t.Fatalf("expired = %+v, %v", expired, err)
+	}
+	for _, a := range expired {
+		if a.Title == "day 0" {
+			t.Fatal("expired the latest fetch")
+		}
+	}
+}
+
+func TestSQLiteDeleteSourceWithArticles(t *testing.T) {
+	repo := newSQLiteRepo(t)
+	sources := NewSQLiteSourceRepository(repo.dbPath)
+	ctx := context.Background()
+	for _, id := range []string{"bbc", "empty"} {
+		if _, err := sources.CreateSource(ctx, news.Source{ID: id, Name: id, FeedURL: "https://" + id + ".test/feed", Enabled: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repo.UpsertArticles(ctx, []news.Article{{Title: "A", URL: "https://bbc.test/a", Source: "bbc", PublishedAt: time.Now().UTC()}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sources.DeleteSource(ctx, "bbc"); !errors.Is(err, ErrSourceInUse) {
+		t.Fatalf("delete bbc: %v", err)
+	}
+	if got, _ := repo.GetArticleByID(ctx, 1); got.Source != "bbc" {
+		t.Errorf("article source = %q", got.Source)
+	}
+	if err := sources.DeleteSource(ctx, "empty"); err != nil {
+		t.Fatalf("delete empty: %v", err)
+	}
+	if err := sources.DeleteSource(ctx, "empty"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("delete twice: %v", err)
+	}
+}
+
+func TestSQLiteBackfillKeepsPlainTitles(t *testing.T) {
+	repo := newSQLiteRepo(t)
+	ctx := context.Background()
+	titles := []string{"<", "Profits up <5% this year", "Why a<b matters"}
+	for i, title := range titles {
+		a := news.Article{Title: title, URL: fmt.Sprintf("https://example.com/%d", i), Content: "<p>body</p>", PublishedAt: time.Now().UTC()}
+		if _, err := repo.UpsertArticles(ctx, []news.Article{a}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Pretend the rows predate sanitisation so the backfill picks them up.
+	if _, err := runSQLite(repo.dbPath, "UPDATE articles SET content_text = '';"); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewSQLiteArticleRepository(repo.dbPath, filepath.Join("..", "..", "db", "schema.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, title := range titles {
+		got, err := reopened.GetArticleByID(ctx, int64(i+1))
+		if err != nil || got.Title != title || got.ContentText != "body" {
+			t.Errorf("article %d = %q / %q, %v; want title %q", i+1, got.Title, got.ContentText, err, title)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"news-go/internal/news"
+)
+
+// RetentionPolicy bounds the articles kept. Articles are ranked by when
+// they were last seen: published or delivered by a feed, whichever is
+// later, so an article still in its feed is the newest of its source.
+// Zero fields disable the corresponding rule.
+type RetentionPolicy struct {
+	MaxAge time.Duration
+	// SourceMaxAge overrides MaxAge by source ID.
+	SourceMaxAge map[string]time.Duration
+	// MaxPerSource keeps the most recently seen articles of each source,
+	// MaxRows those of all sources together. Neither removes the articles
+	// of a source's latest fetch: they would only come back as new on the
+	// next one, so a limit below a feed's size may be exceeded instead.
+	MaxPerSource int
+	MaxRows      int
+}
+
+// IsZero reports whether p keeps every article.
+func (p RetentionPolicy) IsZero() bool {
+	return p.MaxAge <= 0 && len(p.SourceMaxAge) == 0 && p.MaxPerSource <= 0 && p.MaxRows <= 0
+}
+
+// maxAge is the age limit of a source's articles, 0 for none.
+func (p RetentionPolicy) maxAge(sourceID string) time.Duration {
+	if d, ok := p.SourceMaxAge[sourceID]; ok && d > 0 {
+		return d
+	}
+	return p.MaxAge
+}
+
+// ArticleRetentionRepository finds the articles a retention policy no
+// longer keeps.
+type ArticleRetentionRepository interface {
+	// ExpiredArticles returns up to limit articles outside p at now, least
+	// recently seen first.
+	ExpiredArticles(ctx context.Context, p RetentionPolicy, now time.Time, limit int) ([]news.Article, error)
+}
+
+func lastSeen(a news.Article) time.Time {
+	if a.FetchedAt.After(a.PublishedAt) {
+		return a.FetchedAt
+	}
+	return a.PublishedAt
+}
+
+func (r *MemoryArticleRepository) ExpiredArticles(_ context.Context, p RetentionPolicy, now time.Time, limit int) ([]news.Article, error) {
+	if p.IsZero() {
+		return []news.Article{}, nil
+	}
+	r.mu.RLock()
+	items := append([]news.Article{}, r.articles...)
+	r.mu.RUnlock()
+	sort.Slice(items, func(i, j int) bool {
+		si, sj := lastSeen(items[i]), lastSeen(items[j])
+		if !si.Equal(sj) {
+			return si.After(sj)
+		}
+		return items[i].ID > items[j].ID
+	})
+	latest := map[string]time.Time{}
+	for _, a := range items {
+		if a.FetchedAt.After(latest[a.Source]) {
+			latest[a.Source] = a.FetchedAt
+		}
+	}
+	perSource := map[string]int{}
+	expired := []news.Article{}
+	for rank, a := range items {
+		perSource[a.Source]++
+		age := p.maxAge(a.Source)
+		inFeed := !a.FetchedAt.Before(latest[a.Source])
+		switch {
+		case age > 0 && lastSeen(a).Before(now.Add(-age)),
+			!inFeed && p.MaxPerSource > 0 && perSource[a.Source] > p.MaxPerSource,
+			!inFeed && p.MaxRows > 0 && rank >= p.MaxRows:
+			expired = append(expired, a)
+		}
+	}
+	// items ran from most to least recent.
+	for i, j := 0, len(expired)-1; i < j; i, j = i+1, j-1 {
+		expired[i], expired[j] = expired[j], expired[i]
+	}
+	if limit > 0 && len(expired) > limit {
+		expired = expired[:limit]
+	}
+	return expired, nil
+}
+
+func (r *MemoryArticleRepository) DeleteArticles(_ context.Context, ids []int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	drop := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	kept := r.articles[:0]
+	for _, a := range r.articles {
+		if !drop[a.ID] {
+			kept = append(kept, a)
+		}
+	}
+	n := len(r.articles) - len(kept)
+	r.articles = kept
+	for id := range drop {
+		delete(r.revisions, id)
+	}
+	return n, nil
+}
+
+// ExpiredArticles ranks rows with window functions, which need SQLite
+// 3.25 or later.
+func (r *SQLiteArticleRepository) ExpiredArticles(_ context.Context, p RetentionPolicy, now time.Time, limit int) ([]news.Article, error) {
+	if p.IsZero() {
+		return []news.Article{}, nil
+	}
+	cutoff := func(d time.Duration) string {
+		if d <= 0 {
+			return "''"
+		}
+		t := now.Add(-d)
+		return sqlTime(&t)
+	}
+	var conds []string
+	if len(p.SourceMaxAge) == 0 {
+		if p.MaxAge > 0 {
+			conds = append(conds, "a.seen < "+cutoff(p.MaxAge))
+		}
+	} else {
+		var b strings.Builder
+		b.WriteString("a.seen < CASE COALESCE((SELECT slug FROM sources s WHERE s.id = a.source_id), 'rss')")
+		for id, d := range p.SourceMaxAge {
+			b.WriteString(fmt.Sprintf(" WHEN '%s' THEN %s", esc(id), cutoff(d)))
+		}
+		b.WriteString(" ELSE " + cutoff(p.MaxAge) + " END")
+		conds = append(conds, b.String())
+	}
+	var counts []string
+	if p.MaxPerSource > 0 {
+		counts = append(counts, fmt.Sprintf("a.source_rank > %d", p.MaxPerSource))
+	}
+	if p.MaxRows > 0 {
+		counts = append(counts, fmt.Sprintf("a.row_rank > %d", p.MaxRows))
+	}
+	if len(counts) > 0 {
+		conds = append(conds, fmt.Sprintf("(COALESCE(a.fetched_at,'') < a.latest_fetch AND (%s))", strings.Join(counts, " OR ")))
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+	ranked := "SELECT r.*, ROW_NUMBER() OVER (PARTITION BY r.source_id ORDER BY r.seen DESC, r.id DESC) AS source_rank, ROW_NUMBER() OVER (ORDER BY r.seen DESC, r.id DESC) AS row_rank, " +
+		"MAX(COALESCE(r.fetched_at,'')) OVER (PARTITION BY r.source_id) AS latest_fetch " +
+		"FROM (SELECT *, MAX(COALESCE(published_at,''), COALESCE(fetched_at,'')) AS seen FROM articles) r"
+	return r.queryArticles(fmt.Sprintf("SELECT %s FROM (%s) a WHERE %s ORDER BY a.seen, a.id LIMIT %d;", articleColumns, ranked, strings.Join(conds, " OR "), limit))
+}
+
+func (r *SQLiteArticleRepository) DeleteArticles(_ context.Context, ids []int64) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	list := make([]string, len(ids))
+	for i, id := range ids {
+		list[i] = fmt.Sprint(id)
+	}
+	in := strings.Join(list, ",")
+	var rows []struct {
+		N int `json:"n"`
+	}
+	q := fmt.Sprintf("BEGIN; DELETE FROM article_revisions WHERE article_id IN (%[1]s); DELETE FROM articles WHERE id IN (%[1]s); SELECT changes() AS n; COMMIT;", in)
+	if err := querySQLite(r.dbPath, q, &rows); err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return rows[0].N, nil
+}
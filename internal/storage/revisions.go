@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"news-go/internal/news"
+)
+
+// ArticleRevisionRepository keeps the earlier versions of articles whose
+// title or content a feed changed. UpsertArticles records them.
+type ArticleRevisionRepository interface {
+	// ArticleRevisions lists an article's earlier versions, oldest first.
+	ArticleRevisions(ctx context.Context, articleID int64) ([]news.ArticleRevision, error)
+}
+
+// keepRevision stores old as replaced at now; r.mu must be held.
+func (r *MemoryArticleRepository) keepRevision(old news.Article, now time.Time) {
+	revs := r.revisions[old.ID]
+	seen := old.UpdatedAt
+	if len(revs) > 0 {
+		seen = revs[len(revs)-1].ReplacedAt
+	}
+	r.revID++
+	r.revisions[old.ID] = append(revs, news.ArticleRevision{
+		ID:          r.revID,
+		ArticleID:   old.ID,
+		Title:       old.Title,
+		Content:     old.Content,
+		ContentText: old.ContentText,
+		ContentHash: old.ContentHash,
+		SeenAt:      seen,
+		ReplacedAt:  now,
+	})
+}
+
+func (r *MemoryArticleRepository) ArticleRevisions(_ context.Context, articleID int64) ([]news.ArticleRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]news.ArticleRevision{}, r.revisions[articleID]...), nil
+}
+
+func (r *SQLiteArticleRepository) ArticleRevisions(_ context.Context, articleID int64) ([]news.ArticleRevision, error) {
+	var rows []struct {
+		ID          int64  `json:"id"`
+		ArticleID   int64  `json:"article_id"`
+		Title       string `json:"title"`
+		Content     string `json:"content"`
+		ContentText string `json:"content_text"`
+		ContentHash string `json:"content_hash"`
+		SeenAt      string `json:"seen_at"`
+		ReplacedAt  string `json:"replaced_at"`
+	}
+	q := fmt.Sprintf("SELECT id, article_id, title, content, content_text, content_hash, COALESCE(seen_at,'') AS seen_at, replaced_at FROM article_revisions WHERE article_id = %d ORDER BY id;", articleID)
+	if err := querySQLite(r.dbPath, q, &rows); err != nil {
+		return nil, err
+	}
+	items := make([]news.ArticleRevision, 0, len(rows))
+	for _, row := range rows {
+		rev := news.ArticleRevision{
+			ID:          row.ID,
+			ArticleID:   row.ArticleID,
+			Title:       row.Title,
+			Content:     row.Content,
+			ContentText: row.ContentText,
+			ContentHash: row.ContentHash,
+		}
+		rev.SeenAt, _ = time.Parse(time.RFC3339, row.SeenAt)
+		rev.ReplacedAt, _ = time.Parse(time.RFC3339, row.ReplacedAt)
+		items = append(items, rev)
+	}
+	return items, nil
+}
+
+// hashStoredContent fills in the content hash of articles stored before
+// it existed, so their first change is recognised as a revision.
+func hashStoredContent(dbPath string) error {
+	for {
+		var rows []struct {
+			ID          int64  `json:"id"`
+			Title       string `json:"title"`
+			ContentText string `json:"content_text"`
+		}
+		q := "SELECT id, title, content_text FROM articles WHERE content_hash = '' ORDER BY id LIMIT 200;"
+		if err := querySQLite(dbPath, q, &rows); err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		var b strings.Builder
+		for _, row := range rows {
+			b.WriteString(fmt.Sprintf("UPDATE articles SET content_hash = '%s' WHERE id = %d;", news.ContentHash(row.Title, row.ContentText), row.ID))
+		}
+		if _, err := runSQLite(dbPath, b.String()); err != nil {
+			return err
+		}
+	}
+}
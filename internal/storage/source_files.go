@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"news-go/internal/news"
+)
+
+// SourceFileRepository is the ledger of files read by directory file
+// sources.
+type SourceFileRepository interface {
+	// SourceFileStatus returns the recorded status of the file with the
+	// given checksum, or "" when the source has not read it.
+	SourceFileStatus(ctx context.Context, sourceID, checksum string) (string, error)
+	RecordSourceFile(ctx context.Context, f news.SourceFile) error
+	// ListSourceFiles returns the newest records first; an empty sourceID
+	// lists every source.
+	ListSourceFiles(ctx context.Context, sourceID string, limit int) ([]news.SourceFile, error)
+}
+
+type MemorySourceFileRepository struct {
+	mu    sync.RWMutex
+	files map[[2]string]news.SourceFile
+}
+
+func NewMemorySourceFileRepository() *MemorySourceFileRepository {
+	return &MemorySourceFileRepository{files: map[[2]string]news.SourceFile{}}
+}
+
+func (r *MemorySourceFileRepository) SourceFileStatus(_ context.Context, sourceID, checksum string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.files[[2]string{sourceID, checksum}].Status, nil
+}
+
+func (r *MemorySourceFileRepository) RecordSourceFile(_ context.Context, f news.SourceFile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.files[[2]string{f.SourceID, f.Checksum}] = f
+	return nil
+}
+
+func (r *MemorySourceFileRepository) ListSourceFiles(_ context.Context, sourceID string, limit int) ([]news.SourceFile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := []news.SourceFile{}
+	for _, f := range r.files {
+		if sourceID == "" || f.SourceID == sourceID {
+			items = append(items, f)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ProcessedAt.After(items[j].ProcessedAt) })
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+type SQLiteSourceFileRepository struct{ dbPath string }
+
+func NewSQLiteSourceFileRepository(dbPath string) *SQLiteSourceFileRepository {
+	return &SQLiteSourceFileRepository{dbPath: dbPath}
+}
+
+type sourceFileRow struct {
+	SourceID    string `json:"source_id"`
+	Checksum    string `json:"checksum"`
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Items       int    `json:"items"`
+	Error       string `json:"error"`
+	ProcessedAt string `json:"processed_at"`
+}
+
+func (r *SQLiteSourceFileRepository) SourceFileStatus(_ context.Context, sourceID, checksum string) (string, error) {
+	var rows []struct {
+		Status string `json:"status"`
+	}
+	q := fmt.Sprintf("SELECT status FROM source_files WHERE source_id = '%s' AND checksum = '%s';", esc(sourceID), esc(checksum))
+	if err := querySQLite(r.dbPath, q, &rows); err != nil || len(rows) == 0 {
+		return "", err
+	}
+	return rows[0].Status, nil
+}
+
+func (r *SQLiteSourceFileRepository) RecordSourceFile(_ context.Context, f news.SourceFile) error {
+	q := fmt.Sprintf("INSERT INTO source_files (source_id, checksum, name, status, items, error, processed_at) VALUES ('%s','%s','%s','%s',%d,'%s',%s) "+
+		"ON CONFLICT(source_id, checksum) DO UPDATE SET name=excluded.name, status=excluded.status, items=excluded.items, error=excluded.error, processed_at=excluded.processed_at;",
+		esc(f.SourceID), esc(f.Checksum), esc(f.Name), esc(f.Status), f.Items, esc(f.Error), sqlTime(&f.ProcessedAt))
+	_, err := runSQLite(r.dbPath, q)
+	return err
+}
+
+func (r *SQLiteSourceFileRepository) ListSourceFiles(_ context.Context, sourceID string, limit int) ([]news.SourceFile, error) {
+	where := ""
+	if sourceID != "" {
+		where = fmt.Sprintf("WHERE source_id = '%s' ", esc(sourceID))
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+	var rows []sourceFileRow
+	q := fmt.Sprintf("SELECT source_id, checksum, name, status, items, error, processed_at FROM source_files %sORDER BY processed_at DESC, name LIMIT %d;", where, limit)
+	if err := querySQLite(r.dbPath, q, &rows); err != nil {
+		return nil, err
+	}
+	items := make([]news.SourceFile, 0, len(rows))
+	for _, row := range rows {
+		f := news.SourceFile{SourceID: row.SourceID, Checksum: row.Checksum, Name: row.Name, Status: row.Status, Items: row.Items, Error: row.Error}
+		f.ProcessedAt, _ = time.Parse(time.RFC3339, row.ProcessedAt)
+		items = append(items, f)
+	}
+	return items, nil
+}
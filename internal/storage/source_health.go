@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"news-go/internal/news"
+)
+
+type SourceHealthRepository interface {
+	GetSourceHealth(ctx context.Context, sourceID string) (news.SourceHealth, error)
+	SaveSourceHealth(ctx context.Context, h news.SourceHealth) error
+	ListSourceHealth(ctx context.Context) ([]news.SourceHealth, error)
+}
+
+type MemorySourceHealthRepository struct {
+	mu     sync.RWMutex
+	health map[string]news.SourceHealth
+}
+
+func NewMemorySourceHealthRepository() *MemorySourceHealthRepository {
+	return &MemorySourceHealthRepository{health: map[string]news.SourceHealth{}}
+}
+
+func (r *MemorySourceHealthRepository) GetSourceHealth(_ context.Context, sourceID string) (news.SourceHealth, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	h, ok := r.health[sourceID]
+	if !ok {
+		return news.SourceHealth{}, ErrNotFound
+	}
+	return h, nil
+}
+
+func (r *MemorySourceHealthRepository) SaveSourceHealth(_ context.Context, h news.SourceHealth) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.health[h.SourceID] = h
+	return nil
+}
+
+func (r *MemorySourceHealthRepository) ListSourceHealth(_ context.Context) ([]news.SourceHealth, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := make([]news.SourceHealth, 0, len(r.health))
+	for _, h := range r.health {
+		items = append(items, h)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].SourceID < items[j].SourceID })
+	return items, nil
+}
+
+type SQLiteSourceHealthRepository struct{ dbPath string }
+
+func NewSQLiteSourceHealthRepository(dbPath string) *SQLiteSourceHealthRepository {
+	return &SQLiteSourceHealthRepository{dbPath: dbPath}
+}
+
+const sourceHealthColumns = "source_id, COALESCE(last_attempt_at,'') AS last_attempt_at, COALESCE(last_success_at,'') AS last_success_at, consecutive_failures, last_error, COALESCE(newest_article_at,'') AS newest_article_at, poll_interval_sec, COALESCE(next_poll_at,'') AS next_poll_at"
+
+type sourceHealthRow struct {
+	SourceID            string `json:"source_id"`
+	LastAttemptAt       string `json:"last_attempt_at"`
+	LastSuccessAt       string `json:"last_success_at"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	LastError           string `json:"last_error"`
+	NewestArticleAt     string `json:"newest_article_at"`
+	PollIntervalSec     int    `json:"poll_interval_sec"`
+	NextPollAt          string `json:"next_poll_at"`
+}
+
+func (row sourceHealthRow) health() news.SourceHealth {
+	return news.SourceHealth{
+		SourceID:            row.SourceID,
+		LastAttemptAt:       parseOptionalTime(row.LastAttemptAt),
+		LastSuccessAt:       parseOptionalTime(row.LastSuccessAt),
+		ConsecutiveFailures: row.ConsecutiveFailures,
+		LastError:           row.LastError,
+		NewestArticleAt:     parseOptionalTime(row.NewestArticleAt),
+		PollIntervalSec:     row.PollIntervalSec,
+		NextPollAt:          parseOptionalTime(row.NextPollAt),
+	}
+}
+
+func (r *SQLiteSourceHealthRepository) GetSourceHealth(_ context.Context, sourceID string) (news.SourceHealth, error) {
+	var rows []sourceHealthRow
+	if err := querySQLite(r.dbPath, fmt.Sprintf("SELECT %s FROM source_health WHERE source_id = '%s';", sourceHealthColumns, esc(sourceID)), &rows); err != nil {
+		return news.SourceHealth{}, err
+	}
+	if len(rows) == 0 {
+		return news.SourceHealth{}, ErrNotFound
+	}
+	return rows[0].health(), nil
+}
+
+func (r *SQLiteSourceHealthRepository) SaveSourceHealth(_ context.Context, h news.SourceHealth) error {
+	q := fmt.Sprintf("INSERT INTO source_health (source_id, last_attempt_at, last_success_at, consecutive_failures, last_error, newest_article_at, poll_interval_sec, next_poll_at) VALUES ('%s',%s,%s,%d,'%s',%s,%d,%s) ON CONFLICT(source_id) DO UPDATE SET last_attempt_at=excluded.last_attempt_at, last_success_at=excluded.last_success_at, consecutive_failures=excluded.consecutive_failures, last_error=excluded.last_error, newest_article_at=excluded.newest_article_at, poll_interval_sec=excluded.poll_interval_sec, next_poll_at=excluded.next_poll_at;",
+		esc(h.SourceID), sqlTime(h.LastAttemptAt), sqlTime(h.LastSuccessAt), h.ConsecutiveFailures, esc(h.LastError), sqlTime(h.NewestArticleAt), h.PollIntervalSec, sqlTime(h.NextPollAt))
+	_, err := runSQLite(r.dbPath, q)
+	return err
+}
+
+func (r *SQLiteSourceHealthRepository) ListSourceHealth(_ context.Context) ([]news.SourceHealth, error) {
+	var rows []sourceHealthRow
+	if err := querySQLite(r.dbPath, "SELECT "+sourceHealthColumns+" FROM source_health ORDER BY source_id;", &rows); err != nil {
+		return nil, err
+	}
+	items := make([]news.SourceHealth, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row.health())
+	}
+	return items, nil
+}
+
+func parseOptionalTime(v string) *time.Time {
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// sqlTime renders t as a quoted RFC3339 literal, or NULL.
+func sqlTime(t *time.Time) string {
+	if t == nil {
+		return "NULL"
+	}
+	return "'" + t.UTC().Format(time.RFC3339) + "'"
+}
@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"news-go/internal/news"
+)
+
+type SourceRepository interface {
+	ListSources(ctx context.Context) ([]news.Source, error)
+	GetSource(ctx context.Context, id string) (news.Source, error)
+	CreateSource(ctx context.Context, src news.Source) (news.Source, error)
+	UpdateSource(ctx context.Context, src news.Source) (news.Source, error)
+	DeleteSource(ctx context.Context, id string) error
+}
+
+type MemorySourceRepository struct {
+	mu      sync.RWMutex
+	sources map[string]news.Source
+}
+
+func NewMemorySourceRepository() *MemorySourceRepository {
+	return &MemorySourceRepository{sources: map[string]news.Source{}}
+}
+
+func (r *MemorySourceRepository) ListSources(_ context.Context) ([]news.Source, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := make([]news.Source, 0, len(r.sources))
+	for _, s := range r.sources {
+		items = append(items, s)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items, nil
+}
+
+func (r *MemorySourceRepository) GetSource(_ context.Context, id string) (news.Source, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.sources[id]
+	if !ok {
+		return news.Source{}, ErrNotFound
+	}
+	return s, nil
+}
+
+func (r *MemorySourceRepository) CreateSource(_ context.Context, src news.Source) (news.Source, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sources[src.ID]; ok {
+		return news.Source{}, ErrConflict
+	}
+	now := time.Now().UTC()
+	src.CreatedAt, src.UpdatedAt = now, now
+	r.sources[src.ID] = src
+	return src, nil
+}
+
+func (r *MemorySourceRepository) UpdateSource(_ context.Context, src news.Source) (news.Source, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, ok := r.sources[src.ID]
+	if !ok {
+		return news.Source{}, ErrNotFound
+	}
+	src.CreatedAt = old.CreatedAt
+	src.UpdatedAt = time.Now().UTC()
+	r.sources[src.ID] = src
+	return src, nil
+}
+
+func (r *MemorySourceRepository) DeleteSource(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sources[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.sources, id)
+	return nil
+}
+
+// SQLiteSourceRepository shares the articles database; the schema is applied
+// by NewSQLiteArticleRepository.
+type SQLiteSourceRepository struct{ dbPath string }
+
+func NewSQLiteSourceRepository(dbPath string) *SQLiteSourceRepository {
+	return &SQLiteSourceRepository{dbPath: dbPath}
+}
+
+const sourceColumns = "slug, name, url, country, base_authority, topics, enabled, extract_full_text, kind, options, http_options, retention_days, COALESCE(created_at,'') AS created_at, COALESCE(updated_at,'') AS updated_at"
+
+type sourceRow struct {
+	Slug          string  `json:"slug"`
+	Name          string  `json:"name"`
+	URL           string  `json:"url"`
+	Country       string  `json:"country"`
+	BaseAuthority float64 `json:"base_authority"`
+	Topics        string  `json:"topics"`
+	Enabled       int     `json:"enabled"`
+	ExtractFull   int     `json:"extract_full_text"`
+	Kind          string  `json:"kind"`
+	Options       string  `json:"options"`
+	HTTPOptions   string  `json:"http_options"`
+	RetentionDays int     `json:"retention_days"`
+	CreatedAt     string  `json:"created_at"`
+	UpdatedAt     string  `json:"updated_at"`
+}
+
+func (row sourceRow) source() news.Source {
+	s := news.Source{
+		ID:              row.Slug,
+		Name:            row.Name,
+		Country:         row.Country,
+		FeedURL:         row.URL,
+		BaseAuthority:   row.BaseAuthority,
+		Enabled:         row.Enabled != 0,
+		ExtractFullText: row.ExtractFull != 0,
+		Kind:            row.Kind,
+		RetentionDays:   row.RetentionDays,
+		Topics:          []string{},
+	}
+	if row.Options != "" && json.Valid([]byte(row.Options)) {
+		s.Options = json.RawMessage(row.Options)
+	}
+	if row.HTTPOptions != "" {
+		var h news.SourceHTTP
+		if json.Unmarshal([]byte(row.HTTPOptions), &h) == nil {
+			s.HTTP = &h
+		}
+	}
+	_ = json.Unmarshal([]byte(row.Topics), &s.Topics)
+	s.CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	s.UpdatedAt, _ = time.Parse(time.RFC3339, row.UpdatedAt)
+	return s
+}
+
+func (r *SQLiteSourceRepository) ListSources(_ context.Context) ([]news.Source, error) {
+	var rows []sourceRow
+	if err := querySQLite(r.dbPath, "SELECT "+sourceColumns+" FROM sources WHERE slug IS NOT NULL ORDER BY slug;", &rows); err != nil {
+		return nil, err
+	}
+	items := make([]news.Source, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row.source())
+	}
+	return items, nil
+}
+
+func (r *SQLiteSourceRepository) GetSource(_ context.Context, id string) (news.Source, error) {
+	var rows []sourceRow
+	if err := querySQLite(r.dbPath, fmt.Sprintf("SELECT %s FROM sources WHERE slug = '%s';", sourceColumns, esc(id)), &rows); err != nil {
+		return news.Source{}, err
+	}
+	if len(rows) == 0 {
+		return news.Source{}, ErrNotFound
+	}
+	return rows[0].source(), nil
+}
+
+func (r *SQLiteSourceRepository) CreateSource(ctx context.Context, src news.Source) (news.Source, error) {
+	if _, err := r.GetSource(ctx, src.ID); err == nil {
+		return news.Source{}, ErrConflict
+	} else if err != ErrNotFound {
+		return news.Source{}, err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	q := fmt.Sprintf("INSERT INTO sources (slug, name, url, country, base_authority, topics, enabled, extract_full_text, kind, options, http_options, retention_days, created_at, updated_at) VALUES ('%s','%s','%s','%s',%g,'%s',%d,%d,'%s','%s','%s',%d,'%s','%s');",
+		esc(src.ID), esc(src.Name), esc(src.FeedURL), esc(src.Country), src.BaseAuthority, esc(stringsJSON(src.Topics)), boolInt(src.Enabled), boolInt(src.ExtractFullText), esc(sourceKind(src)), esc(string(src.Options)), esc(httpOptionsJSON(src.HTTP)), src.RetentionDays, now, now)
+	if _, err := runSQLite(r.dbPath, q); err != nil {
+		return news.Source{}, err
+	}
+	return r.GetSource(ctx, src.ID)
+}
+
+func (r *SQLiteSourceRepository) UpdateSource(ctx context.Context, src news.Source) (news.Source, error) {
+	if _, err := r.GetSource(ctx, src.ID); err != nil {
+		return news.Source{}, err
+	}
+	q := fmt.Sprintf("UPDATE sources SET name='%s', url='%s', country='%s', base_authority=%g, topics='%s', enabled=%d, extract_full_text=%d, kind='%s', options='%s', http_options='%s', retention_days=%d, updated_at='%s' WHERE slug='%s';",
+		esc(src.Name), esc(src.FeedURL), esc(src.Country), src.BaseAuthority, esc(stringsJSON(src.Topics)), boolInt(src.Enabled), boolInt(src.ExtractFullText), esc(sourceKind(src)), esc(string(src.Options)), esc(httpOptionsJSON(src.HTTP)), src.RetentionDays, time.Now().UTC().Format(time.RFC3339), esc(src.ID))
+	if _, err := runSQLite(r.dbPath, q); err != nil {
+		return news.Source{}, err
+	}
+	return r.GetSource(ctx, src.ID)
+}
+
+func (r *SQLiteSourceRepository) DeleteSource(ctx context.Context, id string) error {
+	if _, err := r.GetSource(ctx, id); err != nil {
+		return err
+	}
+	// Articles find their source slug through source_id, so a source they
+	// reference stays; the check and the delete are one statement so a
+	// concurrent crawl cannot slip an article in between.
+	var rows []struct {
+		N int `json:"n"`
+	}
+	q := fmt.Sprintf("DELETE FROM sources WHERE slug='%s' AND NOT EXISTS (SELECT 1 FROM articles WHERE source_id = sources.id); SELECT changes() AS n;", esc(id))
+	if err := querySQLite(r.dbPath, q, &rows); err != nil {
+		return err
+	}
+	if len(rows) == 0 || rows[0].N == 0 {
+		return ErrSourceInUse
+	}
+	return nil
+}
+
+func stringsJSON(values []string) string {
+	if values == nil {
+		values = []string{}
+	}
+	b, _ := json.Marshal(values)
+	return string(b)
+}
+
+// httpOptionsJSON stores absent settings as an empty string.
+func httpOptionsJSON(h *news.SourceHTTP) string {
+	if h.IsZero() {
+		return ""
+	}
+	b, _ := json.Marshal(h)
+	return string(b)
+}
+
+func sourceKind(src news.Source) string {
+	if src.Kind == "" {
+		return news.KindRSS
+	}
+	return src.Kind
+}
+
+func boolInt(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
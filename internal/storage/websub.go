@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"news-go/internal/news"
+)
+
+type WebSubRepository interface {
+	GetSubscription(ctx context.Context, sourceID string) (news.WebSubSubscription, error)
+	SaveSubscription(ctx context.Context, sub news.WebSubSubscription) error
+	ListSubscriptions(ctx context.Context) ([]news.WebSubSubscription, error)
+}
+
+type MemoryWebSubRepository struct {
+	mu   sync.RWMutex
+	subs map[string]news.WebSubSubscription
+}
+
+func NewMemoryWebSubRepository() *MemoryWebSubRepository {
+	return &MemoryWebSubRepository{subs: map[string]news.WebSubSubscription{}}
+}
+
+func (r *MemoryWebSubRepository) GetSubscription(_ context.Context, sourceID string) (news.WebSubSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sub, ok := r.subs[sourceID]
+	if !ok {
+		return news.WebSubSubscription{}, ErrNotFound
+	}
+	return sub, nil
+}
+
+func (r *MemoryWebSubRepository) SaveSubscription(_ context.Context, sub news.WebSubSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subs[sub.SourceID] = sub
+	return nil
+}
+
+func (r *MemoryWebSubRepository) ListSubscriptions(_ context.Context) ([]news.WebSubSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := make([]news.WebSubSubscription, 0, len(r.subs))
+	for _, s := range r.subs {
+		items = append(items, s)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].SourceID < items[j].SourceID })
+	return items, nil
+}
+
+type SQLiteWebSubRepository struct{ dbPath string }
+
+func NewSQLiteWebSubRepository(dbPath string) *SQLiteWebSubRepository {
+	return &SQLiteWebSubRepository{dbPath: dbPath}
+}
+
+const webSubColumns = "source_id, hub, topic, secret, state, lease_seconds, requested_at, COALESCE(expires_at,'') AS expires_at, COALESCE(last_push_at,'') AS last_push_at, last_error"
+
+type webSubRow struct {
+	SourceID     string `json:"source_id"`
+	Hub          string `json:"hub"`
+	Topic        string `json:"topic"`
+	Secret       string `json:"secret"`
+	State        string `json:"state"`
+	LeaseSeconds int    `json:"lease_seconds"`
+	RequestedAt  string `json:"requested_at"`
+	ExpiresAt    string `json:"expires_at"`
+	LastPushAt   string `json:"last_push_at"`
+	LastError    string `json:"last_error"`
+}
+
+func (row webSubRow) subscription() news.WebSubSubscription {
+	sub := news.WebSubSubscription{
+		SourceID:     row.SourceID,
+		Hub:          row.Hub,
+		Topic:        row.Topic,
+		Secret:       row.Secret,
+		State:        row.State,
+		LeaseSeconds: row.LeaseSeconds,
+		ExpiresAt:    parseOptionalTime(row.ExpiresAt),
+		LastPushAt:   parseOptionalTime(row.LastPushAt),
+		LastError:    row.LastError,
+	}
+	sub.RequestedAt, _ = time.Parse(time.RFC3339, row.RequestedAt)
+	return sub
+}
+
+func (r *SQLiteWebSubRepository) GetSubscription(_ context.Context, sourceID string) (news.WebSubSubscription, error) {
+	var rows []webSubRow
+	if err := querySQLite(r.dbPath, fmt.Sprintf("SELECT %s FROM websub_subscriptions WHERE source_id = '%s';", webSubColumns, esc(sourceID)), &rows); err != nil {
+		return news.WebSubSubscription{}, err
+	}
+	if len(rows) == 0 {
+		return news.WebSubSubscription{}, ErrNotFound
+	}
+	return rows[0].subscription(), nil
+}
+
+func (r *SQLiteWebSubRepository) SaveSubscription(_ context.Context, sub news.WebSubSubscription) error {
+	q := fmt.Sprintf("INSERT INTO websub_subscriptions (source_id, hub, topic, secret, state, lease_seconds, requested_at, expires_at, last_push_at, last_error) VALUES ('%s','%s','%s','%s','%s',%d,%s,%s,%s,'%s') "+
+		"ON CONFLICT(source_id) DO UPDATE SET hub=excluded.hub, topic=excluded.topic, secret=excluded.secret, state=excluded.state, lease_seconds=excluded.lease_seconds, requested_at=excluded.requested_at, expires_at=excluded.expires_at, last_push_at=excluded.last_push_at, last_error=excluded.last_error;",
+		esc(sub.SourceID), esc(sub.Hub), esc(sub.Topic), esc(sub.Secret), esc(sub.State), sub.LeaseSeconds, sqlTime(&sub.RequestedAt), sqlTime(sub.ExpiresAt), sqlTime(sub.LastPushAt), esc(sub.LastError))
+	_, err := runSQLite(r.dbPath, q)
+	return err
+}
+
+func (r *SQLiteWebSubRepository) ListSubscriptions(_ context.Context) ([]news.WebSubSubscription, error) {
+	var rows []webSubRow
+	if err := querySQLite(r.dbPath, "SELECT "+webSubColumns+" FROM websub_subscriptions ORDER BY source_id;", &rows); err != nil {
+		return nil, err
+	}
+	items := make([]news.WebSubSubscription, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row.subscription())
+	}
+	return items, nil
+}
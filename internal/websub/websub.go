@@ -0,0 +1,259 @@
+// Package websub subscribes sources to the WebSub (PubSubHubbub) hubs their
+// feeds advertise and accepts the content hubs push back. Polling keeps
+// running alongside, so a hub that stops pushing only costs latency.
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"news-go/internal/news"
+	"news-go/internal/storage"
+)
+
+var (
+	// ErrUnknownSubscription means the callback was hit for a source or
+	// topic this server did not subscribe to.
+	ErrUnknownSubscription = errors.New("unknown subscription")
+	// ErrBadSignature means pushed content was not signed with the
+	// subscription's secret. The spec asks for a 2xx reply regardless.
+	ErrBadSignature = errors.New("invalid content signature")
+)
+
+// CallbackPath is where hubs reach the server; the source ID follows it.
+const CallbackPath = "/v1/websub/callback/"
+
+const (
+	// pendingTimeout is how long a hub may take to verify intent before
+	// the subscription request is sent again.
+	pendingTimeout = 10 * time.Minute
+	// deniedRetry is how long a denial is respected.
+	deniedRetry = 24 * time.Hour
+)
+
+// Deliver stores the body of a pushed feed for a source.
+type Deliver func(ctx context.Context, sourceID string, body []byte) error
+
+type Subscriber struct {
+	repo     storage.WebSubRepository
+	client   *http.Client
+	callback string
+	lease    time.Duration
+	deliver  Deliver
+	now      func() time.Time
+}
+
+// NewSubscriber builds callback URLs from baseURL, the server's public
+// address. lease is the lease asked for; hubs may grant another.
+func NewSubscriber(repo storage.WebSubRepository, client *http.Client, baseURL string, lease time.Duration, deliver Deliver) *Subscriber {
+	return &Subscriber{
+		repo:     repo,
+		client:   client,
+		callback: strings.TrimRight(baseURL, "/") + CallbackPath,
+		lease:    lease,
+		deliver:  deliver,
+		now:      time.Now,
+	}
+}
+
+// Ensure subscribes sourceID to topic at hub unless a live subscription
+// exists. It is called after every poll, which also renews leases once
+// four fifths of them have passed.
+func (s *Subscriber) Ensure(ctx context.Context, sourceID, hub, topic string) error {
+	now := s.now().UTC()
+	sub, err := s.repo.GetSubscription(ctx, sourceID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	if err == nil && sub.Hub == hub && sub.Topic == topic && !s.due(sub, now) {
+		return nil
+	}
+	if err != nil || sub.Hub != hub || sub.Topic != topic {
+		secret, err := newSecret()
+		if err != nil {
+			return err
+		}
+		sub = news.WebSubSubscription{SourceID: sourceID, Hub: hub, Topic: topic, Secret: secret, State: news.WebSubPending}
+	}
+	if sub.State != news.WebSubActive {
+		sub.State = news.WebSubPending
+	}
+	sub.RequestedAt, sub.LastError = now, ""
+	// Saved before the request: hubs may verify intent before replying.
+	if err := s.repo.SaveSubscription(ctx, sub); err != nil {
+		return err
+	}
+	if err := s.request(ctx, sub); err != nil {
+		sub.LastError = err.Error()
+		if saveErr := s.repo.SaveSubscription(ctx, sub); saveErr != nil {
+			log.Printf("event=websub status=error source=%s err=%v", sourceID, saveErr)
+		}
+		return err
+	}
+	log.Printf("event=websub status=requested source=%s hub=%s topic=%s", sourceID, hub, topic)
+	return nil
+}
+
+func (s *Subscriber) due(sub news.WebSubSubscription, now time.Time) bool {
+	switch sub.State {
+	case news.WebSubPending:
+		return now.Sub(sub.RequestedAt) > pendingTimeout
+	case news.WebSubDenied:
+		return now.Sub(sub.RequestedAt) > deniedRetry
+	case news.WebSubActive:
+		if sub.ExpiresAt == nil {
+			return false
+		}
+		lease := time.Duration(sub.LeaseSeconds) * time.Second
+		return now.After(sub.ExpiresAt.Add(-lease / 5))
+	}
+	return true
+}
+
+func (s *Subscriber) request(ctx context.Context, sub news.WebSubSubscription) error {
+	form := url.Values{
+		"hub.mode":     {"subscribe"},
+		"hub.topic":    {sub.Topic},
+		"hub.callback": {s.callback + url.PathEscape(sub.SourceID)},
+		"hub.secret":   {sub.Secret},
+	}
+	if s.lease > 0 {
+		form.Set("hub.lease_seconds", strconv.Itoa(int(s.lease/time.Second)))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Verify answers a hub's intent verification and returns the challenge to
+// echo. A denial is recorded and acknowledged with an empty challenge.
+func (s *Subscriber) Verify(ctx context.Context, sourceID string, q url.Values) (string, error) {
+	sub, err := s.repo.GetSubscription(ctx, sourceID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", ErrUnknownSubscription
+	}
+	if err != nil {
+		return "", err
+	}
+	if q.Get("hub.topic") != sub.Topic {
+		return "", ErrUnknownSubscription
+	}
+	now := s.now().UTC()
+	switch q.Get("hub.mode") {
+	case "subscribe":
+		challenge := q.Get("hub.challenge")
+		if challenge == "" {
+			return "", fmt.Errorf("%w: missing challenge", ErrUnknownSubscription)
+		}
+		lease, err := strconv.Atoi(q.Get("hub.lease_seconds"))
+		if err != nil || lease <= 0 {
+			lease = int(s.lease / time.Second)
+		}
+		sub.State, sub.LeaseSeconds, sub.LastError = news.WebSubActive, lease, ""
+		expires := now.Add(time.Duration(lease) * time.Second)
+		sub.ExpiresAt = &expires
+		if err := s.repo.SaveSubscription(ctx, sub); err != nil {
+			return "", err
+		}
+		log.Printf("event=websub status=active source=%s lease_sec=%d", sourceID, lease)
+		return challenge, nil
+	case "denied":
+		sub.State, sub.ExpiresAt = news.WebSubDenied, nil
+		sub.LastError = strings.TrimSpace("denied by hub " + q.Get("hub.reason"))
+		if err := s.repo.SaveSubscription(ctx, sub); err != nil {
+			return "", err
+		}
+		log.Printf("event=websub status=denied source=%s reason=%q", sourceID, q.Get("hub.reason"))
+		return "", nil
+	}
+	// Unsubscribes are never requested, so none is confirmed.
+	return "", ErrUnknownSubscription
+}
+
+// Receive checks the X-Hub-Signature of pushed content and hands the body
+// to the Deliver function.
+func (s *Subscriber) Receive(ctx context.Context, sourceID string, body []byte, signature string) error {
+	sub, err := s.repo.GetSubscription(ctx, sourceID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrUnknownSubscription
+	}
+	if err != nil {
+		return err
+	}
+	if sub.State != news.WebSubActive {
+		return ErrUnknownSubscription
+	}
+	if !validSignature(sub.Secret, body, signature) {
+		return ErrBadSignature
+	}
+	if err := s.deliver(ctx, sourceID, body); err != nil {
+		return err
+	}
+	now := s.now().UTC()
+	sub.LastPushAt = &now
+	return s.repo.SaveSubscription(ctx, sub)
+}
+
+// validSignature checks "method=hexdigest" as sent by hubs; sha1 is still
+// what many hubs use.
+func validSignature(secret string, body []byte, header string) bool {
+	method, digest, ok := strings.Cut(strings.TrimSpace(header), "=")
+	if !ok {
+		return false
+	}
+	var h func() hash.Hash
+	switch strings.ToLower(method) {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha384":
+		h = sha512.New384
+	case "sha512":
+		h = sha512.New
+	default:
+		return false
+	}
+	want, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,163 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"news-go/internal/news"
+	"news-go/internal/storage"
+)
+
+// standInHub accepts subscription requests and verifies intent by calling
+// the callback, as a real hub would.
+type standInHub struct {
+	mu       sync.Mutex
+	requests []url.Values
+	verified chan string
+}
+
+func (h *standInHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.mu.Lock()
+	h.requests = append(h.requests, r.PostForm)
+	h.mu.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+	form := r.PostForm
+	go func() {
+		q := url.Values{
+			"hub.mode":          {"subscribe"},
+			"hub.topic":         {form.Get("hub.topic")},
+			"hub.challenge":     {"c-123"},
+			"hub.lease_seconds": {"600"},
+		}
+		resp, err := http.Get(form.Get("hub.callback") + "?" + q.Encode())
+		if err != nil {
+			h.verified <- "error: " + err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		h.verified <- string(body)
+	}()
+}
+
+func (h *standInHub) last() url.Values {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.requests[len(h.requests)-1]
+}
+
+func callbackServer(sub *Subscriber) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, CallbackPath)
+		challenge, err := sub.Verify(r.Context(), id, r.URL.Query())
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, challenge)
+	}))
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSubscribeVerifyAndReceive(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewMemoryWebSubRepository()
+	var delivered [][]byte
+	sub := NewSubscriber(repo, http.DefaultClient, "", time.Hour, func(_ context.Context, id string, body []byte) error {
+		delivered = append(delivered, body)
+		return nil
+	})
+	cb := callbackServer(sub)
+	defer cb.Close()
+	sub.callback = cb.URL + CallbackPath
+	hub := &standInHub{verified: make(chan string, 1)}
+	hubSrv := httptest.NewServer(hub)
+	defer hubSrv.Close()
+
+	if err := sub.Ensure(ctx, "blog", hubSrv.URL, "https://ex.test/feed"); err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	if got := <-hub.verified; got != "c-123" {
+		t.Fatalf("challenge echo = %q", got)
+	}
+	req := hub.last()
+	if req.Get("hub.mode") != "subscribe" || req.Get("hub.lease_seconds") != "3600" || req.Get("hub.secret") == "" {
+		t.Fatalf("unexpected subscribe request: %v", req)
+	}
+	got, err := repo.GetSubscription(ctx, "blog")
+	if err != nil || got.State != news.WebSubActive || got.LeaseSeconds != 600 || got.ExpiresAt == nil {
+		t.Fatalf("subscription = %+v, %v", got, err)
+	}
+
+	body := []byte("<rss/>")
+	if err := sub.Receive(ctx, "blog", body, "sha256=00"); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("bad signature: %v", err)
+	}
+	if err := sub.Receive(ctx, "blog", body, sign(req.Get("hub.secret"), body)); err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if len(delivered) != 1 || string(delivered[0]) != "<rss/>" {
+		t.Fatalf("delivered = %q", delivered)
+	}
+	if err := sub.Receive(ctx, "other", body, sign(req.Get("hub.secret"), body)); !errors.Is(err, ErrUnknownSubscription) {
+		t.Fatalf("unknown source: %v", err)
+	}
+	if _, err := sub.Verify(ctx, "blog", url.Values{"hub.mode": {"subscribe"}, "hub.topic": {"https://evil.test/"}, "hub.challenge": {"x"}}); !errors.Is(err, ErrUnknownSubscription) {
+		t.Fatalf("foreign topic verified: %v", err)
+	}
+}
+
+func TestEnsureRenewsNearLeaseEnd(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewMemoryWebSubRepository()
+	hub := &standInHub{verified: make(chan string, 1)}
+	hubSrv := httptest.NewServer(hub)
+	defer hubSrv.Close()
+	sub := NewSubscriber(repo, http.DefaultClient, "https://news.test", time.Hour, nil)
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	sub.now = func() time.Time { return now }
+	expires := now.Add(30 * time.Minute)
+	_ = repo.SaveSubscription(ctx, news.WebSubSubscription{
+		SourceID: "blog", Hub: hubSrv.URL, Topic: "https://ex.test/feed", Secret: "s",
+		State: news.WebSubActive, LeaseSeconds: 3600, RequestedAt: now.Add(-30 * time.Minute), ExpiresAt: &expires,
+	})
+
+	if err := sub.Ensure(ctx, "blog", hubSrv.URL, "https://ex.test/feed"); err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	if len(hub.requests) != 0 {
+		t.Fatalf("renewed too early")
+	}
+	now = now.Add(20 * time.Minute)
+	if err := sub.Ensure(ctx, "blog", hubSrv.URL, "https://ex.test/feed"); err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	req := hub.last()
+	if req.Get("hub.secret") != "s" || req.Get("hub.callback") != "https://news.test/v1/websub/callback/blog" {
+		t.Fatalf("unexpected renewal: %v", req)
+	}
+	got, _ := repo.GetSubscription(ctx, "blog")
+	if got.State != news.WebSubActive {
+		t.Fatalf("renewal dropped the active state: %+v", got)
+	}
+}
@@ -0,0 +1,143 @@
+// Package worddiff compares two texts word by word. Words are runs of
+// non-space characters, except that Chinese, Japanese and Korean
+// characters count as one word each, since those scripts do not separate
+// words with spaces.
+package worddiff
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	Equal  = "equal"
+	Insert = "insert"
+	Delete = "delete"
+)
+
+// Op is a run of words kept, inserted into the new text or deleted from
+// the old one. Text keeps the whitespace after each word, so joining the
+// equal and insert ops gives the new text and joining the equal and
+// delete ops the old one, less leading whitespace.
+type Op struct {
+	Kind string `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxCells bounds the table of the longest-common-subsequence search over
+// the words between the common prefix and suffix. Beyond it the middle is
+// reported as deleted and inserted whole.
+const maxCells = 1 << 22
+
+// Diff returns the ops turning old into new, with adjacent ops of the same
+// kind merged. Whitespace-only changes are not reported.
+func Diff(old, new string) []Op {
+	a, b := tokens(old), tokens(new)
+	var ops []Op
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix].word == b[prefix].word {
+		ops = appendOp(ops, Equal, b[prefix].text)
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix].word == b[len(b)-1-suffix].word {
+		suffix++
+	}
+	ops = diffMiddle(ops, a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	for _, t := range b[len(b)-suffix:] {
+		ops = appendOp(ops, Equal, t.text)
+	}
+	return ops
+}
+
+func diffMiddle(ops []Op, a, b []token) []Op {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 || n*m > maxCells {
+		for _, t := range a {
+			ops = appendOp(ops, Delete, t.text)
+		}
+		for _, t := range b {
+			ops = appendOp(ops, Insert, t.text)
+		}
+		return ops
+	}
+	// lcs[i*(m+1)+j] is the length of the longest common subsequence of
+	// a[i:] and b[j:].
+	lcs := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case a[i].word == b[j].word:
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j]
+			default:
+				lcs[i*(m+1)+j] = lcs[i*(m+1)+j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i].word == b[j].word:
+			ops = appendOp(ops, Equal, b[j].text)
+			i++
+			j++
+		case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+			ops = appendOp(ops, Delete, a[i].text)
+			i++
+		default:
+			ops = appendOp(ops, Insert, b[j].text)
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = appendOp(ops, Delete, a[i].text)
+	}
+	for ; j < m; j++ {
+		ops = appendOp(ops, Insert, b[j].text)
+	}
+	return ops
+}
+
+func appendOp(ops []Op, kind, text string) []Op {
+	if len(ops) > 0 && ops[len(ops)-1].Kind == kind {
+		ops[len(ops)-1].Text += text
+		return ops
+	}
+	return append(ops, Op{Kind: kind, Text: text})
+}
+
+// token is a word and, in text, the word with the whitespace after it.
+type token struct {
+	word string
+	text string
+}
+
+func tokens(s string) []token {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	var out []token
+	for len(s) > 0 {
+		end := 0
+		for end < len(s) {
+			r, size := utf8.DecodeRuneInString(s[end:])
+			if unicode.IsSpace(r) || end > 0 && isIdeograph(r) {
+				break
+			}
+			end += size
+			if isIdeograph(r) {
+				break
+			}
+		}
+		rest := strings.TrimLeftFunc(s[end:], unicode.IsSpace)
+		out = append(out, token{word: s[:end], text: s[:len(s)-len(rest)]})
+		s = rest
+	}
+	return out
+}
+
+func isIdeograph(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,59 @@
+package worddiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	got := Diff("Minister resigns after  scandal", "Minister resigns after long scandal, aides say")
+	want := []Op{
+		{Equal, "Minister resigns after "},
+		{Delete, "scandal"},
+		{Insert, "long scandal, aides say"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff = %#v", got)
+	}
+	if ops := Diff("a  b\nc", "a b c"); len(ops) != 1 || ops[0].Kind != Equal {
+		t.Fatalf("whitespace-only change: %#v", ops)
+	}
+	if ops := Diff("", ""); len(ops) != 0 {
+		t.Fatalf("empty: %#v", ops)
+	}
+}
+
+func TestDiffIdeographs(t *testing.T) {
+	got := Diff("央行宣布降息", "央行宣布加息")
+	want := []Op{{Equal, "央行宣布"}, {Delete, "降"}, {Insert, "加"}, {Equal, "息"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff = %#v", got)
+	}
+}
+
+func TestDiffRebuildsBothTexts(t *testing.T) {
+	old := "The company said revenue rose ten percent in the third quarter."
+	new := "The company said on Tuesday that revenue rose eight percent in the quarter."
+	var before, after strings.Builder
+	for _, op := range Diff(old, new) {
+		if op.Kind != Insert {
+			before.WriteString(op.Text)
+		}
+		if op.Kind != Delete {
+			after.WriteString(op.Text)
+		}
+	}
+	if strings.Join(strings.Fields(before.String()), " ") != old || after.String() != new {
+		t.Fatalf("old %q\nnew %q", before.String(), after.String())
+	}
+}
+
+func TestDiffLargeInputFallsBack(t *testing.T) {
+	a := strings.Repeat("x ", 3000) + "end"
+	b := strings.Repeat("y ", 3000) + "end"
+	ops := Diff(a, b)
+	if len(ops) != 3 || ops[0].Kind != Delete || ops[1].Kind != Insert || ops[2].Text != "end" {
+		t.Fatalf("ops = %d %#v", len(ops), ops[len(ops)-1])
+	}
+}